@@ -83,12 +83,13 @@ func newApplication() *Application {
 	a.twitchUserStore = NewUserStore()
 	state.StoreTwitchUserStore(a.twitchUserStore)
 	a.twitchUserContext = NewUserContext()
-	a.twitchStreamStore = NewStreamStore()
 
 	a.Quit = make(chan string)
 	a.pubSub = pubsub.New()
 	state.StorePubSub(a.pubSub)
 
+	a.twitchStreamStore = NewStreamStore(a.pubSub, &a)
+
 	go a.pubSub.Run()
 
 	return &a
@@ -242,7 +243,12 @@ func (a *Application) InitializeModules() (err error) {
 		fmt.Println("Error starting twitter stream:", err)
 	}
 
-	err = modules.InitServer(a, &a.config.Pajbot1, a.ReportHolder)
+	err = modules.InitServer(a, &a.config.Pajbot1, &a.config.Web, a.ReportHolder)
+	if err != nil {
+		return
+	}
+
+	err = modules.LoadGlobalModules()
 	if err != nil {
 		return
 	}
@@ -492,9 +498,11 @@ func (a *Application) StartBots() error {
 
 			bot.OnNewRoomstateMessage(bot.HandleRoomstateMessage)
 
-			bot.OnNewUnsetMessage(func(rawMessage string) {
-				fmt.Println("Unparsed message:", rawMessage)
-			})
+			bot.OnNewUsernoticeMessage(bot.HandleUserNoticeMessage)
+
+			bot.OnNewClearchatMessage(bot.HandleClearChatMessage)
+
+			bot.OnNewUnsetMessage(bot.HandleUnsetMessage)
 
 			// Ensure that the bot has joined its own chat
 			bot.JoinChannel(bot.TwitchAccount().ID())
@@ -550,83 +558,44 @@ func (a *Application) listenToModeratorActions(userID, channelID, userToken stri
 	moderationTopic := twitchpubsub.ModerationActionTopic(userID, channelID)
 	a.TwitchPubSub.Listen(moderationTopic, userToken)
 	a.TwitchPubSub.OnModerationAction(func(channelID string, event *twitchpubsub.ModerationAction) {
-		const ActionUnknown = 0
-		const ActionTimeout = 1
-		const ActionBan = 2
-		const ActionUnban = 3
-		duration := 0
-
-		content := fmt.Sprintf("Moderation action: %+v", event)
-		fmt.Println(content)
-		var actionContext *string
-		action := 0
-		reason := ""
-		const queryF = "INSERT INTO `ModerationAction` (ChannelID, UserID, Action, Duration, TargetID, Reason, Context) VALUES (?, ?, ?, ?, ?, ?, ?);"
-		switch event.ModerationAction {
-		case "timeout":
-			action = ActionTimeout
-			content = fmt.Sprintf("%s timed out %s for %s seconds", event.CreatedBy, event.Arguments[0], event.Arguments[1])
-			duration, _ = strconv.Atoi(event.Arguments[1])
-			if len(event.Arguments[2]) > 0 {
-				reason = event.Arguments[2]
-				content += " for reason: \"" + reason + "\""
-			}
+		fmt.Printf("Moderation action: %+v\n", event)
+
+		action := pb2twitch.ModerationAction{
+			Channel: pkg.PubSubUser{
+				ID: channelID,
+			},
+			Target: pkg.PubSubUser{
+				ID: event.TargetUserID,
+			},
+			Source: pkg.PubSubUser{
+				ID:   event.CreatedByUserID,
+				Name: event.CreatedBy,
+			},
+		}
 
-			e := pkg.PubSubTimeoutEvent{
-				Channel: pkg.PubSubUser{
-					ID: channelID,
-				},
-				Target: pkg.PubSubUser{
-					ID:   event.TargetUserID,
-					Name: event.Arguments[0],
-				},
-				Source: pkg.PubSubUser{
-					ID:   event.CreatedByUserID,
-					Name: event.CreatedBy,
-				},
-				Duration: duration,
-				Reason:   reason,
-			}
+		if len(event.Arguments) > 0 {
+			action.Target.Name = event.Arguments[0]
+		}
 
-			a.pubSub.Publish(a, "TimeoutEvent", e)
+		switch event.ModerationAction {
+		case "timeout":
+			action.Action = pkg.ModerationActionTimeout
+			action.Duration, _ = strconv.Atoi(event.Arguments[1])
+			action.Reason = event.Arguments[2]
 
 		case "ban":
-			action = ActionBan
-			content = fmt.Sprintf("%s banned %s", event.CreatedBy, event.Arguments[0])
-			if len(event.Arguments[1]) > 0 {
-				reason = event.Arguments[1]
-				content += " for reason: \"" + reason + "\""
-			}
-
-			e := pkg.PubSubBanEvent{
-				Channel: pkg.PubSubUser{
-					ID: channelID,
-				},
-				Target: pkg.PubSubUser{
-					ID:   event.TargetUserID,
-					Name: event.Arguments[0],
-				},
-				Source: pkg.PubSubUser{
-					ID:   event.CreatedByUserID,
-					Name: event.CreatedBy,
-				},
-				Reason: reason,
-			}
-
-			a.pubSub.Publish(a, "BanEvent", e)
+			action.Action = pkg.ModerationActionBan
+			action.Reason = event.Arguments[1]
 
 		case "unban", "untimeout":
-			action = ActionUnban
-			content = fmt.Sprintf("%s unbanned %s", event.CreatedBy, event.Arguments[0])
-		}
+			action.Action = pkg.ModerationActionUnban
 
-		if action != 0 {
-			_, err := a.sqlClient.Exec(queryF, channelID, event.CreatedByUserID, action, duration, event.TargetUserID, reason, actionContext)
-			if err != nil {
-				fmt.Println("Error in moderation action callback:", err)
-				return
-			}
+		default:
+			return
 		}
+
+		// The same action also reaches us through CLEARCHAT, whichever comes first is logged and published
+		pb2twitch.HandleModerationAction(a.sqlClient, a.twitchUserContext, a.pubSub, a, action)
 	})
 
 	return nil
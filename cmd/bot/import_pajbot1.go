@@ -0,0 +1,713 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/commands"
+	"github.com/pajlada/pajbot2/pkg/filters"
+	"github.com/pajlada/pajbot2/pkg/twitch"
+	"github.com/pajlada/pajbot2/pkg/users"
+	"github.com/pajlada/pajbot2/pkg/utils"
+)
+
+var importPajbot1Flags = flag.NewFlagSet("import-pajbot1", flag.ExitOnError)
+
+var (
+	importDryRun = importPajbot1Flags.Bool("dry-run", false, "Only report what would be imported, don't write anything")
+
+	importPointServerHost = importPajbot1Flags.String("pointserver", "localhost:54321", "Host of the point server that user points are imported into")
+
+	// The bot currently connects to the point server as pajlada in every channel, see Bot.ConnectToPointServer
+	importPointServerChannel = importPajbot1Flags.String("pointserver-channel", "pajlada", "Channel name to use when connecting to the point server")
+)
+
+// Variables pajbot1 commands can use that have a direct pajbot2 equivalent
+var pajbot1VariableReplacer = strings.NewReplacer(
+	"$(source:name)", "$(user)",
+	"$(source:username)", "$(user)",
+	"$(source:username_raw)", "$(user)",
+	"$(source:points)", "$(points)",
+	"$(1)", "$(args 1)",
+	"$(2)", "$(args 2)",
+	"$(3)", "$(args 3)",
+	"$(4)", "$(args 4)",
+	"$(5)", "$(args 5)",
+	"$(6)", "$(args 6)",
+	"$(7)", "$(args 7)",
+	"$(8)", "$(args 8)",
+	"$(9)", "$(args 9)",
+)
+
+var templateVariableRegex = regexp.MustCompile(`\$\(([^ )]*)`)
+
+// Variables that pajbot2 text commands understand, see commands.RenderTemplate
+var supportedTemplateVariables = map[string]bool{
+	"user":     true,
+	"channel":  true,
+	"args":     true,
+	"points":   true,
+	"uptime":   true,
+	"count":    true,
+	"random":   true,
+	"urlfetch": true,
+}
+
+// importReport keeps track of what was (or would be) imported, and what couldn't be
+type importReport struct {
+	name string
+
+	imported []string
+	skipped  []string
+}
+
+func (r *importReport) addImported(format string, a ...interface{}) {
+	r.imported = append(r.imported, fmt.Sprintf(format, a...))
+}
+
+func (r *importReport) addSkipped(format string, a ...interface{}) {
+	r.skipped = append(r.skipped, fmt.Sprintf(format, a...))
+}
+
+func (r *importReport) print(verbose bool) {
+	fmt.Printf("== %s: %d imported, %d not imported\n", r.name, len(r.imported), len(r.skipped))
+
+	if verbose {
+		for _, line := range r.imported {
+			fmt.Println("   +", line)
+		}
+	}
+
+	for _, line := range r.skipped {
+		fmt.Println("   -", line)
+	}
+}
+
+type pajbot1Importer struct {
+	app *Application
+
+	// pajbot1 database
+	old *sql.DB
+
+	// pajbot2 database
+	db *sql.DB
+
+	channelID   string
+	channelName string
+
+	dryRun bool
+}
+
+func importPajbot1Cmd(args []string) {
+	importPajbot1Flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: pajbot2 import-pajbot1 [-dry-run] [-pointserver HOST] [-pointserver-channel NAME] <channel>")
+		fmt.Fprintln(os.Stderr, "Imports banphrases, commands, user points and user levels from the pajbot1 database in the config file.")
+		fmt.Fprintln(os.Stderr, "Running it again only imports what's new, i.e. points users have earned in pajbot1 since the last import.")
+		importPajbot1Flags.PrintDefaults()
+	}
+
+	importPajbot1Flags.Parse(args)
+
+	if importPajbot1Flags.NArg() != 1 {
+		importPajbot1Flags.Usage()
+		os.Exit(1)
+	}
+
+	app := newApplication()
+
+	err := app.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Println("An error occured while loading the config file: ", err)
+		os.Exit(1)
+	}
+
+	err = app.InitializeAPIs()
+	if err != nil {
+		fmt.Println("An error occured while initializing APIs: ", err)
+		os.Exit(1)
+	}
+
+	err = app.InitializeSQL()
+	if err != nil {
+		fmt.Println("Error starting SQL client:", err)
+		os.Exit(1)
+	}
+
+	if !*importDryRun {
+		err = app.RunDatabaseMigrations()
+		if err != nil {
+			fmt.Println("An error occured while running database migrations: ", err)
+			os.Exit(1)
+		}
+	}
+
+	old, err := sql.Open("mysql", app.config.Pajbot1.SQL.DSN)
+	if err != nil {
+		fmt.Println("Error connecting to the pajbot1 database:", err)
+		os.Exit(1)
+	}
+
+	defer old.Close()
+
+	channelName := strings.ToLower(importPajbot1Flags.Arg(0))
+	channelID := app.UserStore().GetID(channelName)
+	if channelID == "" {
+		fmt.Println("Unable to find the twitch user ID of", channelName)
+		os.Exit(1)
+	}
+
+	importer := &pajbot1Importer{
+		app: app,
+		old: old,
+		db:  app.SQL(),
+
+		channelID:   channelID,
+		channelName: channelName,
+
+		dryRun: *importDryRun,
+	}
+
+	if importer.dryRun {
+		fmt.Printf("Dry run: nothing will be written. Reporting what would be imported into %s (%s)\n", channelName, channelID)
+	} else {
+		fmt.Printf("Importing pajbot1 data into %s (%s)\n", channelName, channelID)
+	}
+
+	steps := []func() (*importReport, error){
+		importer.importBanphrases,
+		importer.importCommands,
+		importer.disablePajbot1Commands,
+		importer.importUsers,
+	}
+
+	for _, step := range steps {
+		report, err := step()
+		if report != nil {
+			report.print(importer.dryRun)
+		}
+
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+
+	if !importer.dryRun {
+		fmt.Println("Done. Restart the bot to load the imported data")
+	}
+}
+
+// disablePajbot1Commands disables the pajbot1_commands module in the channel, since the imported commands are now answered by the commands module
+func (i *pajbot1Importer) disablePajbot1Commands() (*importReport, error) {
+	report := &importReport{name: "Modules"}
+
+	if i.dryRun {
+		report.addImported("pajbot1_commands would be disabled")
+		return report, nil
+	}
+
+	const queryF = `
+UPDATE
+	BotChannelModule
+INNER JOIN BotChannel ON BotChannel.id=BotChannelModule.bot_channel_id
+SET
+	BotChannelModule.enabled=0
+WHERE
+	BotChannelModule.module_id='pajbot1_commands' AND BotChannel.twitch_channel_id=?`
+
+	res, err := i.db.Exec(queryF, i.channelID)
+	if err != nil {
+		return report, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return report, err
+	}
+
+	report.addImported("pajbot1_commands disabled for %d bots", n)
+
+	return report, nil
+}
+
+func (i *pajbot1Importer) importBanphrases() (*importReport, error) {
+	report := &importReport{name: "Banphrases"}
+
+	type banphraseKey struct {
+		phrase   string
+		operator int
+	}
+
+	// Banphrases that already apply in the channel, i.e. from an earlier import
+	existing := make(map[banphraseKey]bool)
+
+	const existingQueryF = `SELECT b.phrase, COALESCE(b.type, g.type, 0) FROM Banphrase b LEFT JOIN BanphraseGroup g ON g.id=b.group_id WHERE b.channel_id IS NULL OR b.channel_id=?`
+
+	rows, err := i.db.Query(existingQueryF, i.channelID)
+	if err != nil {
+		return report, err
+	}
+
+	for rows.Next() {
+		var key banphraseKey
+		if err = rows.Scan(&key.phrase, &key.operator); err != nil {
+			rows.Close()
+			return report, err
+		}
+
+		existing[key] = true
+	}
+
+	rows.Close()
+
+	rows, err = i.old.Query(`SELECT * FROM tb_banphrase`)
+	if err != nil {
+		return report, err
+	}
+
+	defer rows.Close()
+
+	const insertQueryF = `
+INSERT INTO
+	Banphrase
+	(channel_id, enabled, description, phrase, length, case_sensitive, type, sub_immunity, remove_accents)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	for rows.Next() {
+		var bp filters.Pajbot1Banphrase
+		if err = bp.LoadScan(rows); err != nil {
+			return report, err
+		}
+
+		description := fmt.Sprintf("#%d %s (%s)", bp.ID, bp.Name, bp.Phrase)
+
+		key := banphraseKey{bp.Phrase, int(bp.Operator)}
+		if existing[key] {
+			report.addSkipped("%s: already exists", description)
+			continue
+		}
+
+		// In the Banphrase table, a length of 0 means permaban
+		length := bp.Length
+		if bp.Permanent {
+			length = 0
+		} else if length <= 0 {
+			report.addSkipped("%s: timeout length %d can't be represented", description, bp.Length)
+			continue
+		}
+
+		if !i.dryRun {
+			_, err = i.db.Exec(insertQueryF, i.channelID, bp.Enabled, bp.Name, bp.Phrase, length, bp.CaseSensitive, int(bp.Operator), bp.SubImmunity, bp.RemoveAccents)
+			if err != nil {
+				return report, err
+			}
+		}
+
+		existing[key] = true
+
+		var notes []string
+		if bp.Warning {
+			notes = append(notes, "warnings are not imported, a regular timeout is used")
+		}
+		if bp.Notify {
+			notes = append(notes, "notify is not supported")
+		}
+
+		if len(notes) > 0 {
+			report.addImported("%s: %s", description, strings.Join(notes, ", "))
+		} else {
+			report.addImported("%s", description)
+		}
+	}
+
+	return report, rows.Err()
+}
+
+// pajbot1Command is a row of the pajbot1 tb_command table
+type pajbot1Command struct {
+	ID       int
+	Level    int
+	Action   string
+	Triggers string
+
+	GlobalCooldown int
+	UserCooldown   int
+
+	Enabled bool
+
+	PointCost int
+	TokenCost int
+
+	SubOnly bool
+	ModOnly bool
+}
+
+type pajbot1CommandAction struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// toTextCommand converts the pajbot1 command to a pajbot2 text command. If that's not possible, the reason is returned as an error
+func (c *pajbot1Command) toTextCommand(channelID string) (*commands.TextCommand, error) {
+	var action pajbot1CommandAction
+	if err := json.Unmarshal([]byte(c.Action), &action); err != nil {
+		return nil, fmt.Errorf("unable to parse action: %s", err)
+	}
+
+	textCommand := commands.NewTextCommand(channelID)
+
+	switch action.Type {
+	case "say", "me":
+		textCommand.ResponseType = commands.ResponseTypeSay
+	case "whisper":
+		textCommand.ResponseType = commands.ResponseTypeWhisper
+	case "reply":
+		textCommand.ResponseType = commands.ResponseTypeReply
+	default:
+		return nil, fmt.Errorf("action type %q is not supported", action.Type)
+	}
+
+	if c.TokenCost > 0 {
+		return nil, fmt.Errorf("token costs are not supported")
+	}
+
+	if c.PointCost < 0 {
+		return nil, fmt.Errorf("negative point cost %d", c.PointCost)
+	}
+
+	triggers, err := commands.ParseTriggers(c.Triggers)
+	if err != nil {
+		return nil, err
+	}
+
+	response := pajbot1VariableReplacer.Replace(action.Message)
+
+	var unsupported []string
+	for _, match := range templateVariableRegex.FindAllStringSubmatch(response, -1) {
+		if !supportedTemplateVariables[strings.ToLower(match[1])] {
+			unsupported = append(unsupported, "$("+match[1]+")")
+		}
+	}
+
+	if len(unsupported) > 0 {
+		return nil, fmt.Errorf("unsupported variables %s", strings.Join(unsupported, ", "))
+	}
+
+	level := c.Level
+	if c.SubOnly && level < commands.LevelSubscriber {
+		level = commands.LevelSubscriber
+	}
+	if c.ModOnly && level < commands.LevelModerator {
+		level = commands.LevelModerator
+	}
+	if level < commands.LevelUser {
+		level = commands.LevelUser
+	}
+
+	textCommand.Triggers = triggers
+	textCommand.Response = response
+	textCommand.Level = level
+	textCommand.GlobalCooldown = c.GlobalCooldown
+	textCommand.UserCooldown = c.UserCooldown
+	textCommand.PointCost = uint64(c.PointCost)
+	textCommand.Enabled = c.Enabled
+
+	return textCommand, textCommand.Validate()
+}
+
+func (i *pajbot1Importer) importCommands() (*importReport, error) {
+	report := &importReport{name: "Commands"}
+
+	existing, err := commands.LoadTextCommands(i.db, i.channelID)
+	if err != nil {
+		return report, err
+	}
+
+	const queryF = `SELECT id, level, action, command, delay_all, delay_user, enabled, cost, tokens_cost, sub_only, mod_only FROM tb_command ORDER BY id`
+
+	rows, err := i.old.Query(queryF)
+	if err != nil {
+		return report, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var c pajbot1Command
+		err = rows.Scan(&c.ID, &c.Level, &c.Action, &c.Triggers, &c.GlobalCooldown, &c.UserCooldown, &c.Enabled, &c.PointCost, &c.TokenCost, &c.SubOnly, &c.ModOnly)
+		if err != nil {
+			return report, err
+		}
+
+		description := fmt.Sprintf("#%d !%s", c.ID, strings.Replace(c.Triggers, "|", ", !", -1))
+
+		textCommand, err := c.toTextCommand(i.channelID)
+		if err != nil {
+			report.addSkipped("%s: %s", description, err)
+			continue
+		}
+
+		if trigger := findTriggerConflict(existing, textCommand); trigger != "" {
+			report.addSkipped("%s: a command with the trigger !%s already exists", description, trigger)
+			continue
+		}
+
+		if !i.dryRun {
+			if err = textCommand.Save(i.db); err != nil {
+				return report, err
+			}
+		}
+
+		existing = append(existing, textCommand)
+
+		report.addImported("%s: level %d, cooldown %ds/%ds, cost %d", description, textCommand.Level, textCommand.GlobalCooldown, textCommand.UserCooldown, textCommand.PointCost)
+	}
+
+	return report, rows.Err()
+}
+
+func findTriggerConflict(existing []*commands.TextCommand, c *commands.TextCommand) string {
+	for _, trigger := range c.Triggers {
+		for _, other := range existing {
+			if other.HasTrigger(trigger) {
+				return trigger
+			}
+		}
+	}
+
+	return ""
+}
+
+// pajbot1User is a row of the pajbot1 tb_user table
+type pajbot1User struct {
+	Username string
+	Level    int
+	Points   int64
+}
+
+// channelPermissions returns the pajbot2 channel permissions that best match the users pajbot1 level
+func (u *pajbot1User) channelPermissions() pkg.Permission {
+	var permissions pkg.Permission
+
+	if u.Level >= commands.LevelModerator {
+		permissions |= pkg.PermissionModeration
+	}
+
+	if u.Level >= commands.LevelBroadcaster {
+		permissions |= pkg.PermissionRaffle
+	}
+
+	return permissions
+}
+
+// loadImportedPoints returns the pajbot1 points that earlier imports have given users in the channel, by user ID
+func (i *pajbot1Importer) loadImportedPoints() (map[string]int64, error) {
+	const queryF = `SELECT user_id, points FROM Pajbot1ImportedPoints WHERE channel_id=?`
+
+	rows, err := i.db.Query(queryF, i.channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	importedPoints := make(map[string]int64)
+
+	for rows.Next() {
+		var userID string
+		var points int64
+		if err = rows.Scan(&userID, &points); err != nil {
+			return nil, err
+		}
+
+		importedPoints[userID] = points
+	}
+
+	return importedPoints, rows.Err()
+}
+
+// recordImportedPoints remembers how many pajbot1 points the user has been given in total
+func (i *pajbot1Importer) recordImportedPoints(userID string, points int64) error {
+	const queryF = `
+INSERT INTO
+	Pajbot1ImportedPoints
+	(channel_id, user_id, points)
+	VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE points=?`
+
+	_, err := i.db.Exec(queryF, i.channelID, userID, points, points)
+	return err
+}
+
+func (i *pajbot1Importer) importUsers() (*importReport, error) {
+	report := &importReport{name: "Users"}
+
+	const queryF = `SELECT username, level, points FROM tb_user WHERE level>? OR points>0`
+
+	rows, err := i.old.Query(queryF, commands.LevelUser)
+	if err != nil {
+		return report, err
+	}
+
+	var pajbot1Users []*pajbot1User
+	var usernames []string
+
+	for rows.Next() {
+		u := &pajbot1User{}
+		if err = rows.Scan(&u.Username, &u.Level, &u.Points); err != nil {
+			rows.Close()
+			return report, err
+		}
+
+		u.Username = strings.ToLower(u.Username)
+
+		pajbot1Users = append(pajbot1Users, u)
+		usernames = append(usernames, u.Username)
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return report, err
+	}
+
+	userIDs := i.app.UserStore().GetIDs(usernames)
+
+	// The point server can only add points, so we only give users the points they earned since the last import
+	importedPoints, err := i.loadImportedPoints()
+	if err != nil {
+		return report, err
+	}
+
+	var pointServer *pointServerConnection
+
+	if !i.dryRun {
+		pointServer, err = dialPointServer(*importPointServerHost, *importPointServerChannel)
+		if err != nil {
+			return report, fmt.Errorf("unable to connect to the point server: %s", err)
+		}
+
+		defer pointServer.Close()
+	}
+
+	for _, u := range pajbot1Users {
+		userID, ok := userIDs[u.Username]
+		if !ok {
+			report.addSkipped("%s: unable to find twitch user ID, the account might have been deleted or renamed", u.Username)
+			continue
+		}
+
+		var imported []string
+
+		if newPoints := u.Points - importedPoints[userID]; newPoints > 0 {
+			if !i.dryRun {
+				if err = pointServer.addPoints(userID, uint64(newPoints)); err != nil {
+					return report, err
+				}
+
+				if err = i.recordImportedPoints(userID, u.Points); err != nil {
+					return report, err
+				}
+			}
+
+			imported = append(imported, fmt.Sprintf("%d points", newPoints))
+		} else if u.Points > 0 {
+			report.addSkipped("%s: %d points were already imported", u.Username, importedPoints[userID])
+		}
+
+		if permissions := u.channelPermissions(); permissions != pkg.PermissionNone {
+			if !i.dryRun {
+				oldPermissions, err := users.GetUserChannelPermissions(userID, i.channelID)
+				if err != nil {
+					return report, err
+				}
+
+				if err = users.SetUserChannelPermissions(userID, i.channelID, oldPermissions|permissions); err != nil {
+					return report, err
+				}
+			}
+
+			imported = append(imported, fmt.Sprintf("level %d", u.Level))
+		}
+
+		if u.Level >= commands.LevelAdmin {
+			report.addSkipped("%s: level %d was imported as a channel level, global admin permissions must be given manually", u.Username, u.Level)
+		}
+
+		if len(imported) > 0 {
+			report.addImported("%s (%s): %s", u.Username, userID, strings.Join(imported, ", "))
+		}
+	}
+
+	return report, nil
+}
+
+// pointServerConnection is a synchronous connection to the point server, used so we know that every import actually went through
+type pointServerConnection struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialPointServer(host, channelName string) (*pointServerConnection, error) {
+	conn, err := net.DialTimeout("tcp", host, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pointServerConnection{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+
+	if err = p.send(twitch.CommandConnect, []byte(channelName)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *pointServerConnection) send(command uint8, body []byte) error {
+	bodyLength := make([]byte, 4)
+	binary.BigEndian.PutUint32(bodyLength, uint32(len(body)))
+
+	// Header (Command + Body length) followed by the body
+	_, err := p.conn.Write(append(append([]byte{command}, bodyLength...), body...))
+	return err
+}
+
+func (p *pointServerConnection) addPoints(userID string, points uint64) error {
+	var bodyPayload []byte
+	bodyPayload = append(bodyPayload, utils.Uint64ToBytes(points)...)
+	bodyPayload = append(bodyPayload, []byte(userID)...)
+
+	if err := p.send(twitch.CommandAdd, bodyPayload); err != nil {
+		return err
+	}
+
+	response := make([]byte, 9)
+	if _, err := io.ReadFull(p.reader, response); err != nil {
+		return err
+	}
+
+	if response[0] > 0 {
+		return fmt.Errorf("point server refused to add %d points to %s", points, userID)
+	}
+
+	return nil
+}
+
+func (p *pointServerConnection) Close() error {
+	return p.conn.Close()
+}
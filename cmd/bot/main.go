@@ -70,6 +70,9 @@ func main() {
 	case "fix":
 		fixCmd()
 
+	case "import-pajbot1":
+		importPajbot1Cmd(flag.Args()[1:])
+
 	default:
 		fallthrough
 	case "run":
@@ -86,6 +89,7 @@ Commands:
    check          Check the config file for missing fields
    install        Start the installation process (WIP)
    create <name>  Create a migration (WIP)
+   import-pajbot1 Import banphrases, commands and users from pajbot1 (see import-pajbot1 -h)
    newbot         Create a new bot
    linkchannel    Link a channel to a bot ID
 `)
@@ -22,30 +22,77 @@ var _ pkg.StreamStore = &StreamStore{}
 type StreamStore struct {
 	mutex   *sync.Mutex
 	streams map[string]*twitch.Stream
+
+	pubSub       pkg.PubSub
+	pubSubSource pkg.PubSubSource
 }
 
-func NewStreamStore() *StreamStore {
+func NewStreamStore(pubSub pkg.PubSub, pubSubSource pkg.PubSubSource) *StreamStore {
 	s := &StreamStore{
 		mutex:   &sync.Mutex{},
 		streams: make(map[string]*twitch.Stream),
+
+		pubSub:       pubSub,
+		pubSubSource: pubSubSource,
 	}
 
 	return s
 }
 
+// We assume that mutex is locked already
+func (s *StreamStore) updateStream(stream *twitch.Stream, data *pkg.StreamData) {
+	if !stream.Update(data) {
+		return
+	}
+
+	if data == nil {
+		fmt.Println("Stream went offline:", stream.ID)
+		s.pubSub.Publish(s.pubSubSource, "StreamOffline", &pkg.PubSubStreamEvent{
+			ChannelID: stream.ID,
+		})
+		return
+	}
+
+	fmt.Println("Stream went online:", stream.ID)
+	s.pubSub.Publish(s.pubSubSource, "StreamOnline", &pkg.PubSubStreamEvent{
+		ChannelID: stream.ID,
+		Title:     data.Title,
+		StartedAt: &data.StartedAt,
+	})
+}
+
+// PollStreams polls the status of streams we haven't polled yet, or haven't heard about in a while.
+// The streams webhook should tell us about any changes, so this is mainly a fallback
 func (s *StreamStore) PollStreams() {
 	s.mutex.Lock()
 
 	var remaining []string
+	var subscribe []string
 
 	for streamID, stream := range s.streams {
 		if stream.NeedsInitialPoll() {
+			subscribe = append(subscribe, streamID)
+		}
+
+		if stream.NeedsPoll() {
 			remaining = append(remaining, streamID)
 		}
 	}
 
 	s.mutex.Unlock()
 
+	// Subscribe to the streams and followers webhook topics for the channels as well
+	for _, userID := range subscribe {
+		go func(userID string) {
+			for _, topic := range []gotwitch.WebhookTopic{gotwitch.WebhookTopicStreams, gotwitch.WebhookTopicFollows} {
+				err := apirequest.TwitchWrapper.WebhookSubscribe(topic, userID)
+				if err != nil {
+					fmt.Println("Error subscribing to webhook for user", userID, err)
+				}
+			}
+		}(userID)
+	}
+
 	if len(remaining) == 0 {
 		return
 	}
@@ -58,33 +105,28 @@ func (s *StreamStore) PollStreams() {
 	for _, batch := range batches {
 		wg.Add(1)
 
-		// Subscribe to the streams webhook topic for the channels as well
 		go func(batch []string) {
-			for _, userID := range batch {
-				go func(userID string) {
-					err := apirequest.TwitchWrapper.WebhookSubscribe(gotwitch.WebhookTopicStreams, userID)
-					if err != nil {
-						fmt.Println("Error subscribing to webhook for user", userID, err)
-					}
-				}(userID)
-			}
-		}(batch)
-
-		go func() {
 			defer wg.Done()
 			data, err := apirequest.TwitchWrapper.GetStreams(batch, nil)
 			if err != nil {
 				fmt.Println("api error:", err)
+				return
+			}
+
+			liveStreams := make(map[string]*pkg.StreamData)
+			for i := range data {
+				liveStreams[data[i].UserID] = twitch.NewStreamData(&data[i])
 			}
 
 			s.mutex.Lock()
 			defer s.mutex.Unlock()
-			for _, activeStream := range data {
-				if stream, ok := s.streams[activeStream.UserID]; ok {
-					stream.Update(&activeStream)
+			// Streams that are not in the response are offline
+			for _, userID := range batch {
+				if stream, ok := s.streams[userID]; ok {
+					s.updateStream(stream, liveStreams[userID])
 				}
 			}
-		}()
+		}(batch)
 	}
 
 	wg.Wait()
@@ -123,3 +165,12 @@ func (s *StreamStore) JoinStream(account pkg.Account) {
 		s.streams[account.ID()] = twitch.NewTwitchStream(account)
 	}
 }
+
+func (s *StreamStore) UpdateStream(channelID string, data *pkg.StreamData) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if stream, ok := s.streams[channelID]; ok {
+		s.updateStream(stream, data)
+	}
+}
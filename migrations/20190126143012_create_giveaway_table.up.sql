@@ -0,0 +1,15 @@
+CREATE TABLE `Giveaway` (
+	`id` INT(11) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`channel_id` VARCHAR(64) NOT NULL COMMENT 'twitch ID of channel the giveaway was held in',
+	`keyword` VARCHAR(128) NOT NULL COMMENT 'keyword or emote name users type to enter the giveaway',
+	`requirement` VARCHAR(32) NOT NULL DEFAULT '' COMMENT 'empty, "follower" or "subscriber"',
+	`cost` INT(11) UNSIGNED NOT NULL DEFAULT 0 COMMENT 'number of points it costs to enter the giveaway',
+	`started_by_id` VARCHAR(64) NOT NULL COMMENT 'twitch user ID of the user who started the giveaway',
+	`started_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`stopped_at` TIMESTAMP NULL DEFAULT NULL COMMENT 'time the giveaway stopped accepting entries',
+	PRIMARY KEY (`id`),
+	INDEX `channel_id` (`channel_id`)
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
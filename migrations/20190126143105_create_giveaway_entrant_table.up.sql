@@ -0,0 +1,20 @@
+CREATE TABLE `GiveawayEntrant` (
+	`id` INT(11) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`giveaway_id` INT(11) UNSIGNED NOT NULL,
+	`user_id` VARCHAR(64) NOT NULL COMMENT 'twitch user ID of the entrant',
+	`user_name` VARCHAR(64) NOT NULL COMMENT 'twitch user name of the entrant',
+	`entered_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`winner` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'set to 1 if the entrant has been drawn as a winner',
+	`rerolled` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'set to 1 if the entrant was drawn as a winner, but was re-drawn',
+
+	PRIMARY KEY (`id`),
+
+	FOREIGN KEY (giveaway_id)
+		REFERENCES Giveaway(id)
+		ON DELETE CASCADE,
+
+	UNIQUE INDEX `giveaway_user` (giveaway_id, user_id)
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,18 @@
+CREATE TABLE `Command` (
+	`id` INT(11) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`channel_id` VARCHAR(64) NOT NULL COMMENT 'twitch ID of channel the command belongs to',
+	`triggers` VARCHAR(512) NOT NULL COMMENT 'Each trigger is divided by a pipe character "|". No !\'s allowed in command names. Example: testman|testman1|anotheralias',
+	`response` VARCHAR(1024) NOT NULL,
+	`response_type` ENUM('say','whisper','reply') NOT NULL DEFAULT 'say',
+	`level` INT(11) NOT NULL DEFAULT '100' COMMENT 'User level required to use the command',
+	`cooldown_all` INT(11) NOT NULL DEFAULT '5' COMMENT 'global cooldown in seconds',
+	`cooldown_user` INT(11) NOT NULL DEFAULT '15' COMMENT 'per-user cooldown in seconds',
+	`cost_points` INT(10) UNSIGNED NOT NULL DEFAULT '0',
+	`enabled` BOOLEAN NOT NULL DEFAULT TRUE,
+
+	PRIMARY KEY (`id`),
+	INDEX `channel_id` (`channel_id`)
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,2 @@
+ALTER TABLE `Command`
+	ADD COLUMN `count` INT(11) UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Number of times the command has been used, used by the $(count) variable' AFTER `enabled`;
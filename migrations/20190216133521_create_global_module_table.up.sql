@@ -0,0 +1,11 @@
+CREATE TABLE `GlobalModule` (
+    `module_id` VARCHAR(128) NOT NULL COMMENT 'i.e. nuke',
+    `enabled` BOOLEAN NULL COMMENT 'if null, it uses the modules default enabled value',
+    `settings` BLOB NULL COMMENT 'json blob with settings',
+
+    PRIMARY KEY(`module_id`)
+)
+COMMENT='Store the state of modules that are not tied to a bot channel'
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,11 @@
+CREATE TABLE `SharedBanGroup` (
+	`id` INT(11) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`name` VARCHAR(64) NOT NULL COMMENT 'Lowercase name used when joining the group, i.e. "forsen-network"',
+
+	PRIMARY KEY (`id`),
+	UNIQUE INDEX `name` (`name`)
+)
+COMMENT='Groups of channels that share their bans with each other'
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,12 @@
+CREATE TABLE `SharedBanGroupChannel` (
+	`group_id` INT(11) UNSIGNED NOT NULL,
+	`channel_id` VARCHAR(64) NOT NULL COMMENT 'twitch ID of the channel that opted into the group',
+	`auto_apply` BOOLEAN NOT NULL DEFAULT FALSE COMMENT 'Apply bans from the other channels in the group without waiting for approval',
+
+	PRIMARY KEY (`group_id`, `channel_id`),
+	INDEX `channel_id` (`channel_id`),
+	CONSTRAINT `FK_SharedBanGroupChannel_SharedBanGroup` FOREIGN KEY (`group_id`) REFERENCES `SharedBanGroup` (`id`) ON DELETE CASCADE
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
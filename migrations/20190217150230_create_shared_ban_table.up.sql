@@ -0,0 +1,24 @@
+CREATE TABLE `SharedBan` (
+	`id` INT(11) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`group_id` INT(11) UNSIGNED NOT NULL,
+	`origin_channel_id` VARCHAR(64) NOT NULL COMMENT 'twitch ID of the channel the user was banned in',
+	`channel_id` VARCHAR(64) NOT NULL COMMENT 'twitch ID of the channel the ban is proposed to',
+	`user_id` VARCHAR(64) NOT NULL,
+	`user_name` VARCHAR(64) NOT NULL,
+	`source_id` VARCHAR(64) NOT NULL COMMENT 'twitch ID of the moderator or bot that banned the user in the origin channel',
+	`source_name` VARCHAR(64) NOT NULL,
+	`reason` VARCHAR(512) NOT NULL DEFAULT '',
+	`state` TINYINT(3) UNSIGNED NOT NULL DEFAULT '0' COMMENT '0 = pending, 1 = applied, 2 = denied, 3 = allowlisted',
+	`time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`handler_id` VARCHAR(64) NULL DEFAULT NULL,
+	`handler_name` VARCHAR(64) NULL DEFAULT NULL,
+	`time_handled` DATETIME NULL DEFAULT NULL,
+
+	PRIMARY KEY (`id`),
+	UNIQUE INDEX `channel_user` (`channel_id`, `user_id`),
+	CONSTRAINT `FK_SharedBan_SharedBanGroup` FOREIGN KEY (`group_id`) REFERENCES `SharedBanGroup` (`id`) ON DELETE CASCADE
+)
+COMMENT='Bans that were proposed to, or applied in, a channel because the user was banned in another channel of the same group'
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
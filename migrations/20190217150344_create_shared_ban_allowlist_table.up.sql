@@ -0,0 +1,12 @@
+CREATE TABLE `SharedBanAllowlist` (
+	`channel_id` VARCHAR(64) NOT NULL,
+	`user_id` VARCHAR(64) NOT NULL COMMENT 'twitch ID of the user that should never be banned in this channel by a shared ban',
+	`user_name` VARCHAR(64) NOT NULL,
+	`added_by_id` VARCHAR(64) NOT NULL,
+	`time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (`channel_id`, `user_id`)
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
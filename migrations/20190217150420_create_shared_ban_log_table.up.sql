@@ -0,0 +1,17 @@
+CREATE TABLE `SharedBanLog` (
+	`id` INT(11) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`channel_id` VARCHAR(64) NOT NULL COMMENT 'twitch ID of the channel the entry is relevant to',
+	`shared_ban_id` INT(11) UNSIGNED NULL DEFAULT NULL,
+	`action` VARCHAR(32) NOT NULL COMMENT 'i.e. proposed, applied, denied',
+	`actor_id` VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'twitch ID of the user that performed the action. Empty if the bot performed it on its own',
+	`actor_name` VARCHAR(64) NOT NULL DEFAULT '',
+	`details` VARCHAR(512) NOT NULL DEFAULT '',
+	`time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (`id`),
+	INDEX `channel_id` (`channel_id`)
+)
+COMMENT='Audit log of everything that happens to shared bans'
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,14 @@
+CREATE TABLE `BotChannelPermaMode` (
+	`bot_channel_id` INT(11) UNSIGNED NOT NULL,
+    `mode` VARCHAR(32) NOT NULL COMMENT 'i.e. subscribers',
+    `argument` VARCHAR(32) NOT NULL DEFAULT '' COMMENT 'argument to the chat command, i.e. 30 for 30 second slow mode',
+
+    PRIMARY KEY(`bot_channel_id`, `mode`),
+
+    FOREIGN KEY (bot_channel_id)
+        REFERENCES BotChannel(id)
+        ON DELETE CASCADE
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
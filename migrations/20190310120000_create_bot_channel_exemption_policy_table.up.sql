@@ -0,0 +1,13 @@
+CREATE TABLE `BotChannelExemptionPolicy` (
+	`bot_channel_id` INT(11) UNSIGNED NOT NULL,
+    `policy` TEXT NOT NULL COMMENT 'json encoded pkg.ExemptionPolicy',
+
+    PRIMARY KEY(`bot_channel_id`),
+
+    FOREIGN KEY (bot_channel_id)
+        REFERENCES BotChannel(id)
+        ON DELETE CASCADE
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,8 @@
+-- The filters used to leave these bots alone in every channel, keep doing that for channels that existed before exemption policies
+INSERT INTO `BotChannelExemptionPolicy`
+	(`bot_channel_id`, `policy`)
+SELECT
+	`id`, '{"Roles":["broadcaster","moderator"],"Users":["supibot","gazatu2","titlechange_bot"]}'
+FROM
+	`BotChannel`
+;
@@ -0,0 +1 @@
+ALTER TABLE `Banphrase` ADD COLUMN `channel_id` VARCHAR(64) NULL DEFAULT NULL COMMENT 'twitch ID of channel the banphrase applies in, NULL = every channel' AFTER `group_id`, ADD INDEX `channel_id` (`channel_id`);
@@ -0,0 +1,11 @@
+CREATE TABLE `Pajbot1ImportedPoints` (
+	`channel_id` VARCHAR(64) NOT NULL COMMENT 'twitch ID of channel the points were imported into',
+    `user_id` VARCHAR(64) NOT NULL COMMENT 'twitch ID of user the points were given to',
+    `points` BIGINT(20) UNSIGNED NOT NULL COMMENT 'pajbot1 points that have been added to the point server so far',
+
+    PRIMARY KEY(`channel_id`, `user_id`)
+)
+COMMENT='Keeps track of the points import-pajbot1 has given out, so running it again does not add them again'
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
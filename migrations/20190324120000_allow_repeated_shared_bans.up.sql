@@ -0,0 +1 @@
+ALTER TABLE `SharedBan` DROP INDEX `channel_user`, ADD INDEX `channel_user` (`channel_id`, `user_id`);
@@ -0,0 +1 @@
+UPDATE BotChannelModule INNER JOIN BotChannel ON BotChannel.id=BotChannelModule.bot_channel_id SET BotChannelModule.enabled=0 WHERE BotChannelModule.module_id='message_length_limit' AND BotChannel.twitch_channel_id<>'22484632';
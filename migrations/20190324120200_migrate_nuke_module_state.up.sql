@@ -0,0 +1 @@
+INSERT INTO GlobalModule (module_id, enabled) SELECT 'nuke', 0 FROM BotChannelModule WHERE module_id='nuke' HAVING COUNT(*) > 0 AND SUM(IFNULL(enabled, 1))=0 ON DUPLICATE KEY UPDATE enabled=0;
@@ -1,16 +1,41 @@
 package pkg
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+	"time"
+)
 
 type ActionType interface {
 	Do(Sender, Channel, User) error
 	Priority() int
+
+	// Human readable description of the action, i.e. "timeout 300s: No links allowed"
+	String() string
+}
+
+// ActionCandidate is an action that was proposed for a message
+type ActionCandidate struct {
+	// ID of the module that proposed the action. Empty if the action was set from outside a module
+	Source string
+
+	Action ActionType
+
+	// Set if this is the action that will be, or has been, carried out
+	Chosen bool
 }
 
 type Action interface {
+	// Carries out the chosen action. The action is only carried out once, no matter how many times Do is called
 	Do() error
 	Set(ActionType)
 
+	// Propose is like Set, but remembers which module proposed the action
+	Propose(source string, action ActionType)
+
+	// All actions that have been proposed, in the order they were proposed
+	Candidates() []ActionCandidate
+
 	NotifyModerator() User
 	SetNotifyModerator(User)
 }
@@ -26,9 +51,34 @@ type TwitchAction struct {
 
 	action ActionType
 
+	candidates []ActionCandidate
+
+	// Index of the chosen action in candidates
+	chosen int
+
+	performed bool
+
 	notifyModerator User
 }
 
+type sourcedAction struct {
+	Action
+
+	source string
+}
+
+func (a *sourcedAction) Set(action ActionType) {
+	a.Action.Propose(a.source, action)
+}
+
+// ActionFromSource returns an Action that remembers source as the proposer of any action that's set through it
+func ActionFromSource(action Action, source string) Action {
+	return &sourcedAction{
+		Action: action,
+		source: source,
+	}
+}
+
 type Timeout struct {
 	Duration int
 	Reason   string
@@ -44,6 +94,10 @@ func (a Timeout) Priority() int {
 	return 100 + a.Duration
 }
 
+func (a Timeout) String() string {
+	return fmt.Sprintf("timeout %ds: %s", a.Duration, a.Reason)
+}
+
 type Ban struct {
 	Reason string
 }
@@ -58,25 +112,156 @@ func (a Ban) Priority() int {
 	return 0
 }
 
-func (a TwitchAction) Do() error {
-	if a.action != nil {
-		if a.NotifyModerator() != nil {
-			a.Sender.Whisper(a.NotifyModerator(), fmt.Sprintf("%s triggered bad banphrase in %s", a.User.GetName(), a.Channel.GetChannel()))
+func (a Ban) String() string {
+	return "ban: " + a.Reason
+}
+
+// Twitch doesn't allow timeouts longer than two weeks
+const maxTimeoutDuration = 1209600
+
+// Delete removes just the message that the action was chosen for
+type Delete struct {
+	// Twitch's ID of the message, see Message.GetID
+	MessageID string
+
+	Reason string
+}
+
+func (a Delete) Do(sender Sender, channel Channel, user User) error {
+	sender.Delete(channel, user, a.MessageID)
+
+	return nil
+}
+
+// Priority makes any timeout or ban win over a delete, since they also remove the message
+func (a Delete) Priority() int {
+	return 200 + maxTimeoutDuration
+}
+
+func (a Delete) String() string {
+	return "delete: " + a.Reason
+}
+
+var (
+	// When users were last warned, by channel ID and user ID
+	lastWarningsMutex sync.Mutex
+	lastWarnings      = make(map[string]time.Time)
+)
+
+// allowWarning returns true if the user hasn't been warned in the channel within the cooldown, and remembers that they're warned now
+func allowWarning(channel Channel, user User, cooldown time.Duration, now time.Time) bool {
+	lastWarningsMutex.Lock()
+	defer lastWarningsMutex.Unlock()
+
+	for key, lastWarning := range lastWarnings {
+		// No filter uses a cooldown this long, so the entry can't matter anymore
+		if now.Sub(lastWarning) >= 24*time.Hour {
+			delete(lastWarnings, key)
 		}
-		return a.action.Do(a.Sender, a.Channel, a.User)
+	}
+
+	key := channel.GetID() + ":" + user.GetID()
+
+	if lastWarning, ok := lastWarnings[key]; ok && now.Sub(lastWarning) < cooldown {
+		return false
+	}
+
+	lastWarnings[key] = now
+
+	return true
+}
+
+// Warn carries out a punishment and tells the user why they got it
+type Warn struct {
+	// The punishment, i.e. a Timeout or Delete. May be nil to only warn the user
+	Action ActionType
+
+	// Rendered explanation, i.e. "No links allowed"
+	Message string
+
+	// Whisper the user instead of mentioning them in chat
+	Whisper bool
+
+	// A user is warned at most once every Cooldown in a channel, no matter which filter warns them. The punishment is carried out regardless
+	Cooldown time.Duration
+}
+
+func (a Warn) Do(sender Sender, channel Channel, user User) error {
+	if a.Action != nil {
+		if err := a.Action.Do(sender, channel, user); err != nil {
+			return err
+		}
+	}
+
+	if !allowWarning(channel, user, a.Cooldown, time.Now()) {
+		return nil
+	}
+
+	if a.Whisper {
+		sender.Whisper(user, a.Message)
+	} else {
+		sender.Mention(channel, user, a.Message)
 	}
 
 	return nil
 }
 
+// Priority is the priority of the punishment. A warning without a punishment loses to any other action
+func (a Warn) Priority() int {
+	if a.Action != nil {
+		return a.Action.Priority()
+	}
+
+	return 300 + maxTimeoutDuration
+}
+
+func (a Warn) String() string {
+	if a.Action != nil {
+		return fmt.Sprintf("%s (warned: %s)", a.Action, a.Message)
+	}
+
+	return "warn: " + a.Message
+}
+
+func (a *TwitchAction) Do() error {
+	if a.action == nil || a.performed {
+		return nil
+	}
+
+	a.performed = true
+
+	if a.NotifyModerator() != nil {
+		a.Sender.Whisper(a.NotifyModerator(), fmt.Sprintf("%s triggered bad banphrase in %s", a.User.GetName(), a.Channel.GetChannel()))
+	}
+
+	return a.action.Do(a.Sender, a.Channel, a.User)
+}
+
 func (a *TwitchAction) Set(action ActionType) {
-	if a.action == nil {
+	a.Propose("", action)
+}
+
+func (a *TwitchAction) Propose(source string, action ActionType) {
+	a.candidates = append(a.candidates, ActionCandidate{
+		Source: source,
+		Action: action,
+	})
+
+	if a.action == nil || a.action.Priority() > action.Priority() {
 		a.action = action
-	} else {
-		if a.action.Priority() > action.Priority() {
-			a.action = action
-		}
+		a.chosen = len(a.candidates) - 1
+	}
+}
+
+func (a *TwitchAction) Candidates() []ActionCandidate {
+	candidates := make([]ActionCandidate, len(a.candidates))
+	copy(candidates, a.candidates)
+
+	if a.action != nil {
+		candidates[a.chosen].Chosen = true
 	}
+
+	return candidates
 }
 
 func (a TwitchAction) NotifyModerator() User {
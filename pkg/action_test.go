@@ -0,0 +1,78 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+type testChannel struct{}
+
+func (c testChannel) GetChannel() string { return "pajlada" }
+func (c testChannel) GetID() string      { return "11148817" }
+
+type testUser struct{}
+
+func (u testUser) HasPermission(Channel, Permission) bool        { return false }
+func (u testUser) HasGlobalPermission(Permission) bool           { return false }
+func (u testUser) HasChannelPermission(Channel, Permission) bool { return false }
+func (u testUser) GetName() string                               { return "testman" }
+func (u testUser) GetDisplayName() string                        { return "TestMan" }
+func (u testUser) GetID() string                                 { return "1" }
+func (u testUser) IsModerator() bool                             { return false }
+func (u testUser) IsBroadcaster(Channel) bool                    { return false }
+func (u testUser) IsSubscriber() bool                            { return false }
+func (u testUser) GetBadges() map[string]int                     { return nil }
+
+func TestActionCandidates(t *testing.T) {
+	action := &TwitchAction{}
+
+	ActionFromSource(action, "link_filter").Set(Timeout{180, "No links allowed"})
+	ActionFromSource(action, "banned_names").Set(Ban{"Ban evasion"})
+	action.Set(Timeout{600, "xd"})
+
+	candidates := action.Candidates()
+	if len(candidates) != 3 {
+		t.Fatalf("Expected 3 candidates, got %d", len(candidates))
+	}
+
+	for i, candidate := range candidates {
+		if candidate.Chosen != (i == 1) {
+			t.Errorf("Only the ban should be chosen, got %+v", candidates)
+		}
+	}
+
+	if candidates[0].Source != "link_filter" || candidates[1].Source != "banned_names" || candidates[2].Source != "" {
+		t.Errorf("Unexpected candidate sources: %+v", candidates)
+	}
+}
+
+func TestDeleteLosesToTimeout(t *testing.T) {
+	action := &TwitchAction{}
+
+	action.Set(Delete{"abc", "Your message is too long"})
+	action.Set(Timeout{maxTimeoutDuration, "xd"})
+
+	candidates := action.Candidates()
+	if candidates[0].Chosen || !candidates[1].Chosen {
+		t.Errorf("Expected the timeout to be chosen over the delete, got %+v", candidates)
+	}
+}
+
+func TestAllowWarning(t *testing.T) {
+	channel := testChannel{}
+	user := testUser{}
+
+	now := time.Now()
+
+	if !allowWarning(channel, user, time.Minute, now) {
+		t.Fatal("First warning was not allowed")
+	}
+
+	if allowWarning(channel, user, time.Minute, now.Add(30*time.Second)) {
+		t.Error("Warning within the cooldown was allowed")
+	}
+
+	if !allowWarning(channel, user, time.Minute, now.Add(2*time.Minute)) {
+		t.Error("Warning after the cooldown was not allowed")
+	}
+}
@@ -3,6 +3,7 @@ package apirequest
 import (
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/dankeroni/gotwitch"
@@ -18,7 +19,13 @@ type TwitchWrapperX struct {
 
 	RateLimit TwitchRateLimit
 
+	webhookMutex sync.Mutex
+
+	// Subscriptions that Twitch knows about, refreshed by the lease manager. See RefreshWebhookSubscriptions
 	WebhookSubscriptions []gotwitch.WebhookSubscription
+
+	// Subscriptions we have asked for, by callback URL. The lease manager renews these before they expire
+	wantedWebhookSubscriptions map[string]*wantedWebhookSubscription
 }
 
 var TwitchWrapper *TwitchWrapperX
@@ -29,19 +36,20 @@ func initWrapper(cfg *config.TwitchWebhookConfig) error {
 		api: Twitch,
 
 		RateLimit: NewTwitchRateLimit(),
+
+		wantedWebhookSubscriptions: make(map[string]*wantedWebhookSubscription),
 	}
 
-	subscriptions, err := TwitchWrapper.GetWebhookSubscriptions("", "")
+	err := TwitchWrapper.RefreshWebhookSubscriptions()
 	if err != nil {
 		fmt.Println("ERROR GETTING WEBHOOK SUBSCRIPTIONS:", err)
 		return err
 	}
-	// TODO: Every now and then, refresh our list of webhook subscriptions
-	// TODO: follow paginations
 
-	TwitchWrapper.WebhookSubscriptions = subscriptions.Data
 	fmt.Println("Subscriptions:", TwitchWrapper.WebhookSubscriptions)
 
+	go TwitchWrapper.runWebhookLeaseManager()
+
 	return nil
 }
 
@@ -49,31 +57,56 @@ func (w *TwitchWrapperX) WebhookSubscribe(topic gotwitch.WebhookTopic, userID st
 	url := topic.URL(userID)
 	callbackURL := w.cfg.HostPrefix + "/" + userID + "/" + topic.String()
 
-	for _, subscription := range w.WebhookSubscriptions {
-		if subscription.Topic == url &&
-			subscription.Callback == callbackURL {
+	w.webhookMutex.Lock()
+	wanted, ok := w.wantedWebhookSubscriptions[callbackURL]
+	if !ok {
+		wanted = &wantedWebhookSubscription{
+			Topic:       topic,
+			UserID:      userID,
+			TopicURL:    url,
+			CallbackURL: callbackURL,
+		}
+		w.wantedWebhookSubscriptions[callbackURL] = wanted
+	}
 
-			if subscription.ExpiresAt.Add(-TimeToRefresh).Before(time.Now()) {
-				// We are subscribed already, but it's time to refresh our subscription
-				break
-			}
+	subscription := w.findWebhookSubscription(url, callbackURL)
+	w.webhookMutex.Unlock()
 
-			// We are already subscribed to this topic with the same callback URL
-			return nil
-		}
+	if subscription != nil && subscription.ExpiresAt.Add(-TimeToRefresh).After(time.Now()) {
+		// We are already subscribed to this topic with the same callback URL
+		return nil
 	}
 
+	// We are not subscribed yet, or it's time to refresh our subscription
 	leaseTime := time.Duration(w.cfg.LeaseTimeSeconds) * time.Second
 	// Subscribe!
 	data, response, err := w.api.WebhookSubscribeSimple(callbackURL, topic, userID, leaseTime, w.cfg.Secret)
-	if err != nil {
-		return err
-	}
 	if response != nil {
 		w.RateLimit.Update(response)
 	}
 
-	fmt.Println("Response after subscribing:", string(*data))
+	w.webhookMutex.Lock()
+	defer w.webhookMutex.Unlock()
+
+	if err != nil {
+		wanted.LastError = err.Error()
+		return err
+	}
+
+	wanted.LastError = ""
+	wanted.LastSubscribed = time.Now()
+
+	// Assume the subscription went through until the lease manager refreshes the subscription list,
+	// so we don't subscribe again right away
+	w.setWebhookSubscription(gotwitch.WebhookSubscription{
+		Topic:     url,
+		Callback:  callbackURL,
+		ExpiresAt: time.Now().Add(leaseTime),
+	})
+
+	if data != nil {
+		fmt.Println("Response after subscribing:", string(*data))
+	}
 
 	return nil
 }
@@ -106,6 +139,23 @@ func (w *TwitchWrapperX) GetStreams(userIDs, userLogins []string) (data []gotwit
 	return
 }
 
+// GetFollow returns the follow relationship between the two given user IDs, or nil if fromID is not following toID
+func (w *TwitchWrapperX) GetFollow(fromID, toID string) (*gotwitch.Follow, error) {
+	data, response, err := w.api.GetFollowsSimple(fromID, toID)
+	if response != nil {
+		w.RateLimit.Update(response)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if data == nil || len(data.Data) == 0 {
+		return nil, nil
+	}
+
+	return &data.Data[0], nil
+}
+
 func (w *TwitchWrapperX) GetWebhookSubscriptions(after, first string) (data *gotwitch.WebhookSubscriptionsResponse, err error) {
 	var response *http.Response
 	data, response, err = w.api.GetWebhookSubscriptionsSimple(after, first)
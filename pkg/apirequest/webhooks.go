@@ -0,0 +1,157 @@
+package apirequest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dankeroni/gotwitch"
+)
+
+// WebhookLeaseCheckInterval is how often the lease manager refreshes our list of webhook subscriptions and renews the ones that are about to expire
+const WebhookLeaseCheckInterval = 15 * time.Minute
+
+type wantedWebhookSubscription struct {
+	Topic       gotwitch.WebhookTopic
+	UserID      string
+	TopicURL    string
+	CallbackURL string
+
+	LastSubscribed time.Time
+	LastError      string
+}
+
+// WebhookSubscriptionStatus describes a webhook subscription we have asked for
+type WebhookSubscriptionStatus struct {
+	Topic       string
+	UserID      string
+	CallbackURL string
+
+	// Twitch knows about the subscription and it hasn't expired
+	Active    bool
+	ExpiresAt *time.Time `json:",omitempty"`
+
+	LastSubscribed *time.Time `json:",omitempty"`
+	LastError      string     `json:",omitempty"`
+}
+
+// We assume that webhookMutex is locked already
+// Returns nil if Twitch doesn't know about the subscription
+func (w *TwitchWrapperX) findWebhookSubscription(topicURL, callbackURL string) *gotwitch.WebhookSubscription {
+	for i, subscription := range w.WebhookSubscriptions {
+		if subscription.Topic == topicURL && subscription.Callback == callbackURL {
+			return &w.WebhookSubscriptions[i]
+		}
+	}
+
+	return nil
+}
+
+// We assume that webhookMutex is locked already
+func (w *TwitchWrapperX) setWebhookSubscription(subscription gotwitch.WebhookSubscription) {
+	if existing := w.findWebhookSubscription(subscription.Topic, subscription.Callback); existing != nil {
+		*existing = subscription
+		return
+	}
+
+	w.WebhookSubscriptions = append(w.WebhookSubscriptions, subscription)
+}
+
+// RefreshWebhookSubscriptions fetches the list of webhook subscriptions that Twitch knows about, following pagination
+func (w *TwitchWrapperX) RefreshWebhookSubscriptions() error {
+	var subscriptions []gotwitch.WebhookSubscription
+
+	after := ""
+
+	for {
+		data, err := w.GetWebhookSubscriptions(after, "100")
+		if err != nil {
+			return err
+		}
+
+		if data == nil {
+			break
+		}
+
+		subscriptions = append(subscriptions, data.Data...)
+
+		if data.Pagination.Cursor == "" || len(data.Data) == 0 {
+			break
+		}
+
+		after = data.Pagination.Cursor
+	}
+
+	w.webhookMutex.Lock()
+	w.WebhookSubscriptions = subscriptions
+	w.webhookMutex.Unlock()
+
+	return nil
+}
+
+// renewWebhookSubscriptions subscribes again to every subscription we have asked for that's missing or about to expire
+func (w *TwitchWrapperX) renewWebhookSubscriptions() {
+	var wanted []wantedWebhookSubscription
+
+	w.webhookMutex.Lock()
+	for _, subscription := range w.wantedWebhookSubscriptions {
+		wanted = append(wanted, *subscription)
+	}
+	w.webhookMutex.Unlock()
+
+	for _, subscription := range wanted {
+		// WebhookSubscribe does nothing if the subscription is still good
+		if err := w.WebhookSubscribe(subscription.Topic, subscription.UserID); err != nil {
+			fmt.Println("Error renewing webhook subscription", subscription.CallbackURL, err)
+		}
+	}
+}
+
+func (w *TwitchWrapperX) runWebhookLeaseManager() {
+	for {
+		<-time.After(WebhookLeaseCheckInterval)
+
+		if err := w.RefreshWebhookSubscriptions(); err != nil {
+			fmt.Println("Error refreshing webhook subscriptions:", err)
+			continue
+		}
+
+		w.renewWebhookSubscriptions()
+	}
+}
+
+// WebhookSubscriptionStatuses returns the status of every webhook subscription we have asked for, sorted by callback URL
+func (w *TwitchWrapperX) WebhookSubscriptionStatuses() []WebhookSubscriptionStatus {
+	w.webhookMutex.Lock()
+	defer w.webhookMutex.Unlock()
+
+	statuses := []WebhookSubscriptionStatus{}
+
+	for _, wanted := range w.wantedWebhookSubscriptions {
+		status := WebhookSubscriptionStatus{
+			Topic:       wanted.Topic.String(),
+			UserID:      wanted.UserID,
+			CallbackURL: wanted.CallbackURL,
+			LastError:   wanted.LastError,
+		}
+
+		if !wanted.LastSubscribed.IsZero() {
+			lastSubscribed := wanted.LastSubscribed
+			status.LastSubscribed = &lastSubscribed
+		}
+
+		if subscription := w.findWebhookSubscription(wanted.TopicURL, wanted.CallbackURL); subscription != nil {
+			expiresAt := subscription.ExpiresAt
+			status.ExpiresAt = &expiresAt
+			status.Active = expiresAt.After(time.Now())
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].CallbackURL < statuses[j].CallbackURL
+	})
+
+	return statuses
+}
@@ -11,6 +11,9 @@ type Sender interface {
 	Timeout(Channel, User, int, string)
 	Ban(Channel, User, string)
 
+	// Deletes a single message of the user, given the ID of the message
+	Delete(Channel, User, string)
+
 	GetPoints(Channel, string) uint64
 
 	// give or remove points from user in channel
@@ -33,4 +36,7 @@ type Sender interface {
 
 	// Permanently leave channel with the given channel ID
 	LeaveChannel(channelID string) error
+
+	// Returns the bot channel with the given channel ID, or nil if the bot hasn't joined that channel
+	GetBotChannel(channelID string) BotChannel
 }
@@ -8,5 +8,31 @@ type BotChannel interface {
 	EnableModule(string) error
 	DisableModule(string) error
 
+	// Re-creates the module with the settings currently stored in the database
+	ReloadModule(string) error
+
+	// Stores new settings for the module, reloading it if it's enabled
+	ConfigureModule(moduleID string, settings []byte) error
+
+	// Returns all available modules sorted by priority, and whether they're enabled
+	Modules() []ModuleStatus
+
+	ModuleHealth() []ModuleHealth
+
 	Stream() Stream
+
+	// Chat settings of the channel as of the last ROOMSTATE message
+	RoomState() RoomState
+
+	// Channel modes that the bot turns back on whenever they're turned off
+	PermaModes() []PermaMode
+	EnablePermaMode(mode ChannelMode, argument string) error
+	DisablePermaMode(mode ChannelMode) error
+
+	// Decides which users the filters of the channel leave alone
+	ExemptionPolicy() ExemptionPolicy
+	SetExemptionPolicy(ExemptionPolicy) error
+
+	// The bot that joined the channel, for modules that need to send messages outside of handling a message
+	Bot() Sender
 }
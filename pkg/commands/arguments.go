@@ -0,0 +1,317 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/utils"
+)
+
+// ArgumentType decides how an argument is parsed and validated
+type ArgumentType int
+
+const (
+	// A word, i.e. a module ID
+	ArgumentWord ArgumentType = iota
+
+	// A twitch username that is resolved to a user ID. The @ and trailing , or : are stripped
+	ArgumentUsername
+
+	// A twitch user ID that is resolved to a username
+	ArgumentUserID
+
+	// Either seconds (30) or a go duration (30s, 5m)
+	ArgumentDuration
+
+	// A whole number
+	ArgumentInteger
+
+	// A positive whole number, or "all"
+	ArgumentIntegerOrAll
+
+	// The rest of the message, including spaces. Must be the last argument
+	ArgumentRest
+)
+
+// Argument describes one argument in the signature of a command
+type Argument struct {
+	Name string
+	Type ArgumentType
+
+	Optional bool
+
+	// Consume all remaining words. Only valid for the last argument
+	// Usernames and user IDs that can't be resolved are left out
+	Multiple bool
+}
+
+// ArgumentUser is the value of a username or user ID argument
+type ArgumentUser struct {
+	ID   string
+	Name string
+}
+
+type integerOrAll struct {
+	value int64
+	all   bool
+}
+
+func (a Argument) usage() string {
+	var s string
+
+	switch a.Type {
+	case ArgumentIntegerOrAll:
+		s = a.Name + "|all"
+	default:
+		s = a.Name
+	}
+
+	if a.Multiple || a.Type == ArgumentRest {
+		s += "..."
+	}
+
+	if a.Optional {
+		return "[" + s + "]"
+	}
+
+	return "<" + s + ">"
+}
+
+// Arguments contains the parsed arguments of a command, by name
+type Arguments map[string]interface{}
+
+// Has returns true if the argument with the given name was given
+func (a Arguments) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+// String returns the value of a word or rest-of-line argument
+func (a Arguments) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// Int returns the value of an integer argument. For "all" arguments, 0 is returned
+func (a Arguments) Int(name string) int64 {
+	switch v := a[name].(type) {
+	case int64:
+		return v
+	case integerOrAll:
+		return v.value
+	}
+
+	return 0
+}
+
+// All returns true if "all" was given as the value of an integer-or-all argument
+func (a Arguments) All(name string) bool {
+	v, _ := a[name].(integerOrAll)
+	return v.all
+}
+
+// Duration returns the value of a duration argument
+func (a Arguments) Duration(name string) time.Duration {
+	d, _ := a[name].(time.Duration)
+	return d
+}
+
+// User returns the value of a username or user ID argument
+func (a Arguments) User(name string) ArgumentUser {
+	u, _ := a[name].(ArgumentUser)
+	return u
+}
+
+// Users returns the values of a username or user ID argument that accepts multiple values
+func (a Arguments) Users(name string) []ArgumentUser {
+	u, _ := a[name].([]ArgumentUser)
+	return u
+}
+
+// Words returns the values of a word argument that accepts multiple values
+func (a Arguments) Words(name string) []string {
+	w, _ := a[name].([]string)
+	return w
+}
+
+func usageString(trigger string, arguments []Argument) string {
+	parts := []string{trigger}
+	for _, argument := range arguments {
+		parts = append(parts, argument.usage())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// nextWord returns the next non-empty word in parts, and the remaining parts after that word
+func nextWord(parts []string) (string, []string) {
+	for i, part := range parts {
+		if part != "" {
+			return part, parts[i+1:]
+		}
+	}
+
+	return "", nil
+}
+
+// parseArguments parses parts according to the given signature into args
+// The parts that were not consumed by the signature are returned
+func parseArguments(userStore pkg.UserStore, signature []Argument, parts []string, args Arguments) ([]string, error) {
+	for _, argument := range signature {
+		if argument.Type == ArgumentRest {
+			rest := strings.TrimSpace(strings.Join(parts, " "))
+			if rest == "" {
+				if argument.Optional {
+					return nil, nil
+				}
+
+				return nil, fmt.Errorf("missing %s", argument.Name)
+			}
+
+			args[argument.Name] = rest
+			return nil, nil
+		}
+
+		if argument.Multiple {
+			var words []string
+			for {
+				var word string
+				word, parts = nextWord(parts)
+				if word == "" {
+					break
+				}
+
+				words = append(words, word)
+			}
+
+			if len(words) == 0 {
+				if argument.Optional {
+					return nil, nil
+				}
+
+				return nil, fmt.Errorf("missing %s", argument.Name)
+			}
+
+			value, err := parseMultipleArgument(userStore, argument, words)
+			if err != nil {
+				return nil, err
+			}
+
+			args[argument.Name] = value
+			return nil, nil
+		}
+
+		word, remaining := nextWord(parts)
+		if word == "" {
+			if argument.Optional {
+				continue
+			}
+
+			return nil, fmt.Errorf("missing %s", argument.Name)
+		}
+
+		value, err := parseArgument(userStore, argument, word)
+		if err != nil {
+			return nil, err
+		}
+
+		parts = remaining
+		args[argument.Name] = value
+	}
+
+	return parts, nil
+}
+
+func parseArgument(userStore pkg.UserStore, argument Argument, word string) (interface{}, error) {
+	switch argument.Type {
+	case ArgumentUsername:
+		name := strings.ToLower(utils.FilterUsername(word))
+		if name == "" {
+			return nil, fmt.Errorf("invalid username '%s'", word)
+		}
+
+		id := userStore.GetID(name)
+		if id == "" {
+			return nil, fmt.Errorf("no user with the name %s exists", name)
+		}
+
+		return ArgumentUser{ID: id, Name: name}, nil
+
+	case ArgumentUserID:
+		if !utils.IsValidUserID(word) {
+			return nil, fmt.Errorf("invalid user ID '%s'", word)
+		}
+
+		name := userStore.GetName(word)
+		if name == "" {
+			return nil, fmt.Errorf("no user with the ID %s exists", word)
+		}
+
+		return ArgumentUser{ID: word, Name: name}, nil
+
+	case ArgumentDuration:
+		if seconds, err := strconv.ParseInt(word, 10, 64); err == nil {
+			return time.Duration(seconds) * time.Second, nil
+		}
+
+		d, err := time.ParseDuration(word)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration '%s'", word)
+		}
+
+		return d, nil
+
+	case ArgumentInteger:
+		v, err := strconv.ParseInt(word, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", word)
+		}
+
+		return v, nil
+
+	case ArgumentIntegerOrAll:
+		if strings.EqualFold(word, "all") {
+			return integerOrAll{all: true}, nil
+		}
+
+		v, err := strconv.ParseInt(word, 10, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid number '%s'", word)
+		}
+
+		return integerOrAll{value: v}, nil
+	}
+
+	return word, nil
+}
+
+func parseMultipleArgument(userStore pkg.UserStore, argument Argument, words []string) (interface{}, error) {
+	var users []ArgumentUser
+
+	switch argument.Type {
+	case ArgumentUsername:
+		for username, id := range userStore.GetIDs(utils.FilterUsernames(words)) {
+			users = append(users, ArgumentUser{ID: id, Name: username})
+		}
+
+	case ArgumentUserID:
+		for id, username := range userStore.GetNames(utils.FilterUserIDs(words)) {
+			users = append(users, ArgumentUser{ID: id, Name: username})
+		}
+
+	case ArgumentWord:
+		return words, nil
+
+	default:
+		return nil, fmt.Errorf("argument %s can't have multiple values", argument.Name)
+	}
+
+	if len(users) == 0 {
+		return nil, fmt.Errorf("no valid %s were given", argument.Name)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSignature = []Argument{
+	{Name: "points", Type: ArgumentIntegerOrAll},
+	{Name: "duration", Type: ArgumentDuration, Optional: true},
+	{Name: "message", Type: ArgumentRest, Optional: true},
+}
+
+func TestParseArguments(t *testing.T) {
+	args := make(Arguments)
+	_, err := parseArguments(nil, testSignature, strings.Split("500  2m hello  world", " "), args)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if args.Int("points") != 500 || args.All("points") {
+		t.Errorf("Got points %d (all: %v), expected 500", args.Int("points"), args.All("points"))
+	}
+
+	if args.Duration("duration") != 2*time.Minute {
+		t.Errorf("Got duration %s, expected 2m", args.Duration("duration"))
+	}
+
+	if args.String("message") != "hello  world" {
+		t.Errorf("Got message '%s', expected 'hello  world'", args.String("message"))
+	}
+}
+
+func TestParseArgumentsOptional(t *testing.T) {
+	args := make(Arguments)
+	_, err := parseArguments(nil, testSignature, []string{"all", "30"}, args)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if !args.All("points") {
+		t.Errorf("Expected all points")
+	}
+
+	if args.Duration("duration") != 30*time.Second {
+		t.Errorf("Got duration %s, expected 30s", args.Duration("duration"))
+	}
+
+	if args.Has("message") {
+		t.Errorf("Expected no message")
+	}
+}
+
+func TestParseArgumentsErrors(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"xd"},
+		{"-5"},
+		{"5", "forsen"},
+	}
+
+	for _, parts := range tests {
+		if _, err := parseArguments(nil, testSignature, parts, make(Arguments)); err == nil {
+			t.Errorf("Expected an error parsing %v", parts)
+		}
+	}
+}
+
+func TestUsageString(t *testing.T) {
+	expected := "!test <points|all> [duration] [message...]"
+	if usage := usageString("!test", testSignature); usage != expected {
+		t.Errorf("Got usage '%s', expected '%s'", usage, expected)
+	}
+}
@@ -0,0 +1,318 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pajlada/pajbot2/pkg"
+)
+
+// DefaultPrefix is the command prefix used in channels that haven't configured their own
+const DefaultPrefix = "!"
+
+// Context contains everything a command needs to respond to a message
+type Context struct {
+	Bot        pkg.Sender
+	BotChannel pkg.BotChannel
+	Channel    pkg.Channel
+	User       pkg.User
+	Message    pkg.Message
+	Action     pkg.Action
+
+	// The prefix used in this channel, i.e. "!"
+	Prefix string
+
+	// The trigger that was used, without the prefix
+	Trigger string
+
+	Args Arguments
+
+	// Called once the permission and argument checks have passed, right before the command runs. Returns false if the command is on cooldown
+	useCooldown func() bool
+}
+
+// startCooldown returns false if the command is on cooldown. Otherwise the cooldown is started
+func (ctx *Context) startCooldown() bool {
+	return ctx.useCooldown == nil || ctx.useCooldown()
+}
+
+// Command is a command with a declarative signature
+// The permission check and argument parsing is done before Run is called, and a usage message is printed if the arguments are invalid
+type Command struct {
+	Name        string
+	Aliases     []string
+	Description string
+	Arguments   []Argument
+
+	// Permission required to use the command, either globally or in the channel. PermissionNone means anyone can use it
+	Permission pkg.Permission
+
+	// Minimum user level required to use the command, see UserLevel
+	Level int
+
+	// nil means the default cooldown of the registry is used
+	Cooldown *Cooldown
+
+	// Run returns the message that the user is mentioned with. An empty string means nothing is sent
+	Run func(ctx *Context) string
+
+	// If the command has sub commands, the word after the commands arguments decides which sub command is run
+	subCommands       *subCommands
+	defaultSubCommand string
+}
+
+// checkPermission returns an error message if the user is not allowed to use something that requires the given permission and level
+// channel is nil for whispers, in which case only global permissions are checked
+func checkPermission(channel pkg.Channel, user pkg.User, permission pkg.Permission, level int) string {
+	if channel == nil {
+		if permission != pkg.PermissionNone && !user.HasGlobalPermission(permission) {
+			return "you do not have permission to use this command"
+		}
+
+		if level > LevelUser && !user.HasGlobalPermission(pkg.PermissionAdmin) {
+			return "you do not have permission to use this command"
+		}
+
+		return ""
+	}
+
+	if permission != pkg.PermissionNone && !user.HasPermission(channel, permission) {
+		return "you do not have permission to use this command"
+	}
+
+	if level > 0 && UserLevel(channel, user) < level {
+		return "you do not have permission to use this command"
+	}
+
+	return ""
+}
+
+func (c *Command) triggers() []string {
+	return append([]string{c.Name}, c.Aliases...)
+}
+
+func (c *Command) usage(prefix string) string {
+	usage := usageString(prefix+c.Name, c.Arguments)
+
+	if c.subCommands != nil {
+		usage += " <" + strings.Join(c.subCommands.names(), "|") + ">"
+	}
+
+	return usage
+}
+
+func (c *Command) run(ctx *Context, parts []string) string {
+	if msg := checkPermission(ctx.Channel, ctx.User, c.Permission, c.Level); msg != "" {
+		return msg
+	}
+
+	ctx.Args = make(Arguments)
+
+	parts, err := parseArguments(ctx.Bot.GetUserStore(), c.Arguments, parts, ctx.Args)
+	if err != nil {
+		return fmt.Sprintf("%s. usage: %s", err, c.usage(ctx.Prefix))
+	}
+
+	if c.subCommands == nil {
+		if !ctx.startCooldown() {
+			return ""
+		}
+
+		return c.Run(ctx)
+	}
+
+	name, parts := nextWord(parts)
+	if name == "" {
+		name = c.defaultSubCommand
+	}
+
+	sc, ok := c.subCommands.find(strings.ToLower(name))
+	if !ok {
+		return "usage: " + c.usage(ctx.Prefix)
+	}
+
+	return sc.run(ctx, usageString(ctx.Prefix+c.Name, c.Arguments)+" "+name, parts)
+}
+
+// Registry keeps track of the commands available in a channel, and dispatches messages to them
+type Registry struct {
+	commands []*Command
+
+	// by trigger, without the prefix
+	triggers map[string]*Command
+
+	cooldowns map[*Command]*cooldownTracker
+
+	defaultCooldown Cooldown
+}
+
+// NewRegistry returns a registry with the !help command registered as the given name
+func NewRegistry(helpName string) *Registry {
+	r := &Registry{
+		triggers:  make(map[string]*Command),
+		cooldowns: make(map[*Command]*cooldownTracker),
+
+		defaultCooldown: DefaultCooldown,
+	}
+
+	r.Register(&Command{
+		Name:        helpName,
+		Description: "show the available commands or how to use a command",
+		Arguments: []Argument{
+			{Name: "command", Optional: true},
+		},
+		Run: r.help,
+	})
+
+	return r
+}
+
+// Register adds the command to the registry. Triggers that are already used by another command are overridden
+func (r *Registry) Register(c *Command) {
+	r.commands = append(r.commands, c)
+	r.cooldowns[c] = &cooldownTracker{}
+
+	for _, trigger := range c.triggers() {
+		r.triggers[strings.ToLower(trigger)] = c
+	}
+}
+
+// Find returns the command with the given trigger (without the prefix), or nil if no such command exists
+func (r *Registry) Find(trigger string) *Command {
+	return r.triggers[strings.ToLower(trigger)]
+}
+
+// SetDefaultCooldown sets the cooldown of commands that don't have a cooldown of their own
+func (r *Registry) SetDefaultCooldown(cooldown Cooldown) {
+	r.defaultCooldown = cooldown
+}
+
+// SetCooldown overrides the cooldown of the command with the given name
+func (r *Registry) SetCooldown(name string, cooldown Cooldown) error {
+	c := r.Find(name)
+	if c == nil {
+		return fmt.Errorf("no command called %s exists", name)
+	}
+
+	c.Cooldown = &cooldown
+
+	return nil
+}
+
+func (r *Registry) cooldown(c *Command) Cooldown {
+	if c.Cooldown != nil {
+		return *c.Cooldown
+	}
+
+	return r.defaultCooldown
+}
+
+// Dispatch runs the command in the message if there is one. Returns true if a command was run
+func (r *Registry) Dispatch(prefix string, bot pkg.Sender, botChannel pkg.BotChannel, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) bool {
+	parts := strings.Split(message.GetText(), " ")
+	if !strings.HasPrefix(parts[0], prefix) {
+		return false
+	}
+
+	ctx := &Context{
+		Bot:        bot,
+		BotChannel: botChannel,
+		Channel:    channel,
+		User:       user,
+		Message:    message,
+		Action:     action,
+
+		Prefix: prefix,
+	}
+
+	return r.dispatch(ctx, strings.TrimPrefix(parts[0], prefix), parts[1:])
+}
+
+// DispatchWhisper runs the command in the whisper if there is one. Returns true if a command was run
+// The prefix is optional in whispers, and the response is whispered back. Channel and BotChannel are nil in the context
+func (r *Registry) DispatchWhisper(bot pkg.Sender, user pkg.User, message pkg.Message) bool {
+	parts := strings.Split(message.GetText(), " ")
+
+	ctx := &Context{
+		Bot:     bot,
+		User:    user,
+		Message: message,
+
+		Prefix: DefaultPrefix,
+	}
+
+	return r.dispatch(ctx, strings.TrimPrefix(parts[0], DefaultPrefix), parts[1:])
+}
+
+func (r *Registry) dispatch(ctx *Context, trigger string, args []string) bool {
+	c := r.Find(trigger)
+	if c == nil {
+		return false
+	}
+
+	ctx.Trigger = strings.ToLower(trigger)
+
+	cooldown := r.cooldown(c)
+	onCooldown := false
+
+	// The cooldown is only used once we know the command is going to run, so users without permission or with invalid arguments don't start it.
+	// Commands that whisper on cooldown still run, the cooldown only keeps their response out of the chat
+	ctx.useCooldown = func() bool {
+		// Moderators are not affected by cooldowns
+		if ctx.Channel != nil && UserLevel(ctx.Channel, ctx.User) >= LevelModerator {
+			return true
+		}
+
+		if r.cooldowns[c].use(ctx.User.GetID(), cooldown.global(), cooldown.user()) {
+			return true
+		}
+
+		onCooldown = true
+		return cooldown.WhisperOnCooldown
+	}
+
+	response := c.run(ctx, args)
+	if response == "" {
+		return true
+	}
+
+	if ctx.Channel == nil || onCooldown {
+		ctx.Bot.Whisper(ctx.User, response)
+	} else {
+		ctx.Bot.Mention(ctx.Channel, ctx.User, response)
+	}
+
+	return true
+}
+
+func (r *Registry) help(ctx *Context) string {
+	if name := ctx.Args.String("command"); name != "" {
+		c := r.Find(strings.TrimPrefix(name, ctx.Prefix))
+		if c == nil {
+			return fmt.Sprintf("no command called %s exists", name)
+		}
+
+		response := "usage: " + c.usage(ctx.Prefix)
+		if c.Description != "" {
+			response += " - " + c.Description
+		}
+
+		if len(c.Aliases) > 0 {
+			response += " (aliases: " + ctx.Prefix + strings.Join(c.Aliases, ", "+ctx.Prefix) + ")"
+		}
+
+		return response
+	}
+
+	var names []string
+	for _, c := range r.commands {
+		if checkPermission(ctx.Channel, ctx.User, c.Permission, c.Level) == "" {
+			names = append(names, ctx.Prefix+c.Name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return fmt.Sprintf("available commands: %s. use %s%s COMMAND to see how to use a command", strings.Join(names, ", "), ctx.Prefix, ctx.Trigger)
+}
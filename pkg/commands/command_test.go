@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/pajlada/pajbot2/pkg"
+)
+
+type testSender struct {
+	pkg.Sender
+
+	whispers []string
+	mentions []string
+}
+
+func (s *testSender) Whisper(user pkg.User, message string) { s.whispers = append(s.whispers, message) }
+func (s *testSender) GetUserStore() pkg.UserStore           { return nil }
+
+func (s *testSender) Mention(channel pkg.Channel, user pkg.User, message string) {
+	s.mentions = append(s.mentions, message)
+}
+
+type testMessage struct {
+	pkg.Message
+
+	text string
+}
+
+func (m testMessage) GetText() string { return m.text }
+
+func TestDispatchCooldown(t *testing.T) {
+	runs := 0
+
+	r := NewRegistry("help")
+	r.Register(&Command{
+		Name: "xd",
+		Arguments: []Argument{
+			{Name: "text"},
+		},
+		Cooldown: &Cooldown{
+			Global:            60,
+			WhisperOnCooldown: true,
+		},
+		Run: func(ctx *Context) string {
+			runs++
+			return "xd " + ctx.Args.String("text")
+		},
+	})
+	r.Register(&Command{
+		Name:     "quiet",
+		Cooldown: &Cooldown{Global: 60},
+		Run: func(ctx *Context) string {
+			runs++
+			return "quiet"
+		},
+	})
+	r.Register(&Command{
+		Name:       "admin",
+		Permission: pkg.PermissionAdmin,
+		Cooldown:   &Cooldown{Global: 60},
+		Run: func(ctx *Context) string {
+			runs++
+			return ""
+		},
+	})
+
+	bot := &testSender{}
+	say := func(text string) {
+		r.Dispatch("!", bot, nil, testChannel{}, testUser{}, testMessage{text: text}, nil)
+	}
+
+	// Neither a missing argument nor a missing permission should start the cooldown
+	say("!xd")
+	say("!admin")
+	say("!admin")
+	if runs != 0 || len(bot.mentions) != 3 {
+		t.Fatalf("Commands should not have run, got %d runs and mentions %v", runs, bot.mentions)
+	}
+
+	say("!xd a")
+	if runs != 1 || len(bot.mentions) != 4 || bot.mentions[3] != "xd a" {
+		t.Fatalf("Command should have responded in chat, got %d runs and mentions %v", runs, bot.mentions)
+	}
+
+	say("!xd b")
+	if runs != 2 || len(bot.mentions) != 4 {
+		t.Errorf("Command should run without responding in chat while it's on cooldown, got %d runs and mentions %v", runs, bot.mentions)
+	}
+
+	if len(bot.whispers) != 1 || bot.whispers[0] != "xd b" {
+		t.Errorf("Expected the response to be whispered, got %v", bot.whispers)
+	}
+
+	say("!quiet")
+	say("!quiet")
+	if runs != 3 || len(bot.whispers) != 1 {
+		t.Errorf("Commands that don't whisper on cooldown should be ignored while they're on cooldown, got %d runs and whispers %v", runs, bot.whispers)
+	}
+}
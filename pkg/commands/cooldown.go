@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultCooldown is used by commands in a registry that don't have a cooldown of their own
+var DefaultCooldown = Cooldown{
+	Global: 5,
+	User:   15,
+}
+
+// Cooldown configures how often a command can be used
+type Cooldown struct {
+	// In seconds
+	Global int `json:",omitempty"`
+	User   int `json:",omitempty"`
+
+	// Run the command for users that use it while it's on cooldown and whisper them the response, instead of ignoring them
+	WhisperOnCooldown bool `json:",omitempty"`
+}
+
+func (c Cooldown) global() time.Duration {
+	return time.Duration(c.Global) * time.Second
+}
+
+func (c Cooldown) user() time.Duration {
+	return time.Duration(c.User) * time.Second
+}
+
+// Once this many per-user cooldowns are stored, the expired ones are removed
+const cooldownPruneThreshold = 1000
+
+// cooldownTracker keeps track of when a command was last used, globally and by each user
+type cooldownTracker struct {
+	mutex sync.Mutex
+
+	lastUse time.Time
+
+	// by user ID
+	lastUserUse map[string]time.Time
+}
+
+// use checks whether the command is on cooldown for the given user. If it's not, the cooldown is started and true is returned
+func (t *cooldownTracker) use(userID string, global, user time.Duration) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+
+	if now.Before(t.lastUse.Add(global)) {
+		return false
+	}
+
+	if t.lastUserUse == nil {
+		t.lastUserUse = make(map[string]time.Time)
+	}
+
+	if lastUserUse, ok := t.lastUserUse[userID]; ok {
+		if now.Before(lastUserUse.Add(user)) {
+			return false
+		}
+	}
+
+	if len(t.lastUserUse) >= cooldownPruneThreshold {
+		for id, lastUserUse := range t.lastUserUse {
+			if !now.Before(lastUserUse.Add(user)) {
+				delete(t.lastUserUse, id)
+			}
+		}
+	}
+
+	t.lastUse = now
+	t.lastUserUse[userID] = now
+
+	return true
+}
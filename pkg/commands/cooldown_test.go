@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCooldownTracker(t *testing.T) {
+	var tracker cooldownTracker
+
+	if !tracker.use("1", 0, time.Minute) {
+		t.Fatalf("First use should not be on cooldown")
+	}
+
+	if tracker.use("1", 0, time.Minute) {
+		t.Errorf("Second use by the same user should be on user cooldown")
+	}
+
+	if !tracker.use("2", 0, time.Minute) {
+		t.Errorf("Use by a different user should not be on cooldown")
+	}
+
+	if tracker.use("3", time.Minute, 0) {
+		t.Errorf("Use should be on global cooldown")
+	}
+}
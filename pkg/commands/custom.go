@@ -18,345 +18,302 @@ func init() {
 	startTime = time.Now()
 }
 
-type GetUserID struct {
-}
-
-func (c GetUserID) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	usernames := utils.FilterUsernames(parts[1:])
-
-	if len(usernames) == 0 {
-		bot.Mention(channel, user, "usage: !userid USERNAME (i.e. !userid pajlada)")
-		return
-	}
-
-	userIDs := bot.GetUserStore().GetIDs(usernames)
-	var results []string
-	for username, userID := range userIDs {
-		results = append(results, username+"="+userID)
-	}
+// NewGetUserID returns the !userid command
+func NewGetUserID() *Command {
+	return &Command{
+		Name:        "userid",
+		Description: "print the twitch user IDs of the given users",
+		Arguments: []Argument{
+			{Name: "usernames", Type: ArgumentUsername, Multiple: true},
+		},
+		Run: func(ctx *Context) string {
+			var results []string
+			for _, u := range ctx.Args.Users("usernames") {
+				results = append(results, u.Name+"="+u.ID)
+			}
 
-	if len(results) == 0 {
-		bot.Mention(channel, user, "no valid usernames were given")
-		return
+			return strings.Join(results, ", ")
+		},
 	}
-
-	bot.Mention(channel, user, strings.Join(results, ", "))
-}
-
-type GetUserName struct {
 }
 
-func (c GetUserName) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	userIDs := utils.FilterUserIDs(parts[1:])
-
-	if len(userIDs) == 0 {
-		bot.Mention(channel, user, "usage: !username USERID (i.e. !username 11148817)")
-		return
-	}
-
-	names := bot.GetUserStore().GetNames(userIDs)
-	var results []string
-	for userID, username := range names {
-		results = append(results, userID+"="+username)
-	}
+// NewGetUserName returns the !username command
+func NewGetUserName() *Command {
+	return &Command{
+		Name:        "username",
+		Description: "print the twitch usernames of the given user IDs",
+		Arguments: []Argument{
+			{Name: "userids", Type: ArgumentUserID, Multiple: true},
+		},
+		Run: func(ctx *Context) string {
+			var results []string
+			for _, u := range ctx.Args.Users("userids") {
+				results = append(results, u.ID+"="+u.Name)
+			}
 
-	if len(results) == 0 {
-		bot.Mention(channel, user, "no valid user ids were given")
-		return
+			return strings.Join(results, ", ")
+		},
 	}
-
-	bot.Mention(channel, user, strings.Join(results, ", "))
 }
 
-type GetPoints struct {
-}
-
-func (c GetPoints) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	var potentialTarget string
-	targetID := user.GetID()
-
-	if len(parts) >= 2 {
-		potentialTarget = utils.FilterUsername(parts[1])
-		if potentialTarget != "" {
-			potentialTargetID := bot.GetUserStore().GetID(potentialTarget)
-			if potentialTargetID != "" {
-				targetID = potentialTargetID
-			} else {
-				potentialTarget = ""
+// NewGetPoints returns the !points command
+func NewGetPoints() *Command {
+	return &Command{
+		Name:        "pb2points",
+		Description: "print how many points you or the given user has",
+		Arguments: []Argument{
+			{Name: "user", Type: ArgumentUsername, Optional: true},
+		},
+		Run: func(ctx *Context) string {
+			if !ctx.Args.Has("user") {
+				points := ctx.Bot.GetPoints(ctx.Channel, ctx.User.GetID())
+				return "you have " + strconv.FormatUint(points, 10) + " points"
 			}
-		}
-	}
 
-	points := bot.GetPoints(channel, targetID)
-	if potentialTarget == "" {
-		bot.Mention(channel, user, "you have "+strconv.FormatUint(points, 10)+" points")
-	} else {
-		bot.Mention(channel, user, potentialTarget+" has "+strconv.FormatUint(points, 10)+" points")
+			target := ctx.Args.User("user")
+			points := ctx.Bot.GetPoints(ctx.Channel, target.ID)
+			return target.Name + " has " + strconv.FormatUint(points, 10) + " points"
+		},
 	}
 }
 
-type AddPoints struct {
-}
-
-func (c AddPoints) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	_, points := bot.AddPoints(channel, user.GetID(), uint64(rand.Int31n(50)))
-	bot.Mention(channel, user, "you now have "+strconv.FormatUint(points, 10)+" points")
-}
-
-type RemovePoints struct {
-}
-
-func (c RemovePoints) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	_, points := bot.RemovePoints(channel, user.GetID(), uint64(rand.Int31n(50)))
-	bot.Mention(channel, user, "you now have "+strconv.FormatUint(points, 10)+" points")
-}
-
-type Roulette struct {
+// NewAddPoints returns a command that gives the user a random amount of points, used for testing the point server
+func NewAddPoints() *Command {
+	return &Command{
+		Name: "pb2addpoints",
+		Run: func(ctx *Context) string {
+			_, points := ctx.Bot.AddPoints(ctx.Channel, ctx.User.GetID(), uint64(rand.Int31n(50)))
+			return "you now have " + strconv.FormatUint(points, 10) + " points"
+		},
+	}
 }
 
-func (c Roulette) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	if len(parts) < 2 {
-		bot.Mention(channel, user, "usage: !roulette 500 or !roulette all")
-		return
+// NewRemovePoints returns a command that removes a random amount of points from the user, used for testing the point server
+func NewRemovePoints() *Command {
+	return &Command{
+		Name: "pb2removepoints",
+		Run: func(ctx *Context) string {
+			_, points := ctx.Bot.RemovePoints(ctx.Channel, ctx.User.GetID(), uint64(rand.Int31n(50)))
+			return "you now have " + strconv.FormatUint(points, 10) + " points"
+		},
 	}
+}
 
-	var pointsToRoulette uint64
-
-	if strings.ToLower(parts[1]) == "all" {
-		pointsToRoulette = bot.GetPoints(channel, user.GetID())
-	} else {
-		var err error
-		pointsToRoulette, err = strconv.ParseUint(parts[1], 10, 64)
+// NewRoulette returns the !roulette command
+func NewRoulette() *Command {
+	return &Command{
+		Name:        "pb2roulette",
+		Description: "bet your points for a 50% chance to double them",
+		Arguments: []Argument{
+			{Name: "points", Type: ArgumentIntegerOrAll},
+		},
+		Run: func(ctx *Context) string {
+			bot, channel, user := ctx.Bot, ctx.Channel, ctx.User
+
+			pointsToRoulette := uint64(ctx.Args.Int("points"))
+			if ctx.Args.All("points") {
+				pointsToRoulette = bot.GetPoints(channel, user.GetID())
+			}
 
-		if err != nil {
-			bot.Mention(channel, user, "usage: !roulette 500 or !roulette all")
-			return
-		}
-	}
+			if pointsToRoulette == 0 {
+				return "you have 0 points, you can't roulette ResidentSleeper"
+			}
 
-	if pointsToRoulette == 0 {
-		bot.Mention(channel, user, "you have 0 points, you can't roulette ResidentSleeper")
-		return
-	}
+			if result, _ := bot.RemovePoints(channel, user.GetID(), pointsToRoulette); !result {
+				return "you don't have enough points ResidentSleeper"
+			}
 
-	if result, _ := bot.RemovePoints(channel, user.GetID(), pointsToRoulette); !result {
-		bot.Mention(channel, user, "you don't have enough points ResidentSleeper")
-		return
-	}
+			if rand.Int31n(2) == 0 {
+				// loss
+				return "you lost OMEGALUL"
+			}
 
-	if rand.Int31n(2) == 0 {
-		// loss
-		bot.Mention(channel, user, "you lost OMEGALUL")
-	} else {
-		// win
-		// TODO: Check for integer overflow?
-		_, newPoints := bot.AddPoints(channel, user.GetID(), pointsToRoulette*2)
-		bot.Mention(channel, user, "you won PagChomp you now have "+strconv.FormatUint(newPoints, 10)+" points KKona")
+			// win
+			// TODO: Check for integer overflow?
+			_, newPoints := bot.AddPoints(channel, user.GetID(), pointsToRoulette*2)
+			return "you won PagChomp you now have " + strconv.FormatUint(newPoints, 10) + " points KKona"
+		},
 	}
 }
 
-type GivePoints struct {
-}
-
-func (c GivePoints) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	const USAGE = `usage: !givepoints USER POINTS`
-	if len(parts) < 3 {
-		bot.Mention(channel, user, USAGE)
-		return
-	}
-
-	target := utils.FilterUsername(parts[1])
-	if target == "" {
-		// Invalid username
-		return
-	}
-
-	targetID := bot.GetUserStore().GetID(target)
-	if targetID == "" {
-		// Invalid username
-		return
-	}
-
-	var pointsToGive uint64
-
-	if strings.ToLower(parts[2]) == "all" {
-		pointsToGive = bot.GetPoints(channel, user.GetID())
-	} else {
-		var err error
-		pointsToGive, err = strconv.ParseUint(parts[2], 10, 64)
+// NewGivePoints returns the !givepoints command
+func NewGivePoints() *Command {
+	return &Command{
+		Name:        "pb2givepoints",
+		Description: "give some of your points to another user",
+		Arguments: []Argument{
+			{Name: "user", Type: ArgumentUsername},
+			{Name: "points", Type: ArgumentIntegerOrAll},
+		},
+		Run: func(ctx *Context) string {
+			bot, channel, user := ctx.Bot, ctx.Channel, ctx.User
+			target := ctx.Args.User("user")
+
+			pointsToGive := uint64(ctx.Args.Int("points"))
+			if ctx.Args.All("points") {
+				pointsToGive = bot.GetPoints(channel, user.GetID())
+			}
 
-		if err != nil {
-			bot.Mention(channel, user, USAGE)
-			return
-		}
-	}
+			if pointsToGive == 0 {
+				return "you can't give away 0 points"
+			}
 
-	if pointsToGive == 0 {
-		bot.Mention(channel, user, USAGE)
-		return
-	}
+			if result, _ := bot.RemovePoints(channel, user.GetID(), pointsToGive); !result {
+				return "you don't have enough points ResidentSleeper"
+			}
 
-	if result, _ := bot.RemovePoints(channel, user.GetID(), pointsToGive); !result {
-		bot.Mention(channel, user, "you don't have enough points ResidentSleeper")
-		return
+			bot.AddPoints(channel, target.ID, pointsToGive)
+			return "you gave away " + strconv.FormatUint(pointsToGive, 10) + " points to @" + target.Name
+		},
 	}
-
-	bot.AddPoints(channel, targetID, pointsToGive)
-	bot.Mention(channel, user, "you gave away "+strconv.FormatUint(pointsToGive, 10)+" points to @"+target)
-}
-
-type Ping struct {
 }
 
-func (c Ping) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	bot.Mention(channel, user, fmt.Sprintf("pb2 has been running for %s", utils.TimeSince(startTime)))
-}
-
-type Simplify struct {
+// NewPing returns the !ping command
+func NewPing() *Command {
+	return &Command{
+		Name:        "pb2ping",
+		Description: "print how long the bot has been running",
+		Run: func(ctx *Context) string {
+			return fmt.Sprintf("pb2 has been running for %s", utils.TimeSince(startTime))
+		},
+	}
 }
 
-func (c Simplify) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	if user.IsModerator() || user.IsBroadcaster(channel) {
-		if len(parts) > 1 {
-			normalizedMessage, err := normalize.Normalize(strings.Join(parts[1:], " "))
+// NewSimplify returns the !simplify command
+func NewSimplify() *Command {
+	return &Command{
+		Name:        "pb2simplify",
+		Description: "print the normalized version of a message",
+		Arguments: []Argument{
+			{Name: "message", Type: ArgumentRest},
+		},
+		Level: LevelModerator,
+		Run: func(ctx *Context) string {
+			normalizedMessage, err := normalize.Normalize(ctx.Args.String("message"))
 			if err != nil {
-				bot.Mention(channel, user, fmt.Sprintf("error normalizing string: %s", err.Error()))
-				return
+				return fmt.Sprintf("error normalizing string: %s", err.Error())
 			}
 
-			bot.Mention(channel, user, fmt.Sprintf("normalized string: '%s'", normalizedMessage))
-		}
+			return fmt.Sprintf("normalized string: '%s'", normalizedMessage)
+		},
 	}
 }
 
-type TimeMeOut struct {
-}
-
-func (c TimeMeOut) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	if len(parts) < 2 {
-		return
-	}
-
-	timeoutDuration, err := time.ParseDuration(parts[1])
-	if err != nil {
-		bot.Mention(channel, user, "invalid duration format. use !timemeout 1s or !timemeout 5m")
-		return
-	}
-
-	var reason string
-
-	if len(parts) > 2 {
-		reason = strings.Join(parts[2:], " ")
+// NewTimeMeOut returns the !timemeout command
+func NewTimeMeOut() *Command {
+	return &Command{
+		Name:        "timemeout",
+		Description: "time yourself out",
+		Arguments: []Argument{
+			{Name: "duration", Type: ArgumentDuration},
+			{Name: "reason", Type: ArgumentRest, Optional: true},
+		},
+		Run: func(ctx *Context) string {
+			ctx.Bot.Timeout(ctx.Channel, ctx.User, int(ctx.Args.Duration("duration").Seconds()), ctx.Args.String("reason"))
+			return ""
+		},
 	}
-
-	bot.Timeout(channel, user, int(timeoutDuration.Seconds()), reason)
-}
-
-type Join struct {
 }
 
-func (c *Join) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	if !user.HasGlobalPermission(pkg.PermissionAdmin) {
-		bot.Mention(channel, user, "you do not have permission to use this command. Admin permission is required")
-		return
-	}
-
-	if len(parts) < 2 {
-		return
-	}
+// NewJoin returns the !join command, which makes the bot join a channel
+func NewJoin() *Command {
+	return &Command{
+		Name:        "pb2join",
+		Description: "make the bot join a channel",
+		Arguments: []Argument{
+			{Name: "channel", Type: ArgumentUsername},
+		},
+		Permission: pkg.PermissionAdmin,
+		Run: func(ctx *Context) string {
+			// Channel admins are not allowed to make the bot join other channels
+			if !ctx.User.HasGlobalPermission(pkg.PermissionAdmin) {
+				return "you do not have permission to use this command. Admin permission is required"
+			}
 
-	channelName := parts[1]
+			target := ctx.Args.User("channel")
 
-	if strings.EqualFold(channelName, bot.TwitchAccount().Name()) {
-		bot.Mention(channel, user, "I cannot join my own channel")
-		return
-	}
+			if strings.EqualFold(target.Name, ctx.Bot.TwitchAccount().Name()) {
+				return "I cannot join my own channel"
+			}
 
-	channelID := bot.GetUserStore().GetID(channelName)
-	if channelID == "" {
-		bot.Mention(channel, user, "no channel with that name exists")
-		return
-	}
+			err := ctx.Bot.JoinChannel(target.ID)
+			if err != nil {
+				return err.Error()
+			}
 
-	err := bot.JoinChannel(channelID)
-	if err != nil {
-		bot.Mention(channel, user, err.Error())
-		return
+			return fmt.Sprintf("joined channel %s(%s)", target.Name, target.ID)
+		},
 	}
-
-	bot.Mention(channel, user, fmt.Sprintf("joined channel %s(%s)", channelName, channelID))
 }
 
-type Leave struct {
-}
-
-func (c *Leave) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	if !user.HasGlobalPermission(pkg.PermissionAdmin) {
-		bot.Mention(channel, user, "you do not have permission to use this command. Admin permission is required")
-		return
-	}
-
-	if len(parts) < 2 {
-		return
-	}
+// NewLeave returns the !leave command, which makes the bot leave a channel
+func NewLeave() *Command {
+	return &Command{
+		Name:        "pb2leave",
+		Description: "make the bot leave a channel",
+		Arguments: []Argument{
+			{Name: "channel", Type: ArgumentUsername},
+		},
+		Permission: pkg.PermissionAdmin,
+		Run: func(ctx *Context) string {
+			// Channel admins are not allowed to make the bot leave other channels
+			if !ctx.User.HasGlobalPermission(pkg.PermissionAdmin) {
+				return "you do not have permission to use this command. Admin permission is required"
+			}
 
-	channelName := parts[1]
+			target := ctx.Args.User("channel")
 
-	if strings.EqualFold(channelName, bot.TwitchAccount().Name()) {
-		bot.Mention(channel, user, "I cannot leave my own channel")
-		return
-	}
+			if strings.EqualFold(target.Name, ctx.Bot.TwitchAccount().Name()) {
+				return "I cannot leave my own channel"
+			}
 
-	channelID := bot.GetUserStore().GetID(channelName)
-	if channelID == "" {
-		bot.Mention(channel, user, "no channel with that name exists")
-		return
-	}
+			err := ctx.Bot.LeaveChannel(target.ID)
+			if err != nil {
+				return "Error leaving channel: " + err.Error()
+			}
 
-	err := bot.LeaveChannel(channelID)
-	if err != nil {
-		bot.Mention(channel, user, "Error leaving channel: "+err.Error())
-		return
+			return fmt.Sprintf("left channel %s(%s)", target.Name, target.ID)
+		},
 	}
-
-	bot.Mention(channel, user, fmt.Sprintf("left channel %s(%s)", channelName, channelID))
 }
 
-type Test struct {
-}
-
-func (c Test) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	if !user.IsModerator() && !user.IsBroadcaster(channel) {
-		return
-	}
-
-	if len(parts) <= 1 {
-		return
-	}
+// NewTest returns the !test command, which prints the variations of a message that the banphrase filter checks
+func NewTest() *Command {
+	return &Command{
+		Name:        "pb2test",
+		Description: "print the variations of a message",
+		Arguments: []Argument{
+			{Name: "message", Type: ArgumentRest},
+		},
+		Level: LevelModerator,
+		Run: func(ctx *Context) string {
+			variations, _, err := utils.MakeVariations(ctx.Args.String("message"), true)
+			if err != nil {
+				return err.Error()
+			}
 
-	variations, _, err := utils.MakeVariations(strings.Join(parts[1:], " "), true)
-	if err != nil {
-		bot.Mention(channel, user, err.Error())
-		return
-	}
+			for _, variation := range variations {
+				ctx.Bot.Mention(ctx.Channel, ctx.User, fmt.Sprintf("variation %s", variation))
+			}
 
-	for _, variation := range variations {
-		bot.Mention(channel, user, fmt.Sprintf("variation %s", variation))
+			return ""
+		},
 	}
 }
 
-type IsLive struct {
-}
-
-func (c IsLive) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	if !user.IsModerator() && !user.IsBroadcaster(channel) {
-		return
-	}
+// NewIsLive returns the !islive command
+func NewIsLive() *Command {
+	return &Command{
+		Name:        "pb2islive",
+		Description: "print whether the stream is live",
+		Level:       LevelModerator,
+		Run: func(ctx *Context) string {
+			if ctx.BotChannel.Stream().Status().Live() {
+				startedAt := ctx.BotChannel.Stream().Status().StartedAt()
+				return fmt.Sprintf("LIVE FOR %s KKona", utils.TimeSince(startedAt))
+			}
 
-	if botChannel.Stream().Status().Live() {
-		startedAt := botChannel.Stream().Status().StartedAt()
-		bot.Mention(channel, user, fmt.Sprintf("LIVE FOR %s KKona", utils.TimeSince(startedAt)))
-	} else {
-		bot.Mention(channel, user, "offline FeelsBadMan")
+			return "offline FeelsBadMan"
+		},
 	}
 }
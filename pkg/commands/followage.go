@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/pajlada/pajbot2/pkg/apirequest"
+	"github.com/pajlada/pajbot2/pkg/utils"
+)
+
+// NewFollowAge returns the !followage command, which prints how long a user has been following the channel
+func NewFollowAge() *Command {
+	return &Command{
+		Name:        "followage",
+		Description: "print how long a user has been following the channel",
+		Arguments: []Argument{
+			{Name: "username", Type: ArgumentUsername, Optional: true},
+		},
+		Run: func(ctx *Context) string {
+			target := ctx.Args.User("username")
+			if target.ID == "" {
+				target = ArgumentUser{
+					ID:   ctx.User.GetID(),
+					Name: ctx.User.GetName(),
+				}
+			}
+
+			follow, err := apirequest.TwitchWrapper.GetFollow(target.ID, ctx.Channel.GetID())
+			if err != nil {
+				fmt.Println("Error getting follow:", err)
+				return "error getting follow status"
+			}
+
+			if follow == nil {
+				return fmt.Sprintf("%s is not following %s", target.Name, ctx.Channel.GetChannel())
+			}
+
+			return fmt.Sprintf("%s has been following %s for %s", target.Name, ctx.Channel.GetChannel(), utils.TimeSince(follow.FollowedAt))
+		},
+	}
+}
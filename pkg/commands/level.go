@@ -0,0 +1,33 @@
+package commands
+
+import "github.com/pajlada/pajbot2/pkg"
+
+// User levels, these match the levels used in pajbot1
+const (
+	LevelUser        = 100
+	LevelSubscriber  = 250
+	LevelModerator   = 500
+	LevelBroadcaster = 1000
+	LevelAdmin       = 2000
+)
+
+// UserLevel returns the highest level the given user has in the given channel
+func UserLevel(channel pkg.Channel, user pkg.User) int {
+	if user.HasGlobalPermission(pkg.PermissionAdmin) {
+		return LevelAdmin
+	}
+
+	if user.IsBroadcaster(channel) {
+		return LevelBroadcaster
+	}
+
+	if user.IsModerator() || user.HasChannelPermission(channel, pkg.PermissionModeration) {
+		return LevelModerator
+	}
+
+	if user.IsSubscriber() {
+		return LevelSubscriber
+	}
+
+	return LevelUser
+}
@@ -7,34 +7,53 @@ import (
 	"github.com/pajlada/pajbot2/pkg"
 )
 
-type moduleCommand struct {
-	subCommands       *subCommands
-	defaultSubCommand string
+var moduleIDArguments = []Argument{
+	{Name: "module_id"},
 }
 
-func NewModule() pkg.CustomCommand {
-	u := &moduleCommand{
+func listOrNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// NewModule returns the !module command, which enables, disables and reloads modules in the channel
+func NewModule() *Command {
+	c := &Command{
+		Name:        "pb2module",
+		Description: "enable or disable modules in this channel",
+
 		subCommands:       newSubCommands(),
 		defaultSubCommand: "list",
 	}
 
-	u.subCommands.add("list", &subCommand{
+	c.subCommands.add("list", &subCommand{
 		permission: pkg.PermissionAdmin,
-		cb: func(bot pkg.Sender, botChannel pkg.BotChannel, target userTarget, channel pkg.Channel, user pkg.User, parts []string) string {
-			return "list modules"
+		cb: func(ctx *Context) string {
+			var enabled []string
+			var disabled []string
+
+			for _, status := range ctx.BotChannel.Modules() {
+				if status.Enabled {
+					enabled = append(enabled, fmt.Sprintf("%s (%s %d)", status.Spec.ID(), status.Spec.Phase(), status.Spec.Priority()))
+				} else {
+					disabled = append(disabled, status.Spec.ID())
+				}
+			}
+
+			return fmt.Sprintf("enabled modules (phase priority): %s. available modules: %s", listOrNone(enabled), listOrNone(disabled))
 		},
 	})
 
-	u.subCommands.add("enable", &subCommand{
+	c.subCommands.add("enable", &subCommand{
 		permission: pkg.PermissionAdmin,
-		cb: func(bot pkg.Sender, botChannel pkg.BotChannel, target userTarget, channel pkg.Channel, user pkg.User, parts []string) string {
-			if len(parts) < 3 {
-				return "usage: !module enable MODULE_ID"
-			}
-
-			moduleID := parts[2]
+		arguments:  moduleIDArguments,
+		cb: func(ctx *Context) string {
+			moduleID := ctx.Args.String("module_id")
 
-			err := botChannel.EnableModule(moduleID)
+			err := ctx.BotChannel.EnableModule(moduleID)
 			if err != nil {
 				return err.Error()
 			}
@@ -43,16 +62,13 @@ func NewModule() pkg.CustomCommand {
 		},
 	})
 
-	u.subCommands.addSC("disable", &subCommand{
+	c.subCommands.addSC("disable", &subCommand{
 		permission: pkg.PermissionAdmin,
-		cb: func(bot pkg.Sender, botChannel pkg.BotChannel, target userTarget, channel pkg.Channel, user pkg.User, parts []string) string {
-			if len(parts) < 3 {
-				return "usage: !module disable MODULE_ID"
-			}
-
-			moduleID := parts[2]
+		arguments:  moduleIDArguments,
+		cb: func(ctx *Context) string {
+			moduleID := ctx.Args.String("module_id")
 
-			err := botChannel.DisableModule(moduleID)
+			err := ctx.BotChannel.DisableModule(moduleID)
 			if err != nil {
 				return err.Error()
 			}
@@ -61,19 +77,20 @@ func NewModule() pkg.CustomCommand {
 		},
 	})
 
-	return u
-}
+	c.subCommands.add("reload", &subCommand{
+		permission: pkg.PermissionAdmin,
+		arguments:  moduleIDArguments,
+		cb: func(ctx *Context) string {
+			moduleID := ctx.Args.String("module_id")
 
-func (c *moduleCommand) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	subCommandName := c.defaultSubCommand
-	if len(parts) >= 2 {
-		subCommandName = strings.ToLower(parts[1])
-	}
+			err := ctx.BotChannel.ReloadModule(moduleID)
+			if err != nil {
+				return err.Error()
+			}
 
-	if subCommand, ok := c.subCommands.find(subCommandName); ok {
-		response := subCommand.run(bot, botChannel, userTarget{}, channel, user, parts)
-		if response != "" {
-			bot.Mention(channel, user, response)
-		}
-	}
+			return fmt.Sprintf("Reloaded module %s", moduleID)
+		},
+	})
+
+	return c
 }
@@ -8,7 +8,7 @@ import (
 	"github.com/pajlada/pajbot2/pkg"
 )
 
-const commandPrefix = "!"
+const commandPrefix = DefaultPrefix
 
 // Pajbot1Command is a command loaded from the old pajbot1 database
 type Pajbot1Command struct {
@@ -76,9 +76,5 @@ func (c *Pajbot1Command) IsTriggered(parts []string) bool {
 func (c *Pajbot1Command) Trigger(source pkg.Channel, user pkg.User, parts []string, sender pkg.Sender) error {
 	sender.Say(source, c.Action)
 
-	if user.HasGlobalPermission(pkg.PermissionReport) {
-		sender.Say(source, "You have report permissions!")
-	}
-
 	return nil
 }
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/pajlada/pajbot2/pkg"
+)
+
+var permaModeArguments = []Argument{
+	{Name: "mode"},
+}
+
+// NewPermaMode returns the !pb2permamode command, which manages the channel modes that the bot turns back on whenever they're turned off
+func NewPermaMode() *Command {
+	c := &Command{
+		Name:        "pb2permamode",
+		Description: "keep channel modes like subscribers-only mode enabled in this channel",
+
+		subCommands:       newSubCommands(),
+		defaultSubCommand: "list",
+	}
+
+	c.subCommands.add("list", &subCommand{
+		permission: pkg.PermissionModeration,
+		cb: func(ctx *Context) string {
+			var permaModes []string
+
+			for _, permaMode := range ctx.BotChannel.PermaModes() {
+				if permaMode.Argument != "" {
+					permaModes = append(permaModes, fmt.Sprintf("%s (%s)", permaMode.Mode, permaMode.Argument))
+				} else {
+					permaModes = append(permaModes, string(permaMode.Mode))
+				}
+			}
+
+			return fmt.Sprintf("perma modes: %s", listOrNone(permaModes))
+		},
+	})
+
+	c.subCommands.add("enable", &subCommand{
+		permission: pkg.PermissionModeration,
+		arguments: []Argument{
+			{Name: "mode"},
+			{Name: "argument", Optional: true},
+		},
+		cb: func(ctx *Context) string {
+			mode, err := pkg.ParseChannelMode(ctx.Args.String("mode"))
+			if err != nil {
+				return err.Error()
+			}
+
+			argument := ctx.Args.String("argument")
+
+			if err = ctx.BotChannel.EnablePermaMode(mode, argument); err != nil {
+				return err.Error()
+			}
+
+			return fmt.Sprintf("Enabled perma %s mode", mode)
+		},
+	})
+
+	c.subCommands.add("disable", &subCommand{
+		permission: pkg.PermissionModeration,
+		arguments:  permaModeArguments,
+		cb: func(ctx *Context) string {
+			mode, err := pkg.ParseChannelMode(ctx.Args.String("mode"))
+			if err != nil {
+				return err.Error()
+			}
+
+			if err = ctx.BotChannel.DisablePermaMode(mode); err != nil {
+				return err.Error()
+			}
+
+			return fmt.Sprintf("Disabled perma %s mode", mode)
+		},
+	})
+
+	return c
+}
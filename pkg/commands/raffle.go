@@ -1,108 +1,305 @@
 package commands
 
 import (
+	"fmt"
 	"math/rand"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pajlada/pajbot2/pkg"
 	"github.com/pajlada/pajbot2/pkg/utils"
 )
 
+const (
+	raffleDefaultPoints   = 1000
+	raffleDefaultDuration = 5 * time.Second
+	raffleMaxDuration     = 10 * time.Minute
+	raffleMaxWinners      = 25
+
+	// Reminders are never sent more often than this
+	raffleMinReminderInterval = 15 * time.Second
+	raffleReminderCount       = 3
+)
+
 func NewRaffle() *Raffle {
 	return &Raffle{
-		participantsUsername: make(map[string]string),
+		raffles: make(map[string]*raffle),
 	}
 }
 
+// Raffle handles the !roffle and !join commands. Each channel can have one raffle running at a time
 type Raffle struct {
-	running bool
-	points  int64
+	mutex sync.Mutex
+
+	// Running raffles, by channel ID
+	raffles map[string]*raffle
+}
+
+type raffle struct {
+	// The command prefix of the channel the raffle is running in
+	prefix string
+
+	points   int64
+	winners  int
+	subOnly  bool
+	duration time.Duration
+	endsAt   time.Time
 
 	// by user ID
 	participants         []string
 	participantsUsername map[string]string
 }
 
-func (c *Raffle) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	cmd := strings.ToLower(parts[0])
+func (r *raffle) joinMessage() string {
+	if r.subOnly {
+		return "subscribers type " + r.prefix + "join to have a chance to win"
+	}
 
-	if cmd == "!roffle" {
-		if !user.HasChannelPermission(channel, pkg.PermissionRaffle) {
-			bot.Mention(channel, user, "you do not have the permission to start a raffle")
-			return
+	return "type " + r.prefix + "join to have a chance to win"
+}
+
+func (r *raffle) prizeString() string {
+	if r.winners > 1 {
+		return fmt.Sprintf("%d points split between %d winners", r.points, r.winners)
+	}
+
+	return strconv.FormatInt(r.points, 10) + " points"
+}
+
+// parseRaffleArguments parses the arguments of a !roffle command
+// Usage: !roffle [POINTS] [DURATION] [WINNERS] [sub]
+// DURATION can be given either as seconds (30) or as a go duration (30s, 2m)
+func parseRaffleArguments(parts []string) (*raffle, error) {
+	r := &raffle{
+		points:               raffleDefaultPoints,
+		winners:              1,
+		duration:             raffleDefaultDuration,
+		participantsUsername: make(map[string]string),
+	}
+
+	var positional []string
+	for _, part := range parts {
+		switch strings.ToLower(part) {
+		case "":
+			continue
+		case "sub", "subs", "subonly":
+			r.subOnly = true
+		default:
+			positional = append(positional, part)
 		}
+	}
 
-		if c.running {
-			bot.Mention(channel, user, "a raffle is already running xd")
-			return
+	if len(positional) > 3 {
+		return nil, fmt.Errorf("too many arguments")
+	}
+
+	var err error
+
+	if len(positional) >= 1 {
+		r.points, err = strconv.ParseInt(positional[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount of points '%s'", positional[0])
 		}
+	}
 
-		var pointsToRaffle int64
-		if len(parts) == 1 {
-			pointsToRaffle = 1000
-		} else {
-			var err error
-			pointsToRaffle, err = strconv.ParseInt(parts[1], 10, 32)
-			if err != nil {
-				bot.Mention(channel, user, "usage: !raffle 500")
-				return
-			}
+	if len(positional) >= 2 {
+		r.duration, err = parseRaffleDuration(positional[1])
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		c.running = true
-		c.points = pointsToRaffle
+	if len(positional) >= 3 {
+		r.winners, err = strconv.Atoi(positional[2])
+		if err != nil || r.winners < 1 || r.winners > raffleMaxWinners {
+			return nil, fmt.Errorf("number of winners must be between 1 and %d", raffleMaxWinners)
+		}
+	}
 
-		bot.Say(channel, "A raffle is now running for "+strconv.FormatInt(pointsToRaffle, 10)+" points PepeS type !join to have a chance to win")
+	return r, nil
+}
 
-		time.AfterFunc(time.Second*5, func() {
-			c.running = false
+func parseRaffleDuration(s string) (time.Duration, error) {
+	var d time.Duration
+	if seconds, err := strconv.Atoi(s); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else {
+		d, err = time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s'", s)
+		}
+	}
 
-			// TODO: mutex loooooooooool
-			if len(c.participants) == 0 {
-				bot.Say(channel, "no one joined the raffle FeelsBadMan")
-				return
-			}
+	if d < time.Second || d > raffleMaxDuration {
+		return 0, fmt.Errorf("duration must be between 1s and %s", raffleMaxDuration)
+	}
 
-			winnerIndex := rand.Intn(len(c.participants))
-			winnerID := c.participants[winnerIndex]
-			winnerUsername := c.participantsUsername[winnerID]
+	return d, nil
+}
 
-			var newPoints uint64
+// Commands returns the !roffle and !join commands
+func (c *Raffle) Commands() []*Command {
+	return []*Command{
+		{
+			Name:        "roffle",
+			Description: "start a raffle. the duration is either seconds or i.e. 2m",
+			Arguments: []Argument{
+				{Name: "points", Optional: true},
+				{Name: "duration", Optional: true},
+				{Name: "winners", Optional: true},
+				{Name: "sub", Optional: true},
+			},
+			Permission: pkg.PermissionRaffle,
+			Run: func(ctx *Context) string {
+				// The arguments are validated by parseRaffleArguments since sub can be given in any position
+				c.start(ctx.Bot, ctx.Prefix, strings.Split(ctx.Message.GetText(), " ")[1:], ctx.Channel, ctx.User)
+				return ""
+			},
+		},
+		{
+			Name:        "join",
+			Description: "join the running raffle",
+			// Everyone needs to be able to join at the same time
+			Cooldown: &Cooldown{
+				User: 5,
+			},
+			Run: func(ctx *Context) string {
+				c.join(ctx.Bot, ctx.Channel, ctx.User)
+				return ""
+			},
+		},
+	}
+}
 
-			if c.points > 0 {
-				_, newPoints = bot.AddPoints(channel, winnerID, uint64(c.points))
-			} else {
-				newPoints = bot.ForceRemovePoints(channel, winnerID, uint64(utils.Abs64(c.points)))
-			}
+func (c *Raffle) start(bot pkg.Sender, prefix string, args []string, channel pkg.Channel, user pkg.User) {
+	r, err := parseRaffleArguments(args)
+	if err != nil {
+		bot.Mention(channel, user, err.Error()+". usage: "+prefix+"roffle [POINTS] [DURATION] [WINNERS] [sub]")
+		return
+	}
+
+	r.prefix = prefix
+
+	c.mutex.Lock()
+	if _, ok := c.raffles[channel.GetID()]; ok {
+		c.mutex.Unlock()
+		bot.Mention(channel, user, "a raffle is already running xd")
+		return
+	}
+	r.endsAt = time.Now().Add(r.duration)
+	c.raffles[channel.GetID()] = r
+	c.mutex.Unlock()
+
+	bot.Say(channel, fmt.Sprintf("A raffle is now running for %s PepeS %s (%s)", r.prizeString(), r.joinMessage(), r.duration))
+
+	go c.run(bot, channel, r)
+}
+
+func (c *Raffle) join(bot pkg.Sender, channel pkg.Channel, user pkg.User) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-			bot.Say(channel, "@"+winnerUsername+", you won the raffle PogChamp you now have "+strconv.FormatUint(newPoints, 10)+" points")
+	r, ok := c.raffles[channel.GetID()]
+	if !ok {
+		// No raffle is running
+		return
+	}
 
-			c.participants = []string{}
-			c.participantsUsername = make(map[string]string)
-		})
+	if r.subOnly && !user.IsSubscriber() {
+		return
+	}
 
-		// Start raffle, but only if you have permission
+	if _, ok := r.participantsUsername[user.GetID()]; ok {
+		// User has already joined the raffle
 		return
 	}
 
-	if cmd == "!join" {
-		if !c.running {
-			// No raffle is running
+	r.participantsUsername[user.GetID()] = user.GetName()
+	r.participants = append(r.participants, user.GetID())
+
+	bot.Mention(channel, user, "you have joined the raffle PepeS")
+}
+
+// run sends reminders while the raffle is running, and draws the winners once it's over
+func (c *Raffle) run(bot pkg.Sender, channel pkg.Channel, r *raffle) {
+	timer := time.NewTimer(r.duration)
+	defer timer.Stop()
+
+	var reminders <-chan time.Time
+	reminderInterval := r.duration / (raffleReminderCount + 1)
+	if reminderInterval >= raffleMinReminderInterval {
+		ticker := time.NewTicker(reminderInterval)
+		defer ticker.Stop()
+		reminders = ticker.C
+	}
+
+	for {
+		select {
+		case <-reminders:
+			timeLeft := time.Until(r.endsAt).Round(time.Second)
+			if timeLeft <= 0 {
+				continue
+			}
+
+			c.mutex.Lock()
+			numParticipants := len(r.participants)
+			c.mutex.Unlock()
+
+			bot.Say(channel, fmt.Sprintf("The raffle for %s ends in %s PepeS %s. %d users have joined so far", r.prizeString(), timeLeft, r.joinMessage(), numParticipants))
+
+		case <-timer.C:
+			c.finish(bot, channel, r)
 			return
 		}
+	}
+}
 
-		if _, ok := c.participantsUsername[user.GetID()]; !ok {
-			// User can join the raffle
-			c.participantsUsername[user.GetID()] = user.GetName()
-			c.participants = append(c.participants, user.GetID())
+func (c *Raffle) finish(bot pkg.Sender, channel pkg.Channel, r *raffle) {
+	c.mutex.Lock()
+	delete(c.raffles, channel.GetID())
+	c.mutex.Unlock()
 
-			bot.Mention(channel, user, "you have joined the raffle PepeS")
+	// The raffle has been removed from the running raffles, so nobody else can touch r now
+	if len(r.participants) == 0 {
+		bot.Say(channel, "no one joined the raffle FeelsBadMan")
+		return
+	}
+
+	numWinners := r.winners
+	if numWinners > len(r.participants) {
+		numWinners = len(r.participants)
+	}
+
+	pointsPerWinner := r.points / int64(numWinners)
+	if pointsPerWinner == 0 && r.points != 0 {
+		// Make sure every winner gets (or loses) at least one point
+		if r.points > 0 {
+			pointsPerWinner = 1
+		} else {
+			pointsPerWinner = -1
 		}
+	}
+
+	var winners []string
+	var newPoints uint64
+	for _, i := range rand.Perm(len(r.participants))[:numWinners] {
+		winnerID := r.participants[i]
+
+		if pointsPerWinner > 0 {
+			_, newPoints = bot.AddPoints(channel, winnerID, uint64(pointsPerWinner))
+		} else {
+			newPoints = bot.ForceRemovePoints(channel, winnerID, uint64(utils.Abs64(pointsPerWinner)))
+		}
+
+		winners = append(winners, "@"+r.participantsUsername[winnerID])
+	}
 
+	if numWinners == 1 {
+		bot.Say(channel, winners[0]+", you won the raffle PogChamp you now have "+strconv.FormatUint(newPoints, 10)+" points")
 		return
 	}
 
-	bot.Mention(channel, user, "how did you get here?")
+	bot.Say(channel, fmt.Sprintf("%s won the raffle PogChamp %d points each", strings.Join(winners, ", "), pointsPerWinner))
 }
@@ -1,35 +1,24 @@
 package commands
 
-import (
-	"strconv"
+import "strconv"
 
-	"github.com/pajlada/pajbot2/pkg"
-	"github.com/pajlada/pajbot2/pkg/utils"
-)
-
-type Rank struct {
-}
-
-func (c Rank) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
-	var potentialTarget string
-	targetID := user.GetID()
-
-	if len(parts) >= 2 {
-		potentialTarget = utils.FilterUsername(parts[1])
-		if potentialTarget != "" {
-			potentialTargetID := bot.GetUserStore().GetID(potentialTarget)
-			if potentialTargetID != "" {
-				targetID = potentialTargetID
-			} else {
-				potentialTarget = ""
+// NewRank returns the !rank command
+func NewRank() *Command {
+	return &Command{
+		Name:        "pb2rank",
+		Description: "print your or the given users rank in points",
+		Arguments: []Argument{
+			{Name: "user", Type: ArgumentUsername, Optional: true},
+		},
+		Run: func(ctx *Context) string {
+			if !ctx.Args.Has("user") {
+				rank := ctx.Bot.PointRank(ctx.Channel, ctx.User.GetID())
+				return "you are rank " + strconv.FormatUint(rank, 10) + " in points"
 			}
-		}
-	}
 
-	rank := bot.PointRank(channel, targetID)
-	if potentialTarget == "" {
-		bot.Mention(channel, user, "you are rank "+strconv.FormatUint(rank, 10)+" in points")
-	} else {
-		bot.Mention(channel, user, potentialTarget+" is rank "+strconv.FormatUint(rank, 10)+" in points")
+			target := ctx.Args.User("user")
+			rank := ctx.Bot.PointRank(ctx.Channel, target.ID)
+			return target.Name + " is rank " + strconv.FormatUint(rank, 10) + " in points"
+		},
 	}
 }
@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/sharedbans"
+)
+
+var sharedBanGroupArguments = []Argument{
+	{Name: "group"},
+}
+
+var sharedBanJoinArguments = []Argument{
+	{Name: "group"},
+	{Name: "auto", Optional: true},
+}
+
+var sharedBanUserArguments = []Argument{
+	{Name: "user", Type: ArgumentUsername},
+}
+
+// NewSharedBans returns the !pb2sharedbans command, which manages the shared ban groups and the shared ban allowlist of the channel
+func NewSharedBans(db *sql.DB) *Command {
+	c := &Command{
+		Name:        "pb2sharedbans",
+		Description: "share bans with other channels",
+
+		subCommands:       newSubCommands(),
+		defaultSubCommand: "groups",
+	}
+
+	c.subCommands.addSC("group", &subCommand{
+		permission: pkg.PermissionModeration,
+		cb: func(ctx *Context) string {
+			memberships, err := sharedbans.Groups(db, ctx.Channel.GetID())
+			if err != nil {
+				fmt.Println("Error loading shared ban groups:", err)
+				return "error loading groups"
+			}
+
+			if len(memberships) == 0 {
+				return "this channel is not in any shared ban group"
+			}
+
+			var groups []string
+			for _, m := range memberships {
+				if m.AutoApply {
+					groups = append(groups, m.GroupName+" (auto)")
+				} else {
+					groups = append(groups, m.GroupName)
+				}
+			}
+
+			return "shared ban groups: " + strings.Join(groups, ", ")
+		},
+	})
+
+	c.subCommands.add("join", &subCommand{
+		permission: pkg.PermissionAdmin,
+		arguments:  sharedBanJoinArguments,
+		cb: func(ctx *Context) string {
+			group := strings.ToLower(ctx.Args.String("group"))
+			autoApply := strings.ToLower(ctx.Args.String("auto")) == "auto"
+
+			if err := sharedbans.JoinGroup(db, ctx.Channel.GetID(), group, autoApply); err != nil {
+				fmt.Println("Error joining shared ban group:", err)
+				return "error joining group"
+			}
+
+			sharedbans.Log(db, ctx.Channel.GetID(), 0, sharedbans.LogJoinedGroup, ctx.User.GetID(), ctx.User.GetName(), group)
+
+			if autoApply {
+				return fmt.Sprintf("Joined shared ban group %s. Bans from the other channels in the group will be applied here automatically", group)
+			}
+
+			return fmt.Sprintf("Joined shared ban group %s. Bans from the other channels in the group must be approved on the dashboard", group)
+		},
+	})
+
+	c.subCommands.add("leave", &subCommand{
+		permission: pkg.PermissionAdmin,
+		arguments:  sharedBanGroupArguments,
+		cb: func(ctx *Context) string {
+			group := strings.ToLower(ctx.Args.String("group"))
+
+			if err := sharedbans.LeaveGroup(db, ctx.Channel.GetID(), group); err != nil {
+				return err.Error()
+			}
+
+			sharedbans.Log(db, ctx.Channel.GetID(), 0, sharedbans.LogLeftGroup, ctx.User.GetID(), ctx.User.GetName(), group)
+
+			return fmt.Sprintf("Left shared ban group %s", group)
+		},
+	})
+
+	c.subCommands.add("allow", &subCommand{
+		permission: pkg.PermissionModeration,
+		arguments:  sharedBanUserArguments,
+		cb: func(ctx *Context) string {
+			target := ctx.Args.User("user")
+
+			if err := sharedbans.Allow(db, ctx.Channel.GetID(), target.ID, target.Name, ctx.User.GetID()); err != nil {
+				return err.Error()
+			}
+
+			sharedbans.Log(db, ctx.Channel.GetID(), 0, sharedbans.LogAllowlistAdd, ctx.User.GetID(), ctx.User.GetName(), target.Name)
+
+			return fmt.Sprintf("%s will not be banned here by shared bans", target.Name)
+		},
+	})
+
+	c.subCommands.add("unallow", &subCommand{
+		permission: pkg.PermissionModeration,
+		arguments:  sharedBanUserArguments,
+		cb: func(ctx *Context) string {
+			target := ctx.Args.User("user")
+
+			if err := sharedbans.Disallow(db, ctx.Channel.GetID(), target.ID); err != nil {
+				return err.Error()
+			}
+
+			sharedbans.Log(db, ctx.Channel.GetID(), 0, sharedbans.LogAllowlistRemove, ctx.User.GetID(), ctx.User.GetName(), target.Name)
+
+			return fmt.Sprintf("Removed %s from the shared ban allowlist", target.Name)
+		},
+	})
+
+	return c
+}
@@ -1,9 +1,17 @@
 package commands
 
-import "github.com/pajlada/pajbot2/pkg"
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pajlada/pajbot2/pkg"
+)
 
 type subCommands struct {
 	m map[string]*subCommand
+
+	// Names as they're shown in the usage, without the plural aliases added by addSC
+	usageNames []string
 }
 
 func newSubCommands() *subCommands {
@@ -14,6 +22,7 @@ func newSubCommands() *subCommands {
 
 func (c *subCommands) add(name string, sc *subCommand) {
 	c.m[name] = sc
+	c.usageNames = append(c.usageNames, name)
 }
 
 func (c *subCommands) find(name string) (*subCommand, bool) {
@@ -23,23 +32,36 @@ func (c *subCommands) find(name string) (*subCommand, bool) {
 
 func (c *subCommands) addSC(name string, sc *subCommand) {
 	c.add(name, sc)
-	c.add(name+"s", sc)
+	c.m[name+"s"] = sc
+}
+
+func (c *subCommands) names() []string {
+	names := append([]string{}, c.usageNames...)
+	sort.Strings(names)
+	return names
 }
 
-type subCommandFunc func(pkg.Sender, pkg.BotChannel, userTarget, pkg.Channel, pkg.User, []string) string
+type subCommandFunc func(ctx *Context) string
 
 type subCommand struct {
 	permission pkg.Permission
+	arguments  []Argument
 	cb         subCommandFunc
 }
 
-func (c *subCommand) run(bot pkg.Sender, botChannel pkg.BotChannel, target userTarget, channel pkg.Channel, user pkg.User, parts []string) string {
-	if c.permission != pkg.PermissionNone {
-		if !user.HasPermission(channel, c.permission) {
-			return "you do not have permission to use this command"
-		}
+func (c *subCommand) run(ctx *Context, trigger string, parts []string) string {
+	if msg := checkPermission(ctx.Channel, ctx.User, c.permission, 0); msg != "" {
+		return msg
+	}
+
+	_, err := parseArguments(ctx.Bot.GetUserStore(), c.arguments, parts, ctx.Args)
+	if err != nil {
+		return fmt.Sprintf("%s. usage: %s", err, usageString(trigger, c.arguments))
+	}
 
+	if !ctx.startCooldown() {
+		return ""
 	}
 
-	return c.cb(bot, botChannel, target, channel, user, parts)
+	return c.cb(ctx)
 }
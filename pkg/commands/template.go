@@ -0,0 +1,319 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/utils"
+)
+
+const (
+	urlFetchTimeout   = 3 * time.Second
+	urlFetchMaxLength = 400
+)
+
+var errUnclosedVariable = errors.New("unclosed variable, missing )")
+
+var errChatCommandInjection = errors.New("response contains user data and would be sent as a chat command")
+
+var urlFetchClient = &http.Client{
+	Timeout: urlFetchTimeout,
+}
+
+// TemplateContext contains everything a response template can reference
+type TemplateContext struct {
+	Bot        pkg.Sender
+	BotChannel pkg.BotChannel
+	Channel    pkg.Channel
+	User       pkg.User
+
+	// Arguments given to the command, not including the trigger itself
+	Args []string
+
+	// Number of times the command has been used, including this use
+	Count uint64
+
+	// Hosts that $(urlfetch) is allowed to request. Subdomains of the hosts are allowed too
+	URLFetchAllowlist []string
+
+	// The event the template is rendered for, i.e. a sub. Makes variables like $(months) available
+	Event pkg.Event
+
+	// Why the user is being punished, for warnings. Available as $(reason)
+	Reason string
+}
+
+type templateRenderer struct {
+	ctx *TemplateContext
+
+	// set to true if any variable rendered data that could have been supplied by a user
+	hasUserdata bool
+}
+
+// RenderTemplate renders the given response template. Variables are written as $(name argument), i.e. $(args 1)
+// Variables can be nested, i.e. $(urlfetch https://example.com/?user=$(user))
+// The returned bool is true if the output contains data that could have been supplied by a user, in which case
+// the output should be run through the banphrase filter before it's sent.
+// Output with user data that starts with a chat command the template itself doesn't start with, i.e. "$(args)" rendering ".ban pajlada", is rejected with an error
+func RenderTemplate(template string, ctx *TemplateContext) (string, bool, error) {
+	r := &templateRenderer{
+		ctx: ctx,
+	}
+
+	output, _, err := r.parse(template, 0, false)
+	if err != nil {
+		return "", false, err
+	}
+
+	if r.hasUserdata && isChatCommand(output) && firstWord(output) != firstWord(template) {
+		return "", false, errChatCommandInjection
+	}
+
+	return output, r.hasUserdata, nil
+}
+
+// isChatCommand returns true if Twitch would run the message as a command, i.e. /timeout or .ban
+func isChatCommand(message string) bool {
+	message = strings.TrimSpace(message)
+
+	return strings.HasPrefix(message, "/") || strings.HasPrefix(message, ".")
+}
+
+func firstWord(message string) string {
+	words := strings.Fields(message)
+	if len(words) == 0 {
+		return ""
+	}
+
+	return words[0]
+}
+
+// parse renders the template starting at pos. If inner is true, we're inside of a variable and stop at the matching )
+// The output of variables is never parsed again, so users can't inject variables through arguments
+func (r *templateRenderer) parse(template string, pos int, inner bool) (string, int, error) {
+	var output strings.Builder
+
+	for pos < len(template) {
+		if strings.HasPrefix(template[pos:], "$(") {
+			expression, newPos, err := r.parse(template, pos+2, true)
+			if err != nil {
+				return "", 0, err
+			}
+
+			output.WriteString(r.evaluate(expression))
+			pos = newPos
+			continue
+		}
+
+		if inner && template[pos] == ')' {
+			return output.String(), pos + 1, nil
+		}
+
+		output.WriteByte(template[pos])
+		pos++
+	}
+
+	if inner {
+		return "", 0, errUnclosedVariable
+	}
+
+	return output.String(), pos, nil
+}
+
+func (r *templateRenderer) evaluate(expression string) string {
+	expression = strings.TrimSpace(expression)
+
+	name := expression
+	var argument string
+	if i := strings.IndexByte(expression, ' '); i != -1 {
+		name = expression[:i]
+		argument = strings.TrimSpace(expression[i+1:])
+	}
+
+	ctx := r.ctx
+
+	switch strings.ToLower(name) {
+	case "user":
+		r.hasUserdata = true
+		return ctx.User.GetName()
+
+	case "channel":
+		return ctx.Channel.GetChannel()
+
+	case "args":
+		r.hasUserdata = true
+		return r.args(argument)
+
+	case "points":
+		return strconv.FormatUint(ctx.Bot.GetPoints(ctx.Channel, ctx.User.GetID()), 10)
+
+	case "uptime":
+		return r.uptime()
+
+	case "count":
+		return strconv.FormatUint(ctx.Count, 10)
+
+	case "reason":
+		return ctx.Reason
+
+	case "random":
+		choices := strings.Split(argument, "|")
+		return strings.TrimSpace(choices[rand.Intn(len(choices))])
+
+	case "urlfetch":
+		r.hasUserdata = true
+		return r.urlFetch(argument)
+	}
+
+	if value, ok := r.eventVariable(strings.ToLower(name)); ok {
+		return value
+	}
+
+	// Unknown variables are left as they are
+	return "$(" + expression + ")"
+}
+
+// args returns the argument with the given 1-indexed number, or all arguments if no number is given
+// A number followed by a + returns all arguments from that number, i.e. $(args 2+)
+func (r *templateRenderer) args(argument string) string {
+	args := r.ctx.Args
+
+	if argument == "" {
+		return strings.Join(args, " ")
+	}
+
+	rest := strings.HasSuffix(argument, "+")
+	n, err := strconv.Atoi(strings.TrimSuffix(argument, "+"))
+	if err != nil || n < 1 || n > len(args) {
+		return ""
+	}
+
+	if rest {
+		return strings.Join(args[n-1:], " ")
+	}
+
+	return args[n-1]
+}
+
+// eventVariable returns the value of a variable that describes the event in the context, i.e. $(months) for subs
+// The returned bool is false if the context has no event, or the event doesn't have the variable
+func (r *templateRenderer) eventVariable(name string) (string, bool) {
+	switch event := r.ctx.Event.(type) {
+	case *pkg.SubEvent:
+		switch name {
+		case "months":
+			return strconv.Itoa(event.CumulativeMonths), true
+		case "streak":
+			return strconv.Itoa(event.StreakMonths), true
+		case "plan":
+			return pkg.SubPlanName(event.Plan), true
+		case "message":
+			r.hasUserdata = true
+			return event.Message, true
+		}
+
+	case *pkg.SubGiftEvent:
+		switch name {
+		case "recipient":
+			r.hasUserdata = true
+			return event.Recipient.GetName(), true
+		case "months":
+			return strconv.Itoa(event.Months), true
+		case "plan":
+			return pkg.SubPlanName(event.Plan), true
+		}
+
+	case *pkg.MassSubGiftEvent:
+		switch name {
+		case "gifts":
+			return strconv.Itoa(event.Count), true
+		case "totalgifts":
+			return strconv.Itoa(event.SenderTotal), true
+		case "plan":
+			return pkg.SubPlanName(event.Plan), true
+		}
+
+	case *pkg.RaidEvent:
+		switch name {
+		case "viewers":
+			return strconv.Itoa(event.Viewers), true
+		}
+
+	case *pkg.RitualEvent:
+		switch name {
+		case "ritual":
+			return event.Name, true
+		case "message":
+			r.hasUserdata = true
+			return event.Message, true
+		}
+	}
+
+	return "", false
+}
+
+func (r *templateRenderer) uptime() string {
+	if r.ctx.BotChannel == nil {
+		return "offline"
+	}
+
+	stream := r.ctx.BotChannel.Stream()
+	if stream == nil || stream.Status() == nil || !stream.Status().Live() {
+		return "offline"
+	}
+
+	return utils.TimeSince(stream.Status().StartedAt())
+}
+
+func (r *templateRenderer) urlAllowed(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+
+	for _, allowed := range r.ctx.URLFetchAllowlist {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *templateRenderer) urlFetch(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || !r.urlAllowed(u) {
+		return "(url not allowed)"
+	}
+
+	response, err := urlFetchClient.Get(u.String())
+	if err != nil {
+		fmt.Println("Error fetching url in command:", err)
+		return "(error fetching url)"
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Sprintf("(error fetching url: %d)", response.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, urlFetchMaxLength))
+	if err != nil {
+		fmt.Println("Error reading url response in command:", err)
+		return "(error fetching url)"
+	}
+
+	return strings.TrimSpace(utils.RemoveNewlines(string(body)))
+}
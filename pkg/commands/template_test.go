@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/pajlada/pajbot2/pkg"
+)
+
+type testChannel struct{}
+
+func (c testChannel) GetChannel() string { return "pajlada" }
+func (c testChannel) GetID() string      { return "11148817" }
+
+type testUser struct{}
+
+func (u testUser) HasPermission(pkg.Channel, pkg.Permission) bool        { return false }
+func (u testUser) HasGlobalPermission(pkg.Permission) bool               { return false }
+func (u testUser) HasChannelPermission(pkg.Channel, pkg.Permission) bool { return false }
+func (u testUser) GetName() string                                       { return "testman" }
+func (u testUser) GetDisplayName() string                                { return "TestMan" }
+func (u testUser) GetID() string                                         { return "1" }
+func (u testUser) IsModerator() bool                                     { return false }
+func (u testUser) IsBroadcaster(pkg.Channel) bool                        { return false }
+func (u testUser) IsSubscriber() bool                                    { return false }
+func (u testUser) GetBadges() map[string]int                             { return nil }
+
+func testRenderTemplate(t *testing.T, template string, expectedOutput string, expectedUserdata bool) {
+	ctx := &TemplateContext{
+		Channel: testChannel{},
+		User:    testUser{},
+		Args:    []string{"a", "b", "c"},
+		Count:   5,
+	}
+
+	output, hasUserdata, err := RenderTemplate(template, ctx)
+	if err != nil {
+		t.Errorf("Unexpected error rendering '%s': %s", template, err)
+		return
+	}
+
+	if output != expectedOutput {
+		t.Errorf("Rendering '%s': got '%s', expected '%s'", template, output, expectedOutput)
+	}
+
+	if hasUserdata != expectedUserdata {
+		t.Errorf("Rendering '%s': got userdata %v, expected %v", template, hasUserdata, expectedUserdata)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	testRenderTemplate(t, "no variables", "no variables", false)
+	testRenderTemplate(t, "welcome to $(channel)", "welcome to pajlada", false)
+	testRenderTemplate(t, "used $(count) times", "used 5 times", false)
+	testRenderTemplate(t, "hi $(user)", "hi testman", true)
+	testRenderTemplate(t, "$(args 2)", "b", true)
+	testRenderTemplate(t, "$(args 2+)", "b c", true)
+	testRenderTemplate(t, "$(args)", "a b c", true)
+	testRenderTemplate(t, "$(args 4)", "", true)
+	testRenderTemplate(t, "$(random xd)", "xd", false)
+	testRenderTemplate(t, "$(unknown variable)", "$(unknown variable)", false)
+	testRenderTemplate(t, "(not a variable)", "(not a variable)", false)
+}
+
+func TestRenderTemplateNoInjection(t *testing.T) {
+	ctx := &TemplateContext{
+		Channel: testChannel{},
+		User:    testUser{},
+		Args:    []string{"$(channel)"},
+	}
+
+	output, _, err := RenderTemplate("$(args 1)", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output != "$(channel)" {
+		t.Errorf("Variables in arguments were evaluated: got '%s'", output)
+	}
+}
+
+func TestRenderTemplateChatCommand(t *testing.T) {
+	ctx := &TemplateContext{
+		Channel: testChannel{},
+		User:    testUser{},
+		Args:    []string{".ban", "pajlada"},
+	}
+
+	for _, template := range []string{"$(args)", " $(args)", "/$(user)"} {
+		if _, _, err := RenderTemplate(template, ctx); err != errChatCommandInjection {
+			t.Errorf("Rendering '%s' should be rejected as a chat command, got %v", template, err)
+		}
+	}
+
+	// Commands written into the template by the channel itself are fine
+	testRenderTemplate(t, ".me says hi to $(user)", ".me says hi to testman", true)
+}
+
+func TestRenderTemplateUnclosed(t *testing.T) {
+	_, _, err := RenderTemplate("$(user", &TemplateContext{})
+	if err == nil {
+		t.Error("Expected an error for an unclosed variable")
+	}
+}
+
+func TestRenderTemplateURLFetchAllowlist(t *testing.T) {
+	ctx := &TemplateContext{
+		URLFetchAllowlist: []string{"pajlada.se"},
+	}
+
+	output, _, err := RenderTemplate("$(urlfetch https://evil.com/pajlada.se)", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output != "(url not allowed)" {
+		t.Errorf("Expected url to be denied, got '%s'", output)
+	}
+}
+
+func TestRenderTemplateEvent(t *testing.T) {
+	ctx := &TemplateContext{
+		Channel: testChannel{},
+		User:    testUser{},
+		Event: &pkg.SubEvent{
+			User:             testUser{},
+			Resub:            true,
+			Plan:             "1000",
+			CumulativeMonths: 12,
+		},
+	}
+
+	output, hasUserdata, err := RenderTemplate("$(user) resubscribed with $(plan) for $(months) months $(viewers)", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "testman resubscribed with Tier 1 for 12 months $(viewers)"; output != expected {
+		t.Errorf("Got '%s', expected '%s'", output, expected)
+	}
+
+	if !hasUserdata {
+		t.Error("Expected userdata")
+	}
+}
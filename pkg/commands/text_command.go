@@ -0,0 +1,381 @@
+package commands
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg"
+)
+
+const (
+	ResponseTypeSay     = "say"
+	ResponseTypeWhisper = "whisper"
+	ResponseTypeReply   = "reply"
+)
+
+var (
+	ErrInvalidTrigger      = errors.New("triggers may only contain letters, numbers and underscores")
+	ErrInvalidResponseType = errors.New("response type must be say, whisper or reply")
+	ErrMissingResponse     = errors.New("missing response")
+)
+
+var _ pkg.CustomCommand = &TextCommand{}
+
+// TextCommandEnvironment contains the settings shared between all text commands in a channel
+type TextCommandEnvironment struct {
+	// Hosts the $(urlfetch) variable is allowed to request
+	URLFetchAllowlist []string
+
+	// CheckBanphrases returns true if the given text contains a banned phrase. Responses that contain user data are run through this before they're sent
+	CheckBanphrases func(channel pkg.Channel, text string) bool
+}
+
+// TextCommand is a command with a static text response, stored in the Command table
+type TextCommand struct {
+	ID        int64
+	ChannelID string
+
+	// Without the command prefix, i.e. "test" for the !test command
+	Triggers []string
+
+	Response     string
+	ResponseType string
+
+	// Minimum user level required to use the command
+	Level int
+
+	// In seconds
+	GlobalCooldown int
+	UserCooldown   int
+
+	PointCost uint64
+
+	Enabled bool
+
+	// Number of times the command has been used
+	Count uint64
+
+	Environment *TextCommandEnvironment
+
+	db *sql.DB
+
+	countMutex sync.Mutex
+
+	// Shared with copies of the command, so editing it doesn't reset its cooldowns
+	cooldowns *cooldownTracker
+}
+
+// NewTextCommand returns a text command with the default values from the Command table
+func NewTextCommand(channelID string) *TextCommand {
+	return &TextCommand{
+		ChannelID:      channelID,
+		ResponseType:   ResponseTypeSay,
+		Level:          LevelUser,
+		GlobalCooldown: 5,
+		UserCooldown:   15,
+		Enabled:        true,
+		cooldowns:      &cooldownTracker{},
+	}
+}
+
+// Copy returns a copy of the command that shares its cooldowns with the original
+func (c *TextCommand) Copy() *TextCommand {
+	return &TextCommand{
+		ID:             c.ID,
+		ChannelID:      c.ChannelID,
+		Triggers:       append([]string{}, c.Triggers...),
+		Response:       c.Response,
+		ResponseType:   c.ResponseType,
+		Level:          c.Level,
+		GlobalCooldown: c.GlobalCooldown,
+		UserCooldown:   c.UserCooldown,
+		PointCost:      c.PointCost,
+		Enabled:        c.Enabled,
+		Count:          c.Count,
+		Environment:    c.Environment,
+		db:             c.db,
+		cooldowns:      c.cooldowns,
+	}
+}
+
+// ParseTriggers converts a pipe-separated list of triggers (i.e. "!test|test2") to a list of triggers without the command prefix
+func ParseTriggers(s string) ([]string, error) {
+	var triggers []string
+
+	for _, trigger := range strings.Split(s, "|") {
+		trigger = strings.ToLower(strings.TrimPrefix(trigger, commandPrefix))
+		if trigger == "" {
+			continue
+		}
+
+		for _, r := range trigger {
+			if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '_' {
+				return nil, ErrInvalidTrigger
+			}
+		}
+
+		triggers = append(triggers, trigger)
+	}
+
+	if len(triggers) == 0 {
+		return nil, ErrInvalidTrigger
+	}
+
+	return triggers, nil
+}
+
+// Validate makes sure the command can be saved
+func (c *TextCommand) Validate() error {
+	if len(c.Triggers) == 0 {
+		return ErrInvalidTrigger
+	}
+
+	switch c.ResponseType {
+	case ResponseTypeSay, ResponseTypeWhisper, ResponseTypeReply:
+	default:
+		return ErrInvalidResponseType
+	}
+
+	if strings.TrimSpace(c.Response) == "" {
+		return ErrMissingResponse
+	}
+
+	if c.GlobalCooldown < 0 || c.UserCooldown < 0 {
+		return errors.New("cooldowns may not be negative")
+	}
+
+	return nil
+}
+
+// HasTrigger returns true if the given trigger (i.e. "test" or "!test") triggers this command
+func (c *TextCommand) HasTrigger(trigger string) bool {
+	trigger = strings.ToLower(strings.TrimPrefix(trigger, commandPrefix))
+
+	for _, t := range c.Triggers {
+		if t == trigger {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *TextCommand) Trigger(bot pkg.Sender, botChannel pkg.BotChannel, parts []string, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
+	if !c.Enabled {
+		return
+	}
+
+	if UserLevel(channel, user) < c.Level {
+		return
+	}
+
+	if !c.cooldowns.use(user.GetID(), time.Duration(c.GlobalCooldown)*time.Second, time.Duration(c.UserCooldown)*time.Second) {
+		return
+	}
+
+	if c.PointCost > 0 {
+		if ok, _ := bot.RemovePoints(channel, user.GetID(), c.PointCost); !ok {
+			bot.Whisper(user, "you do not have enough points to use this command")
+			return
+		}
+	}
+
+	ctx := &TemplateContext{
+		Bot:        bot,
+		BotChannel: botChannel,
+		Channel:    channel,
+		User:       user,
+		Args:       parts[1:],
+	}
+
+	if strings.Contains(c.Response, "$(count") {
+		ctx.Count = c.incrementCount()
+	}
+
+	if c.Environment != nil {
+		ctx.URLFetchAllowlist = c.Environment.URLFetchAllowlist
+	}
+
+	// $(urlfetch) makes a HTTP request, which would hold up every other message the bot receives
+	if strings.Contains(c.Response, "$(urlfetch") {
+		go c.respond(bot, channel, user, ctx)
+		return
+	}
+
+	c.respond(bot, channel, user, ctx)
+}
+
+// respond renders the response of the command and sends it. Users are refunded if the response can't be sent
+func (c *TextCommand) respond(bot pkg.Sender, channel pkg.Channel, user pkg.User, ctx *TemplateContext) {
+	refund := func() {
+		if c.PointCost > 0 {
+			bot.AddPoints(channel, user.GetID(), c.PointCost)
+		}
+	}
+
+	response, hasUserdata, err := RenderTemplate(c.Response, ctx)
+	if err != nil {
+		fmt.Printf("Error rendering response of command %d: %s\n", c.ID, err)
+		refund()
+		return
+	}
+
+	if hasUserdata && c.Environment != nil && c.Environment.CheckBanphrases != nil {
+		if c.Environment.CheckBanphrases(channel, response) {
+			fmt.Printf("Response of command %d contained a banned phrase: %s\n", c.ID, response)
+			refund()
+			return
+		}
+	}
+
+	switch c.ResponseType {
+	case ResponseTypeWhisper:
+		bot.Whisper(user, response)
+	case ResponseTypeReply:
+		bot.Mention(channel, user, response)
+	default:
+		bot.Say(channel, response)
+	}
+}
+
+// incrementCount increments the use count of the command, and returns the new count
+func (c *TextCommand) incrementCount() uint64 {
+	c.countMutex.Lock()
+	c.Count++
+	count := c.Count
+	c.countMutex.Unlock()
+
+	if c.db != nil {
+		const queryF = `UPDATE Command SET count=count+1 WHERE id=?`
+		if _, err := c.db.Exec(queryF, c.ID); err != nil {
+			fmt.Println("Error incrementing command count:", err)
+		}
+	}
+
+	return count
+}
+
+const textCommandColumns = "id, channel_id, triggers, response, response_type, level, cooldown_all, cooldown_user, cost_points, enabled, count"
+
+func (c *TextCommand) scan(db *sql.DB, rows *sql.Rows) error {
+	var triggers string
+	if err := rows.Scan(&c.ID, &c.ChannelID, &triggers, &c.Response, &c.ResponseType, &c.Level, &c.GlobalCooldown, &c.UserCooldown, &c.PointCost, &c.Enabled, &c.Count); err != nil {
+		return err
+	}
+
+	c.db = db
+
+	c.Triggers = strings.Split(triggers, "|")
+
+	return nil
+}
+
+// LoadTextCommands loads all text commands for the given channel
+func LoadTextCommands(db *sql.DB, channelID string) ([]*TextCommand, error) {
+	const queryF = "SELECT " + textCommandColumns + " FROM Command WHERE channel_id=? ORDER BY id"
+
+	rows, err := db.Query(queryF, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var textCommands []*TextCommand
+
+	for rows.Next() {
+		c := &TextCommand{cooldowns: &cooldownTracker{}}
+		if err = c.scan(db, rows); err != nil {
+			return nil, err
+		}
+
+		textCommands = append(textCommands, c)
+	}
+
+	return textCommands, nil
+}
+
+// LoadTextCommand loads the text command with the given ID in the given channel. Returns nil if no such command exists
+func LoadTextCommand(db *sql.DB, channelID string, id int64) (*TextCommand, error) {
+	const queryF = "SELECT " + textCommandColumns + " FROM Command WHERE channel_id=? AND id=?"
+
+	rows, err := db.Query(queryF, channelID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	c := &TextCommand{cooldowns: &cooldownTracker{}}
+	if err = c.scan(db, rows); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Save inserts the command into the database if it's new, otherwise updates it
+func (c *TextCommand) Save(db *sql.DB) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	triggers := strings.Join(c.Triggers, "|")
+
+	if c.ID == 0 {
+		const queryF = `
+INSERT INTO
+	Command
+	(channel_id, triggers, response, response_type, level, cooldown_all, cooldown_user, cost_points, enabled)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+		res, err := db.Exec(queryF, c.ChannelID, triggers, c.Response, c.ResponseType, c.Level, c.GlobalCooldown, c.UserCooldown, c.PointCost, c.Enabled)
+		if err != nil {
+			return err
+		}
+
+		c.db = db
+		c.ID, err = res.LastInsertId()
+		return err
+	}
+
+	const queryF = `
+UPDATE
+	Command
+SET
+	triggers=?, response=?, response_type=?, level=?, cooldown_all=?, cooldown_user=?, cost_points=?, enabled=?
+WHERE
+	id=? AND channel_id=?`
+
+	_, err := db.Exec(queryF, triggers, c.Response, c.ResponseType, c.Level, c.GlobalCooldown, c.UserCooldown, c.PointCost, c.Enabled, c.ID, c.ChannelID)
+	return err
+}
+
+// DeleteTextCommand deletes the text command with the given ID in the given channel
+func DeleteTextCommand(db *sql.DB, channelID string, id int64) error {
+	const queryF = `DELETE FROM Command WHERE id=? AND channel_id=?`
+
+	res, err := db.Exec(queryF, id, channelID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return errors.New("no command with that ID exists")
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/users"
+)
+
+func permissionString(permissions pkg.Permission) string {
+	names := pkg.GetPermissionNames(permissions)
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// NewMyPermissions returns a whisper command that prints the users global permissions and their permissions in each channel
+func NewMyPermissions() *Command {
+	return &Command{
+		Name:        "permissions",
+		Description: "show your global permissions and your permissions in each channel",
+		Run: func(ctx *Context) string {
+			globalPermissions, err := users.GetUserGlobalPermissions(ctx.User.GetID())
+			if err != nil {
+				return "error getting global permissions: " + err.Error()
+			}
+
+			channelPermissions, err := users.GetUserChannelPermissionsByChannel(ctx.User.GetID())
+			if err != nil {
+				return "error getting channel permissions: " + err.Error()
+			}
+
+			response := "global permissions: " + permissionString(globalPermissions)
+
+			var channelIDs []string
+			for channelID, permissions := range channelPermissions {
+				if permissions != pkg.PermissionNone {
+					channelIDs = append(channelIDs, channelID)
+				}
+			}
+
+			channelNames := ctx.Bot.GetUserStore().GetNames(channelIDs)
+			for _, channelID := range channelIDs {
+				channelName, ok := channelNames[channelID]
+				if !ok {
+					channelName = channelID
+				}
+
+				response += fmt.Sprintf(". %s: %s", channelName, permissionString(channelPermissions[channelID]))
+			}
+
+			return response
+		},
+	}
+}
+
+// NewLink returns a whisper command that tells the user where to log in to link their twitch account to the web dashboard
+func NewLink(loginURL string) *Command {
+	return &Command{
+		Name:        "link",
+		Description: "link your twitch account to the web dashboard",
+		Run: func(ctx *Context) string {
+			return "log in with your twitch account at " + loginURL + " to link it to the dashboard"
+		},
+	}
+}
@@ -0,0 +1,149 @@
+package pkg
+
+// EventType is the kind of a channel event. The values match the msg-id tag of the USERNOTICE message the event was parsed from
+type EventType string
+
+const (
+	EventTypeSub         EventType = "sub"
+	EventTypeResub       EventType = "resub"
+	EventTypeSubGift     EventType = "subgift"
+	EventTypeMassSubGift EventType = "submysterygift"
+	EventTypeRaid        EventType = "raid"
+	EventTypeRitual      EventType = "ritual"
+)
+
+// Event is something that happened in a channel that isn't a chat message, i.e. a user subscribing or another channel raiding it
+type Event interface {
+	Type() EventType
+
+	// The user that caused the event, i.e. the subscriber, the gifter or the broadcaster of the raiding channel
+	GetUser() User
+}
+
+// SubPlanName returns a readable name for the msg-param-sub-plan tag, i.e. "Tier 1" for "1000"
+func SubPlanName(plan string) string {
+	switch plan {
+	case "Prime":
+		return "Prime"
+	case "1000":
+		return "Tier 1"
+	case "2000":
+		return "Tier 2"
+	case "3000":
+		return "Tier 3"
+	}
+
+	return plan
+}
+
+// SubEvent is a user subscribing or resubscribing
+type SubEvent struct {
+	User User
+
+	Resub bool
+
+	// Prime, 1000, 2000 or 3000
+	Plan string
+
+	// Number of months the user has been subscribed for in total, including this one
+	CumulativeMonths int
+
+	// Number of months in a row the user has been subscribed for. 0 if the user didn't share their streak
+	StreakMonths int
+
+	// Message the user shared with their resub. May be empty
+	Message string
+}
+
+func (e *SubEvent) Type() EventType {
+	if e.Resub {
+		return EventTypeResub
+	}
+
+	return EventTypeSub
+}
+
+func (e *SubEvent) GetUser() User {
+	return e.User
+}
+
+// SubGiftEvent is a user gifting a subscription to another user.
+// Every gift of a mass gift is also sent as a SubGiftEvent, right after the MassSubGiftEvent
+type SubGiftEvent struct {
+	User User
+
+	// The gifter chose to stay anonymous. User is then Twitch's anonymous gifter user
+	Anonymous bool
+
+	Recipient User
+
+	Plan string
+
+	// Number of months the recipient has been subscribed for in total, including this one
+	Months int
+}
+
+func (e *SubGiftEvent) Type() EventType {
+	return EventTypeSubGift
+}
+
+func (e *SubGiftEvent) GetUser() User {
+	return e.User
+}
+
+// MassSubGiftEvent is a user gifting subscriptions to several random users in the channel
+type MassSubGiftEvent struct {
+	User User
+
+	Anonymous bool
+
+	Plan string
+
+	// Number of subscriptions gifted
+	Count int
+
+	// Number of subscriptions the user has gifted in the channel in total. 0 if unknown
+	SenderTotal int
+}
+
+func (e *MassSubGiftEvent) Type() EventType {
+	return EventTypeMassSubGift
+}
+
+func (e *MassSubGiftEvent) GetUser() User {
+	return e.User
+}
+
+// RaidEvent is another channel raiding this channel
+type RaidEvent struct {
+	// Broadcaster of the raiding channel
+	User User
+
+	Viewers int
+}
+
+func (e *RaidEvent) Type() EventType {
+	return EventTypeRaid
+}
+
+func (e *RaidEvent) GetUser() User {
+	return e.User
+}
+
+// RitualEvent is a user taking part in a ritual, i.e. a new chatter introducing themselves
+type RitualEvent struct {
+	User User
+
+	// i.e. new_chatter
+	Name string
+
+	Message string
+}
+
+func (e *RitualEvent) Type() EventType {
+	return EventTypeRitual
+}
+
+func (e *RitualEvent) GetUser() User {
+	return e.User
+}
@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExemptionRole is a chat role whose users can be exempt from filters
+type ExemptionRole string
+
+const (
+	ExemptionRoleBroadcaster ExemptionRole = "broadcaster"
+	ExemptionRoleModerator   ExemptionRole = "moderator"
+	ExemptionRoleVIP         ExemptionRole = "vip"
+
+	// Subscribers are recognized by their subscriber or founder badge
+	ExemptionRoleSubscriber ExemptionRole = "subscriber"
+)
+
+var ExemptionRoles = []ExemptionRole{
+	ExemptionRoleBroadcaster,
+	ExemptionRoleModerator,
+	ExemptionRoleVIP,
+	ExemptionRoleSubscriber,
+}
+
+// ExemptionPolicy decides which users the filters of a channel leave alone
+type ExemptionPolicy struct {
+	Roles []ExemptionRole
+
+	// User names or user IDs, i.e. other bots in the channel
+	Users []string `json:",omitempty"`
+
+	// Users with any of these permissions are exempt, i.e. "moderation"
+	Permissions []string `json:",omitempty"`
+}
+
+// DefaultExemptionPolicy is used in channels that haven't configured their own policy
+func DefaultExemptionPolicy() ExemptionPolicy {
+	return ExemptionPolicy{
+		Roles: []ExemptionRole{ExemptionRoleBroadcaster, ExemptionRoleModerator},
+	}
+}
+
+// Validate returns an error if the policy contains a role or permission we don't know about
+func (p *ExemptionPolicy) Validate() error {
+	for _, role := range p.Roles {
+		known := false
+		for _, exemptionRole := range ExemptionRoles {
+			if role == exemptionRole {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			return fmt.Errorf("unknown role '%s'", role)
+		}
+	}
+
+	for _, permission := range p.Permissions {
+		if GetPermissionBit(permission) == PermissionNone {
+			return fmt.Errorf("unknown permission '%s'", permission)
+		}
+	}
+
+	return nil
+}
+
+func (p *ExemptionPolicy) hasRole(channel Channel, user User, role ExemptionRole) bool {
+	switch role {
+	case ExemptionRoleBroadcaster:
+		return user.IsBroadcaster(channel)
+	case ExemptionRoleModerator:
+		return user.IsModerator()
+	case ExemptionRoleVIP:
+		_, ok := user.GetBadges()["vip"]
+		return ok
+	case ExemptionRoleSubscriber:
+		return user.IsSubscriber()
+	}
+
+	return false
+}
+
+// Exempt returns true if filters should not act on messages the user sends in the channel
+func (p *ExemptionPolicy) Exempt(channel Channel, user User) bool {
+	for _, role := range p.Roles {
+		if p.hasRole(channel, user, role) {
+			return true
+		}
+	}
+
+	for _, allowedUser := range p.Users {
+		if strings.EqualFold(allowedUser, user.GetName()) || allowedUser == user.GetID() {
+			return true
+		}
+	}
+
+	// Permissions are looked up in the database, so they're checked last
+	if len(p.Permissions) > 0 && user.HasPermission(channel, GetPermissionBits(p.Permissions)) {
+		return true
+	}
+
+	return false
+}
+
+// ExemptionPolicyOverrider is implemented by modules that can be configured with their own exemption policy
+type ExemptionPolicyOverrider interface {
+	// Returns nil if the exemption policy of the channel should be used
+	ExemptionPolicyOverride() *ExemptionPolicy
+}
+
+// ExemptMessageHandler is implemented by filters that still need to see messages from exempt users, i.e. to let moderators change their settings from chat
+type ExemptMessageHandler interface {
+	OnExemptMessage(bot Sender, source Channel, user User, message Message) error
+}
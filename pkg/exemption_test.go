@@ -0,0 +1,64 @@
+package pkg
+
+import "testing"
+
+type exemptionTestUser struct {
+	testUser
+
+	moderator   bool
+	badges      map[string]int
+	permissions Permission
+}
+
+func (u exemptionTestUser) HasPermission(c Channel, p Permission) bool { return u.permissions&p != 0 }
+func (u exemptionTestUser) IsModerator() bool                          { return u.moderator }
+func (u exemptionTestUser) IsSubscriber() bool                         { return u.badges["subscriber"] > 0 }
+func (u exemptionTestUser) GetBadges() map[string]int                  { return u.badges }
+
+func TestExemptionPolicy(t *testing.T) {
+	channel := testChannel{}
+
+	tests := []struct {
+		name   string
+		policy ExemptionPolicy
+		user   User
+		exempt bool
+	}{
+		{"default policy, viewer", DefaultExemptionPolicy(), exemptionTestUser{}, false},
+		{"default policy, moderator", DefaultExemptionPolicy(), exemptionTestUser{moderator: true}, true},
+		{"default policy, vip", DefaultExemptionPolicy(), exemptionTestUser{badges: map[string]int{"vip": 1}}, false},
+		{"moderators not exempt", ExemptionPolicy{}, exemptionTestUser{moderator: true}, false},
+		{"vip", ExemptionPolicy{Roles: []ExemptionRole{ExemptionRoleVIP}}, exemptionTestUser{badges: map[string]int{"vip": 1}}, true},
+		{"subscriber", ExemptionPolicy{Roles: []ExemptionRole{ExemptionRoleSubscriber}}, exemptionTestUser{badges: map[string]int{"subscriber": 12}}, true},
+		{"allowlisted name", ExemptionPolicy{Users: []string{"TestMan"}}, exemptionTestUser{}, true},
+		{"allowlisted id", ExemptionPolicy{Users: []string{"1"}}, exemptionTestUser{}, true},
+		{"permission", ExemptionPolicy{Permissions: []string{"raffle", "moderation"}}, exemptionTestUser{permissions: PermissionModeration}, true},
+		{"missing permission", ExemptionPolicy{Permissions: []string{"moderation"}}, exemptionTestUser{permissions: PermissionRaffle}, false},
+	}
+
+	for _, test := range tests {
+		if exempt := test.policy.Exempt(channel, test.user); exempt != test.exempt {
+			t.Errorf("%s: expected exempt to be %v, got %v", test.name, test.exempt, exempt)
+		}
+	}
+}
+
+func TestExemptionPolicyValidate(t *testing.T) {
+	policy := ExemptionPolicy{
+		Roles:       []ExemptionRole{ExemptionRoleVIP},
+		Permissions: []string{"moderation"},
+	}
+	if err := policy.Validate(); err != nil {
+		t.Errorf("Policy should be valid: %s", err)
+	}
+
+	policy.Roles = append(policy.Roles, "staff")
+	if policy.Validate() == nil {
+		t.Error("Unknown role should be rejected")
+	}
+
+	policy = ExemptionPolicy{Permissions: []string{"xd"}}
+	if policy.Validate() == nil {
+		t.Error("Unknown permission should be rejected")
+	}
+}
@@ -3,6 +3,9 @@ package pkg
 type Message interface {
 	GetText() string
 
+	// Twitch's ID of the message, from the id tag. Used to delete the message
+	GetID() string
+
 	GetTwitchReader() EmoteReader
 
 	GetBTTVReader() EmoteReader
@@ -0,0 +1,12 @@
+package pkg
+
+// Values of the Action column in the ModerationAction table
+const (
+	ModerationActionUnknown = 0
+	ModerationActionTimeout = 1
+	ModerationActionBan     = 2
+	ModerationActionUnban   = 3
+
+	// A single message was deleted
+	ModerationActionDelete = 4
+)
@@ -1,5 +1,10 @@
 package pkg
 
+import (
+	"errors"
+	"time"
+)
+
 // A module is local to a bots channel
 // i.e. bot "pajbot" joins channels "pajlada" and "forsen"
 // Module list looks like this:
@@ -24,10 +29,52 @@ type Module interface {
 
 	OnWhisper(bot Sender, source User, message Message) error
 	OnMessage(bot Sender, source Channel, user User, message Message, action Action) error
+
+	// Called for events in the channel that aren't chat messages, i.e. subs and raids
+	OnEvent(bot Sender, source Channel, event Event) error
 }
 
 type ModuleMaker func() Module
 
+// ModulePhase decides when a module sees a message compared to other modules.
+// Modules run phase by phase, and by priority within a phase
+type ModulePhase int
+
+const (
+	// Parse modules read the message and prepare data for later modules, i.e. finding bttv emotes
+	ModulePhaseParse ModulePhase = iota
+
+	// Filter modules decide whether the user should be punished for the message
+	ModulePhaseFilter
+
+	// Act modules react to what the filters decided. They see every message, even if propagation was stopped.
+	// The chosen action itself is carried out once every module has seen the message
+	ModulePhaseAct
+
+	// Command modules respond to the message
+	ModulePhaseCommand
+)
+
+func (p ModulePhase) String() string {
+	switch p {
+	case ModulePhaseParse:
+		return "parse"
+	case ModulePhaseFilter:
+		return "filter"
+	case ModulePhaseAct:
+		return "act"
+	case ModulePhaseCommand:
+		return "command"
+	}
+
+	return "unknown"
+}
+
+// ErrStopPropagation can be returned from OnMessage or OnWhisper to stop the message from reaching the command modules,
+// i.e. when a filter has timed out the user and command modules should not respond to the message.
+// The remaining filters still see the message, so a stronger punishment from a later filter can win. It's not counted as a module failure
+var ErrStopPropagation = errors.New("stop propagation")
+
 type ModuleSpec interface {
 	ID() string
 	Name() string
@@ -36,4 +83,75 @@ type ModuleSpec interface {
 	Maker() ModuleMaker
 
 	Priority() int
+
+	Phase() ModulePhase
+
+	// IDs of the modules that must be enabled for this module to be enabled.
+	// A module runs after the modules it depends on, so it can only depend on modules in the same or an earlier phase
+	Dependencies() []string
+}
+
+// ModuleStatus describes a module that's available in a bot channel
+type ModuleStatus struct {
+	Spec    ModuleSpec
+	Enabled bool
+}
+
+// ModuleHealth describes how a module in a bot channel has been behaving since it was last enabled or reloaded
+type ModuleHealth struct {
+	ModuleID string
+
+	// Number of times the module returned an error or panicked while handling a message or whisper
+	Errors uint64
+	Panics uint64
+
+	// Reset whenever the module handles a message or whisper without failing
+	ConsecutiveFailures int
+
+	LastError     string     `json:",omitempty"`
+	LastErrorTime *time.Time `json:",omitempty"`
+
+	// The module was disabled because it failed too many times in a row. It stays disabled until it's reloaded or enabled again
+	AutoDisabled bool
+}
+
+// GlobalModuleScope decides how many instances of a global module are created
+type GlobalModuleScope int
+
+const (
+	// One instance is shared between all bots
+	GlobalModuleScopeApplication GlobalModuleScope = iota
+
+	// One instance is created for each bot
+	GlobalModuleScopeBot
+)
+
+// A global module is not tied to a bots channel. It receives messages from every channel the bot is in and every whisper the bot receives,
+// so it can hold state that spans channels, i.e. a ban list shared between channels
+type GlobalModule interface {
+	// After the module struct is created, it must be initialized with its settings
+	Initialize(settings []byte) error
+
+	// Called when the module is disabled. The module can do any cleanup it needs to do here
+	Disable() error
+
+	// Returns the spec for the module
+	Spec() GlobalModuleSpec
+
+	OnWhisper(bot Sender, source User, message Message) error
+	OnMessage(bot Sender, botChannel BotChannel, source Channel, user User, message Message, action Action) error
+}
+
+type GlobalModuleMaker func() GlobalModule
+
+type GlobalModuleSpec interface {
+	ID() string
+	Name() string
+	EnabledByDefault() bool
+
+	Scope() GlobalModuleScope
+
+	Maker() GlobalModuleMaker
+
+	Priority() int
 }
@@ -0,0 +1,447 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/apirequest"
+	"github.com/pajlada/pajbot2/pkg/pubsub"
+)
+
+const (
+	// How long messages are kept around so users that took part in a raid can be timed out once it's detected
+	antiRaidHistoryDuration = 2 * time.Minute
+
+	antiRaidCalmCheckInterval = 10 * time.Second
+
+	// New followers are looked up in batches, since follow bots can follow hundreds of times per minute
+	antiRaidAccountCheckInterval = 2 * time.Second
+	antiRaidAccountBatchSize     = 100
+)
+
+type antiRaidMessage struct {
+	userID   string
+	userName string
+
+	// Normalized text, see normalizeRaidMessage
+	text string
+
+	timestamp time.Time
+}
+
+type antiRaid struct {
+	botChannel pkg.BotChannel
+
+	server *server
+
+	mutex sync.Mutex
+
+	// Chat messages from the last antiRaidHistoryDuration, oldest first
+	history []antiRaidMessage
+
+	// When users followed the channel during the last window, oldest first
+	follows []time.Time
+
+	// IDs of followers whose account age we haven't looked up yet
+	uncheckedFollowers []string
+
+	// When new accounts followed the channel during the last window, oldest first
+	newAccountFollows []time.Time
+
+	// When new accounts followed the channel, by user ID. Accounts that followed while the raid mode is enabled,
+	// or within antiRaidHistoryDuration before it was enabled, are timed out when they chat
+	newAccounts map[string]time.Time
+
+	active bool
+
+	// Last time raid activity was seen. The raid mode is reverted once it's been calm for CalmPeriodSeconds
+	lastActivity time.Time
+
+	// Normalized texts of the messages that triggered the raid mode
+	raidMessages map[string]bool
+
+	// by user ID, so we don't time out the same user twice
+	timedOut map[string]bool
+
+	subscription  pubsub.Subscription
+	ticker        *time.Ticker
+	accountTicker *time.Ticker
+	done          chan struct{}
+
+	// Detection happens within a window of this many seconds
+	WindowSeconds int `json:",omitempty"`
+
+	// Number of follows within the window that's considered a follow-bot raid
+	FollowThreshold int `json:",omitempty"`
+
+	// Number of follows from new accounts within the window that's considered a follow-bot raid
+	NewAccountThreshold int `json:",omitempty"`
+
+	// Accounts created less than this many hours ago count as new accounts
+	NewAccountMaxAgeHours int `json:",omitempty"`
+
+	// Number of different users sending the same message within the window that's considered a raid
+	IdenticalMessageThreshold int `json:",omitempty"`
+
+	// Shorter messages are ignored, so emote spam during hype moments doesn't look like a raid
+	MinimumMessageLength int `json:",omitempty"`
+
+	// Chat commands sent when the raid mode is enabled and reverted, i.e. add ".emoteonly" and ".emoteonlyoff" for emote-only mode
+	EnableCommands []string `json:",omitempty"`
+	RevertCommands []string `json:",omitempty"`
+
+	TimeoutDuration int `json:",omitempty"`
+
+	CalmPeriodSeconds int `json:",omitempty"`
+
+	filterWarning
+	filterExemptions
+}
+
+var antiRaidSpec = moduleSpec{
+	id:    "anti_raid",
+	name:  "Anti raid",
+	maker: newAntiRaid,
+
+	phase: pkg.ModulePhaseFilter,
+}
+
+func newAntiRaid() pkg.Module {
+	return &antiRaid{
+		server: &_server,
+
+		raidMessages: make(map[string]bool),
+		timedOut:     make(map[string]bool),
+		newAccounts:  make(map[string]time.Time),
+
+		WindowSeconds:             30,
+		FollowThreshold:           20,
+		NewAccountThreshold:       8,
+		NewAccountMaxAgeHours:     24,
+		IdenticalMessageThreshold: 8,
+		MinimumMessageLength:      15,
+		EnableCommands:            []string{".followers 10m", ".slow 5"},
+		RevertCommands:            []string{".followersoff", ".slowoff"},
+		TimeoutDuration:           600,
+		CalmPeriodSeconds:         300,
+
+		filterWarning: newFilterWarning(),
+	}
+}
+
+func (m *antiRaid) Initialize(botChannel pkg.BotChannel, settings []byte) error {
+	m.botChannel = botChannel
+
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
+	m.server.pubSub.Subscribe(m, "Follow")
+
+	m.ticker = time.NewTicker(antiRaidCalmCheckInterval)
+	m.accountTicker = time.NewTicker(antiRaidAccountCheckInterval)
+	m.done = make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-m.ticker.C:
+				m.revertIfCalm()
+			case <-m.accountTicker.C:
+				m.checkNewAccounts()
+			case <-m.done:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (m *antiRaid) Disable() error {
+	m.ticker.Stop()
+	m.accountTicker.Stop()
+	close(m.done)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.subscription.Cancel()
+
+	// Don't leave the channel stuck in followers-only or slow mode
+	if m.active {
+		m.revert()
+	}
+
+	return nil
+}
+
+func (m *antiRaid) Spec() pkg.ModuleSpec {
+	return &antiRaidSpec
+}
+
+func (m *antiRaid) BotChannel() pkg.BotChannel {
+	return m.botChannel
+}
+
+func (m *antiRaid) AuthenticatedUser() pkg.User {
+	return nil
+}
+
+func (m *antiRaid) IsApplication() bool {
+	return true
+}
+
+func (m *antiRaid) Connection() pkg.PubSubConnection {
+	return m
+}
+
+func (m *antiRaid) window() time.Duration {
+	return time.Duration(m.WindowSeconds) * time.Second
+}
+
+func (m *antiRaid) MessageReceived(source pkg.PubSubSource, topic string, data []byte) error {
+	if err := m.subscription.Check("anti raid module"); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var msg pkg.PubSubFollowEvent
+	if err := json.Unmarshal(data, &msg); err != nil {
+		fmt.Println("Error unmarshalling:", err)
+		return nil
+	}
+
+	if msg.Channel.ID != m.botChannel.ChannelID() {
+		return nil
+	}
+
+	now := time.Now()
+
+	m.follows = append(m.follows, now)
+	if m.NewAccountThreshold > 0 {
+		m.uncheckedFollowers = append(m.uncheckedFollowers, msg.User.ID)
+	}
+
+	for len(m.follows) > 0 && now.Sub(m.follows[0]) >= m.window() {
+		m.follows = m.follows[1:]
+	}
+
+	if m.FollowThreshold > 0 && len(m.follows) >= m.FollowThreshold {
+		m.lastActivity = now
+
+		if !m.active {
+			m.enable(fmt.Sprintf("%d follows in %d seconds", len(m.follows), m.WindowSeconds))
+		}
+	}
+
+	return nil
+}
+
+// checkNewAccounts looks up how old the accounts of the latest followers are, and enables the raid mode if too many of them are new
+func (m *antiRaid) checkNewAccounts() {
+	m.mutex.Lock()
+	userIDs := m.uncheckedFollowers
+	if len(userIDs) > antiRaidAccountBatchSize {
+		userIDs = userIDs[:antiRaidAccountBatchSize]
+	}
+	m.uncheckedFollowers = m.uncheckedFollowers[len(userIDs):]
+	m.mutex.Unlock()
+
+	if len(userIDs) == 0 {
+		return
+	}
+
+	users, err := apirequest.TwitchWrapper.GetUsersByID(userIDs)
+	if err != nil {
+		fmt.Println("Error checking account age of new followers:", err)
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.subscription.Cancelled() {
+		return
+	}
+
+	now := time.Now()
+	maxAge := time.Duration(m.NewAccountMaxAgeHours) * time.Hour
+
+	for _, user := range users {
+		if now.Sub(user.CreatedAt) < maxAge {
+			m.newAccountFollows = append(m.newAccountFollows, now)
+			m.newAccounts[user.ID] = now
+		}
+	}
+
+	for len(m.newAccountFollows) > 0 && now.Sub(m.newAccountFollows[0]) >= m.window() {
+		m.newAccountFollows = m.newAccountFollows[1:]
+	}
+
+	if !m.active {
+		for userID, followedAt := range m.newAccounts {
+			if now.Sub(followedAt) >= antiRaidHistoryDuration {
+				delete(m.newAccounts, userID)
+			}
+		}
+	}
+
+	if len(m.newAccountFollows) < m.NewAccountThreshold {
+		return
+	}
+
+	m.lastActivity = now
+
+	if !m.active {
+		m.enable(fmt.Sprintf("%d new accounts followed in %d seconds", len(m.newAccountFollows), m.WindowSeconds))
+
+		bot := m.botChannel.Bot()
+		m.timeoutRaiders(bot, bot.MakeChannel(m.botChannel.ChannelName()), "")
+	}
+}
+
+// normalizeRaidMessage makes messages that only differ in case or whitespace identical
+func normalizeRaidMessage(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// We assume that mutex is locked already
+// Returns the number of different users that sent the text within the window
+func (m *antiRaid) countSenders(text string, now time.Time) int {
+	senders := make(map[string]bool)
+
+	for _, message := range m.history {
+		if message.text == text && now.Sub(message.timestamp) < m.window() {
+			senders[message.userID] = true
+		}
+	}
+
+	return len(senders)
+}
+
+// We assume that mutex is locked already
+func (m *antiRaid) enable(reason string) {
+	m.active = true
+
+	fmt.Printf("Enabling raid mode in %s: %s\n", m.botChannel.ChannelName(), reason)
+
+	bot := m.botChannel.Bot()
+	channel := bot.MakeChannel(m.botChannel.ChannelName())
+
+	for _, command := range m.EnableCommands {
+		bot.Say(channel, command)
+	}
+}
+
+// We assume that mutex is locked already
+func (m *antiRaid) revert() {
+	m.active = false
+	m.raidMessages = make(map[string]bool)
+	m.timedOut = make(map[string]bool)
+	m.newAccounts = make(map[string]time.Time)
+
+	fmt.Printf("Reverting raid mode in %s\n", m.botChannel.ChannelName())
+
+	bot := m.botChannel.Bot()
+	channel := bot.MakeChannel(m.botChannel.ChannelName())
+
+	for _, command := range m.RevertCommands {
+		bot.Say(channel, command)
+	}
+}
+
+func (m *antiRaid) revertIfCalm() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.subscription.Cancelled() && m.active && time.Since(m.lastActivity) >= time.Duration(m.CalmPeriodSeconds)*time.Second {
+		m.revert()
+	}
+}
+
+// We assume that mutex is locked already
+// Times out everyone in the chat history that sent one of the raid messages or followed from a new account, except for the user that's currently being handled
+func (m *antiRaid) timeoutRaiders(bot pkg.Sender, channel pkg.Channel, skipUserID string) {
+	for _, message := range m.history {
+		if !m.isRaider(message) || m.timedOut[message.userID] || message.userID == skipUserID {
+			continue
+		}
+
+		m.timedOut[message.userID] = true
+		bot.Timeout(channel, bot.MakeUser(message.userName), m.TimeoutDuration, "Raid protection")
+	}
+}
+
+// We assume that mutex is locked already
+func (m *antiRaid) isRaider(message antiRaidMessage) bool {
+	if m.raidMessages[message.text] {
+		return true
+	}
+
+	_, newAccount := m.newAccounts[message.userID]
+
+	return m.active && newAccount
+}
+
+func (m *antiRaid) OnWhisper(bot pkg.Sender, source pkg.User, message pkg.Message) error {
+	return nil
+}
+
+func (m *antiRaid) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
+func (m *antiRaid) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
+	now := time.Now()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// Short messages are kept too, since new accounts that followed during a raid are timed out no matter what they say
+	raidMessage := antiRaidMessage{
+		userID:    user.GetID(),
+		userName:  user.GetName(),
+		text:      normalizeRaidMessage(message.GetText()),
+		timestamp: now,
+	}
+	text := raidMessage.text
+
+	m.history = append(m.history, raidMessage)
+
+	for len(m.history) > 0 && now.Sub(m.history[0].timestamp) >= antiRaidHistoryDuration {
+		m.history = m.history[1:]
+	}
+
+	if m.IdenticalMessageThreshold > 0 && len(text) >= m.MinimumMessageLength && !m.raidMessages[text] && m.countSenders(text, now) >= m.IdenticalMessageThreshold {
+		m.raidMessages[text] = true
+
+		if !m.active {
+			m.enable(fmt.Sprintf("%d users sent the same message", m.IdenticalMessageThreshold))
+		}
+
+		m.timeoutRaiders(bot, channel, user.GetID())
+	}
+
+	if !m.isRaider(raidMessage) {
+		return nil
+	}
+
+	m.lastActivity = now
+	m.timedOut[user.GetID()] = true
+
+	action.Set(m.warn(bot, m.botChannel, channel, user, pkg.Timeout{
+		Duration: m.TimeoutDuration,
+		Reason:   "Raid protection",
+	}))
+
+	return pkg.ErrStopPropagation
+}
@@ -1,6 +1,8 @@
 package modules
 
 import (
+	"fmt"
+
 	"github.com/pajlada/pajbot2/pkg"
 )
 
@@ -8,21 +10,34 @@ type badCharacterFilter struct {
 	botChannel pkg.BotChannel
 
 	badCharacters []rune
+
+	filterWarning
+	filterExemptions
 }
 
 func newBadCharacterFilter() pkg.Module {
-	return &badCharacterFilter{}
+	return &badCharacterFilter{
+		filterWarning: newFilterWarning(),
+	}
 }
 
 var badCharacterSpec = moduleSpec{
 	id:    "bad_character_filter",
 	name:  "Bad character filter",
 	maker: newBadCharacterFilter,
+
+	phase: pkg.ModulePhaseFilter,
 }
 
 func (m *badCharacterFilter) Initialize(botChannel pkg.BotChannel, settings []byte) error {
 	m.botChannel = botChannel
 
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
 	m.badCharacters = append(m.badCharacters, '\x01')
 
 	return nil
@@ -44,14 +59,18 @@ func (m *badCharacterFilter) OnWhisper(bot pkg.Sender, source pkg.User, message
 	return nil
 }
 
+func (m *badCharacterFilter) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
 func (m *badCharacterFilter) OnMessage(bot pkg.Sender, source pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
 	for _, r := range message.GetText() {
 		for _, badCharacter := range m.badCharacters {
 			if r == badCharacter {
-				action.Set(pkg.Timeout{
+				action.Set(m.warn(bot, m.botChannel, source, user, pkg.Timeout{
 					Duration: 300, Reason: "Your message contains a banned character",
-				})
-				return nil
+				}))
+				return pkg.ErrStopPropagation
 			}
 		}
 	}
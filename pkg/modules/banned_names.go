@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"fmt"
 	"regexp"
 
 	"github.com/pajlada/pajbot2/pkg"
@@ -12,11 +13,16 @@ type bannedNames struct {
 	server *server
 
 	badUsernames []*regexp.Regexp
+
+	filterWarning
+	filterExemptions
 }
 
 func newBannedNames() pkg.Module {
 	return &bannedNames{
 		server: &_server,
+
+		filterWarning: newFilterWarning(),
 	}
 }
 
@@ -26,11 +32,19 @@ var bannedNamesSpec = moduleSpec{
 	maker: newBannedNames,
 
 	enabledByDefault: true,
+
+	phase: pkg.ModulePhaseFilter,
 }
 
 func (m *bannedNames) Initialize(botChannel pkg.BotChannel, settings []byte) error {
 	m.botChannel = botChannel
 
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
 	m.badUsernames = append(m.badUsernames, regexp.MustCompile(`tos_is_trash\d+`))
 	m.badUsernames = append(m.badUsernames, regexp.MustCompile(`trash_is_the_tos\d+`))
 	m.badUsernames = append(m.badUsernames, regexp.MustCompile(`terms_of_service_uncool\d+`))
@@ -59,6 +73,10 @@ func (m bannedNames) OnWhisper(bot pkg.Sender, source pkg.User, message pkg.Mess
 	return nil
 }
 
+func (m bannedNames) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
 func (m bannedNames) OnMessage(bot pkg.Sender, source pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
 	if source.GetChannel() != "forsen" {
 		return nil
@@ -67,8 +85,8 @@ func (m bannedNames) OnMessage(bot pkg.Sender, source pkg.Channel, user pkg.User
 	usernameBytes := []byte(user.GetName())
 	for _, badUsername := range m.badUsernames {
 		if badUsername.Match(usernameBytes) {
-			action.Set(pkg.Ban{"Ban evasion"})
-			return nil
+			action.Set(m.warn(bot, m.botChannel, source, user, pkg.Ban{Reason: "Ban evasion"}))
+			return pkg.ErrStopPropagation
 		}
 	}
 
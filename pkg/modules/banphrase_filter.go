@@ -2,6 +2,8 @@ package modules
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pajlada/pajbot2/pkg"
@@ -15,12 +17,44 @@ type pajbot1BanphraseFilter struct {
 	server *server
 
 	banphrases []pkg.Banphrase
+
+	filterWarning
+	filterExemptions
 }
 
 func newPajbot1BanphraseFilter() pkg.Module {
 	return &pajbot1BanphraseFilter{
 		server: &_server,
+
+		filterWarning: newFilterWarning(),
+	}
+}
+
+var (
+	// Enabled banphrase filters, by channel ID
+	_banphraseFiltersMutex sync.Mutex
+	_banphraseFilters      = make(map[string]*pajbot1BanphraseFilter)
+)
+
+// checkBanphrases returns true if the given text contains a phrase that's banned in the given channel
+// If the banphrase filter is not enabled in the channel, nothing is considered banned
+func checkBanphrases(channel pkg.Channel, text string) bool {
+	_banphraseFiltersMutex.Lock()
+	m, ok := _banphraseFilters[channel.GetID()]
+	_banphraseFiltersMutex.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	bp, err := m.match(text)
+	if err != nil {
+		fmt.Println("Error checking banphrases:", err)
+		// Better safe than sorry
+		return true
 	}
+
+	return bp != nil
 }
 
 var pajbot1BanphraseSpec = moduleSpec{
@@ -29,6 +63,8 @@ var pajbot1BanphraseSpec = moduleSpec{
 	maker: newPajbot1BanphraseFilter,
 
 	enabledByDefault: true,
+
+	phase: pkg.ModulePhaseFilter,
 }
 
 func (m *pajbot1BanphraseFilter) addCustomBanphrase(phrase string) {
@@ -48,34 +84,58 @@ func (m *pajbot1BanphraseFilter) addCustomBanphrase(phrase string) {
 	})
 }
 
-func (m *pajbot1BanphraseFilter) loadPajbot1Banphrases() error {
-	const queryF = `SELECT * FROM tb_banphrase`
-
-	session := m.server.oldSession
-
-	rows, err := session.Query(queryF)
+// loadBanphrases loads the enabled banphrases of this channel from the Banphrase table, i.e. the ones imported with import-pajbot1
+// Banphrases without a channel apply in every channel. Settings that are NULL are inherited from the banphrases group
+func (m *pajbot1BanphraseFilter) loadBanphrases() error {
+	const queryF = `
+SELECT
+	b.id, COALESCE(b.description, ''), b.phrase, COALESCE(b.length, g.length, 60), COALESCE(b.case_sensitive, g.case_sensitive, 0),
+	COALESCE(b.type, g.type, 0), COALESCE(b.sub_immunity, g.sub_immunity, 0), COALESCE(b.remove_accents, g.remove_accents, 0)
+FROM
+	Banphrase b
+LEFT JOIN
+	BanphraseGroup g ON g.id=b.group_id
+WHERE
+	COALESCE(b.enabled, g.enabled, 1)=1 AND (b.channel_id IS NULL OR b.channel_id=?)`
+
+	rows, err := m.server.sql.Query(queryF, m.botChannel.ChannelID())
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var bp filters.Pajbot1Banphrase
-		err = bp.LoadScan(rows)
+		bp := filters.Pajbot1Banphrase{
+			Enabled: true,
+		}
+
+		err = rows.Scan(&bp.ID, &bp.Name, &bp.Phrase, &bp.Length, &bp.CaseSensitive, &bp.Operator, &bp.SubImmunity, &bp.RemoveAccents)
 		if err != nil {
 			return err
 		}
 
-		if bp.Enabled {
-			m.banphrases = append(m.banphrases, &bp)
+		// A length of 0 means permaban
+		bp.Permanent = bp.Length == 0
+
+		if !bp.CaseSensitive {
+			bp.Phrase = strings.ToLower(bp.Phrase)
 		}
+
+		m.banphrases = append(m.banphrases, &bp)
 	}
-	return nil
+
+	return rows.Err()
 }
 
 func (m *pajbot1BanphraseFilter) Initialize(botChannel pkg.BotChannel, settings []byte) error {
 	m.botChannel = botChannel
 
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
 	// hard-coded banphrases
 	m.addCustomBanphrase("n!66ger")
 
@@ -274,15 +334,25 @@ func (m *pajbot1BanphraseFilter) Initialize(botChannel pkg.BotChannel, settings
 		SubImmunity:   false,
 		RemoveAccents: true,
 	})
-	err := m.loadPajbot1Banphrases()
+	err := m.loadBanphrases()
 	if err != nil {
 		return err
 	}
 
+	_banphraseFiltersMutex.Lock()
+	_banphraseFilters[botChannel.ChannelID()] = m
+	_banphraseFiltersMutex.Unlock()
+
 	return nil
 }
 
 func (m *pajbot1BanphraseFilter) Disable() error {
+	_banphraseFiltersMutex.Lock()
+	if _banphraseFilters[m.botChannel.ChannelID()] == m {
+		delete(_banphraseFilters, m.botChannel.ChannelID())
+	}
+	_banphraseFiltersMutex.Unlock()
+
 	return nil
 }
 
@@ -306,7 +376,38 @@ func (m *pajbot1BanphraseFilter) OnWhisper(bot pkg.Sender, source pkg.User, mess
 	return nil
 }
 
-func (m *pajbot1BanphraseFilter) check(bot pkg.Sender, source pkg.Channel, text string, action pkg.Action) error {
+func (m *pajbot1BanphraseFilter) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
+// match returns the first banphrase that the given text triggers, or nil if no banphrase was triggered
+func (m *pajbot1BanphraseFilter) match(text string) (pkg.Banphrase, error) {
+	originalVariations, lowercaseVariations, err := utils.MakeVariations(text, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bp := range m.banphrases {
+		variations := originalVariations
+		if !bp.IsCaseSensitive() {
+			variations = lowercaseVariations
+		}
+
+		for _, variation := range variations {
+			if bp.Triggers(variation) {
+				return bp, nil
+			}
+
+			if !bp.IsAdvanced() {
+				break
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func (m *pajbot1BanphraseFilter) check(bot pkg.Sender, source pkg.Channel, user pkg.User, text string, action pkg.Action) error {
 	originalVariations, lowercaseVariations, err := utils.MakeVariations(text, true)
 	if err != nil {
 		return err
@@ -342,10 +443,13 @@ func (m *pajbot1BanphraseFilter) check(bot pkg.Sender, source pkg.Channel, text
 
 				if source.GetChannel() == "krakenbul" || bp.GetID() == -1 {
 					reason := fmt.Sprintf("Matched banphrase with name '%s' and id '%d'", bp.GetName(), bp.GetID())
-					action.Set(pkg.Timeout{bp.GetLength(), reason})
+					action.Set(m.warn(bot, m.botChannel, source, user, pkg.Timeout{
+						Duration: bp.GetLength(),
+						Reason:   reason,
+					}))
 					action.SetNotifyModerator(bot.MakeUser("pajlada"))
 					// fmt.Printf("Banphrase triggered: %#v for user %s", bp, user.GetName())
-					return nil
+					return pkg.ErrStopPropagation
 				}
 			}
 
@@ -362,16 +466,9 @@ func (m *pajbot1BanphraseFilter) check(bot pkg.Sender, source pkg.Channel, text
 }
 
 func (m *pajbot1BanphraseFilter) OnMessage(bot pkg.Sender, source pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
-	if user.IsModerator() || user.IsBroadcaster(source) {
-		return nil
-	}
-
-	if user.GetName() == "supibot" {
-		return nil
+	if err := m.check(bot, source, user, message.GetText(), action); err != nil {
+		return err
 	}
 
-	m.check(bot, source, message.GetText(), action)
-	m.check(bot, source, user.GetName(), action)
-
-	return nil
+	return m.check(bot, source, user, user.GetName(), action)
 }
@@ -1,7 +1,8 @@
 package modules
 
 import (
-	"strings"
+	"fmt"
+	"sync"
 
 	"github.com/pajlada/pajbot2/pkg"
 	"github.com/pajlada/pajbot2/pkg/commands"
@@ -12,7 +13,16 @@ type basicCommandsModule struct {
 
 	server *server
 
-	commands map[string]pkg.CustomCommand
+	commands *commands.Registry
+
+	// Command prefix used in this channel. Defaults to !
+	Prefix string `json:",omitempty"`
+
+	// Cooldown of the commands that aren't configured in Cooldowns
+	DefaultCooldown *commands.Cooldown `json:",omitempty"`
+
+	// Cooldowns by command name, i.e. "pb2ping"
+	Cooldowns map[string]commands.Cooldown `json:",omitempty"`
 }
 
 var basicCommandsModuleSpec = &moduleSpec{
@@ -20,48 +30,98 @@ var basicCommandsModuleSpec = &moduleSpec{
 	name:             "Basic commands",
 	maker:            newBasicCommandsModule,
 	enabledByDefault: true,
+
+	phase: pkg.ModulePhaseCommand,
+}
+
+var (
+	// Command prefixes, by channel ID
+	_commandPrefixesMutex sync.Mutex
+	_commandPrefixes      = make(map[string]string)
+)
+
+// commandPrefix returns the command prefix used in the given channel
+func commandPrefix(channelID string) string {
+	_commandPrefixesMutex.Lock()
+	defer _commandPrefixesMutex.Unlock()
+
+	if prefix, ok := _commandPrefixes[channelID]; ok {
+		return prefix
+	}
+
+	return commands.DefaultPrefix
 }
 
 func newBasicCommandsModule() pkg.Module {
 	return &basicCommandsModule{
 		server: &_server,
 
-		commands: make(map[string]pkg.CustomCommand),
-	}
-}
+		commands: commands.NewRegistry("pb2help"),
 
-func (m *basicCommandsModule) registerCommand(aliases []string, command pkg.CustomCommand) {
-	for _, alias := range aliases {
-		m.commands[alias] = command
+		Prefix: commands.DefaultPrefix,
 	}
 }
 
 func (m *basicCommandsModule) Initialize(botChannel pkg.BotChannel, settings []byte) error {
 	m.botChannel = botChannel
 
-	m.registerCommand([]string{"!userid"}, &commands.GetUserID{})
-	m.registerCommand([]string{"!username"}, &commands.GetUserName{})
-	m.registerCommand([]string{"!pb2points"}, &commands.GetPoints{})
-	m.registerCommand([]string{"!pb2roulette"}, &commands.Roulette{})
-	m.registerCommand([]string{"!pb2givepoints"}, &commands.GivePoints{})
-	// m.registerCommand([]string{"!pb2addpoints"}, &commands.AddPoints{})
-	// m.registerCommand([]string{"!pb2removepoints"}, &commands.RemovePoints{})
-	m.registerCommand([]string{"!roffle", "!join"}, commands.NewRaffle())
-	m.registerCommand([]string{"!user"}, commands.NewUser())
-	m.registerCommand([]string{"!pb2rank"}, &commands.Rank{})
-	m.registerCommand([]string{"!pb2ping"}, &commands.Ping{})
-	m.registerCommand([]string{"!pb2simplify"}, &commands.Simplify{})
-	// m.registerCommand([]string{"!timemeout"}, &commands.TimeMeOut{})
-	m.registerCommand([]string{"!pb2test"}, &commands.Test{})
-	m.registerCommand([]string{"!pb2join"}, &commands.Join{})
-	m.registerCommand([]string{"!pb2leave"}, &commands.Leave{})
-	m.registerCommand([]string{"!pb2module"}, commands.NewModule())
-	m.registerCommand([]string{"!pb2islive"}, commands.IsLive{})
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
+	if m.Prefix == "" {
+		m.Prefix = commands.DefaultPrefix
+	}
+
+	_commandPrefixesMutex.Lock()
+	_commandPrefixes[botChannel.ChannelID()] = m.Prefix
+	_commandPrefixesMutex.Unlock()
+
+	m.commands.Register(commands.NewGetUserID())
+	m.commands.Register(commands.NewGetUserName())
+	m.commands.Register(commands.NewGetPoints())
+	m.commands.Register(commands.NewRoulette())
+	m.commands.Register(commands.NewGivePoints())
+	// m.commands.Register(commands.NewAddPoints())
+	// m.commands.Register(commands.NewRemovePoints())
+	for _, c := range commands.NewRaffle().Commands() {
+		m.commands.Register(c)
+	}
+	m.commands.Register(commands.NewUser())
+	m.commands.Register(commands.NewRank())
+	m.commands.Register(commands.NewPing())
+	m.commands.Register(commands.NewSimplify())
+	// m.commands.Register(commands.NewTimeMeOut())
+	m.commands.Register(commands.NewTest())
+	m.commands.Register(commands.NewJoin())
+	m.commands.Register(commands.NewLeave())
+	m.commands.Register(commands.NewModule())
+	m.commands.Register(commands.NewPermaMode())
+	m.commands.Register(newGlobalModuleCommand())
+	m.commands.Register(commands.NewSharedBans(m.server.sql))
+	m.commands.Register(commands.NewIsLive())
+	m.commands.Register(commands.NewFollowAge())
+
+	if m.DefaultCooldown != nil {
+		m.commands.SetDefaultCooldown(*m.DefaultCooldown)
+	}
+
+	for name, cooldown := range m.Cooldowns {
+		if err := m.commands.SetCooldown(name, cooldown); err != nil {
+			fmt.Println("Error setting command cooldown:", err)
+		}
+	}
 
 	return nil
 }
 
 func (m *basicCommandsModule) Disable() error {
+	_commandPrefixesMutex.Lock()
+	delete(_commandPrefixes, m.botChannel.ChannelID())
+	_commandPrefixesMutex.Unlock()
+
 	return nil
 }
 
@@ -77,15 +137,12 @@ func (m *basicCommandsModule) OnWhisper(bot pkg.Sender, source pkg.User, message
 	return nil
 }
 
-func (m *basicCommandsModule) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
-	parts := strings.Split(message.GetText(), " ")
-	if len(parts) == 0 {
-		return nil
-	}
+func (m *basicCommandsModule) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
 
-	if command, ok := m.commands[strings.ToLower(parts[0])]; ok {
-		command.Trigger(bot, m.botChannel, parts, channel, user, message, action)
-	}
+func (m *basicCommandsModule) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
+	m.commands.Dispatch(m.Prefix, bot, m.botChannel, channel, user, message, action)
 
 	return nil
 }
@@ -23,6 +23,8 @@ var bttvEmoteParserSpec = &moduleSpec{
 
 	enabledByDefault: true,
 
+	phase: pkg.ModulePhaseParse,
+
 	priority: -50000,
 }
 
@@ -53,6 +55,10 @@ func (m *bttvEmoteParser) OnWhisper(bot pkg.Sender, user pkg.User, message pkg.M
 	return nil
 }
 
+func (m *bttvEmoteParser) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
 func (m *bttvEmoteParser) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
 	parts := strings.FieldsFunc(message.GetText(), func(r rune) bool {
 		// TODO(pajlada): This needs better testing
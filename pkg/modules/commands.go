@@ -0,0 +1,387 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/commands"
+	"github.com/pajlada/pajbot2/pkg/pubsub"
+)
+
+type commandsModule struct {
+	botChannel pkg.BotChannel
+
+	server *server
+
+	mutex sync.Mutex
+
+	textCommands []*commands.TextCommand
+
+	environment *commands.TextCommandEnvironment
+
+	subscription pubsub.Subscription
+
+	// Hosts that the $(urlfetch) variable is allowed to request
+	URLFetchAllowlist []string `json:",omitempty"`
+}
+
+var commandsModuleSpec = &moduleSpec{
+	id:               "commands",
+	name:             "Commands",
+	maker:            newCommandsModule,
+	enabledByDefault: true,
+
+	phase: pkg.ModulePhaseCommand,
+}
+
+func newCommandsModule() pkg.Module {
+	return &commandsModule{
+		server: &_server,
+	}
+}
+
+func (m *commandsModule) loadCommands() error {
+	textCommands, err := commands.LoadTextCommands(m.server.sql, m.botChannel.ChannelID())
+	if err != nil {
+		return err
+	}
+
+	for _, c := range textCommands {
+		c.Environment = m.environment
+	}
+
+	m.mutex.Lock()
+	m.textCommands = textCommands
+	m.mutex.Unlock()
+
+	return nil
+}
+
+func (m *commandsModule) Initialize(botChannel pkg.BotChannel, settings []byte) error {
+	m.botChannel = botChannel
+
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
+	m.environment = &commands.TextCommandEnvironment{
+		URLFetchAllowlist: m.URLFetchAllowlist,
+		CheckBanphrases:   checkBanphrases,
+	}
+
+	if err := m.loadCommands(); err != nil {
+		return err
+	}
+
+	m.server.pubSub.Subscribe(m, "CommandsUpdated")
+
+	return nil
+}
+
+func (m *commandsModule) Disable() error {
+	m.subscription.Cancel()
+
+	return nil
+}
+
+func (m *commandsModule) Spec() pkg.ModuleSpec {
+	return commandsModuleSpec
+}
+
+func (m *commandsModule) BotChannel() pkg.BotChannel {
+	return m.botChannel
+}
+
+func (m *commandsModule) AuthenticatedUser() pkg.User {
+	return nil
+}
+
+func (m *commandsModule) IsApplication() bool {
+	return true
+}
+
+func (m *commandsModule) Connection() pkg.PubSubConnection {
+	return m
+}
+
+func (m *commandsModule) MessageReceived(source pkg.PubSubSource, topic string, data []byte) error {
+	if err := m.subscription.Check("commands module"); err != nil {
+		return err
+	}
+
+	switch topic {
+	case "CommandsUpdated":
+		var msg pkg.PubSubCommandsUpdated
+		if err := json.Unmarshal(data, &msg); err != nil {
+			fmt.Println("Error unmarshalling:", err)
+			return nil
+		}
+
+		if msg.ChannelID != m.botChannel.ChannelID() {
+			return nil
+		}
+
+		if err := m.loadCommands(); err != nil {
+			fmt.Println("Error reloading commands:", err)
+		}
+	}
+
+	return nil
+}
+
+func (m *commandsModule) OnWhisper(bot pkg.Sender, source pkg.User, message pkg.Message) error {
+	return nil
+}
+
+func (m *commandsModule) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
+func (m *commandsModule) findCommand(trigger string) *commands.TextCommand {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, c := range m.textCommands {
+		if c.HasTrigger(trigger) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func (m *commandsModule) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
+	prefix := commandPrefix(m.botChannel.ChannelID())
+
+	parts := strings.Split(message.GetText(), " ")
+	if len(parts) == 0 || !strings.HasPrefix(parts[0], prefix) {
+		return nil
+	}
+
+	trigger := strings.ToLower(strings.TrimPrefix(parts[0], prefix))
+
+	switch trigger {
+	case "pb2addcmd", "pb2editcmd", "pb2delcmd":
+		if commands.UserLevel(channel, user) < commands.LevelModerator {
+			return nil
+		}
+
+		var response string
+		switch trigger {
+		case "pb2addcmd":
+			response = m.addCommand(parts[1:])
+		case "pb2editcmd":
+			response = m.editCommand(parts[1:])
+		case "pb2delcmd":
+			response = m.deleteCommand(parts[1:])
+		}
+
+		if response != "" {
+			bot.Mention(channel, user, response)
+		}
+
+		return nil
+	}
+
+	if c := m.findCommand(trigger); c != nil {
+		c.Trigger(bot, m.botChannel, parts, channel, user, message, action)
+	}
+
+	return nil
+}
+
+// parseCommandOptions applies the options at the start of args to the given command
+// Options: --level LEVEL, --cd SECONDS, --usercd SECONDS, --cost POINTS, --say, --whisper, --reply, --enable, --disable, --triggers TRIGGERS
+// The remaining arguments are returned
+func parseCommandOptions(c *commands.TextCommand, args []string) ([]string, error) {
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		option := strings.ToLower(strings.TrimPrefix(args[0], "--"))
+		args = args[1:]
+
+		switch option {
+		case "say":
+			c.ResponseType = commands.ResponseTypeSay
+			continue
+		case "whisper":
+			c.ResponseType = commands.ResponseTypeWhisper
+			continue
+		case "reply":
+			c.ResponseType = commands.ResponseTypeReply
+			continue
+		case "enable":
+			c.Enabled = true
+			continue
+		case "disable":
+			c.Enabled = false
+			continue
+		}
+
+		if len(args) == 0 {
+			return nil, fmt.Errorf("missing value for option --%s", option)
+		}
+
+		value := args[0]
+		args = args[1:]
+
+		var err error
+
+		switch option {
+		case "level":
+			c.Level, err = strconv.Atoi(value)
+		case "cd":
+			c.GlobalCooldown, err = strconv.Atoi(value)
+		case "usercd":
+			c.UserCooldown, err = strconv.Atoi(value)
+		case "cost":
+			c.PointCost, err = strconv.ParseUint(value, 10, 32)
+		case "triggers":
+			c.Triggers, err = commands.ParseTriggers(value)
+		default:
+			return nil, fmt.Errorf("unknown option --%s", option)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for option --%s", option)
+		}
+	}
+
+	return args, nil
+}
+
+// We assume that mutex is locked already
+func (m *commandsModule) triggerConflict(c *commands.TextCommand) string {
+	for _, trigger := range c.Triggers {
+		for _, other := range m.textCommands {
+			if other.ID != c.ID && other.HasTrigger(trigger) {
+				return trigger
+			}
+		}
+	}
+
+	return ""
+}
+
+func (m *commandsModule) saveCommand(c *commands.TextCommand) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if trigger := m.triggerConflict(c); trigger != "" {
+		return fmt.Errorf("a command with the trigger !%s already exists", trigger)
+	}
+
+	isNew := c.ID == 0
+
+	if err := c.Save(m.server.sql); err != nil {
+		return err
+	}
+
+	if isNew {
+		m.textCommands = append(m.textCommands, c)
+		return nil
+	}
+
+	for i, textCommand := range m.textCommands {
+		if textCommand.ID == c.ID {
+			m.textCommands[i] = c
+			break
+		}
+	}
+
+	return nil
+}
+
+// !pb2addcmd TRIGGER[|ALIAS...] [OPTIONS] RESPONSE
+func (m *commandsModule) addCommand(args []string) string {
+	const usage = "usage: !pb2addcmd TRIGGER[|ALIAS] [--level LEVEL] [--cd SECONDS] [--usercd SECONDS] [--cost POINTS] [--say/--whisper/--reply] RESPONSE"
+
+	if len(args) < 2 {
+		return usage
+	}
+
+	triggers, err := commands.ParseTriggers(args[0])
+	if err != nil {
+		return err.Error()
+	}
+
+	c := commands.NewTextCommand(m.botChannel.ChannelID())
+	c.Triggers = triggers
+	c.Environment = m.environment
+
+	args, err = parseCommandOptions(c, args[1:])
+	if err != nil {
+		return err.Error()
+	}
+
+	c.Response = strings.Join(args, " ")
+
+	if err = m.saveCommand(c); err != nil {
+		return err.Error()
+	}
+
+	return fmt.Sprintf("added command !%s (ID %d)", strings.Join(c.Triggers, ", !"), c.ID)
+}
+
+// !pb2editcmd TRIGGER [OPTIONS] [NEW RESPONSE]
+func (m *commandsModule) editCommand(args []string) string {
+	const usage = "usage: !pb2editcmd TRIGGER [--triggers TRIGGER|ALIAS] [--level LEVEL] [--cd SECONDS] [--usercd SECONDS] [--cost POINTS] [--say/--whisper/--reply] [--enable/--disable] [NEW RESPONSE]"
+
+	if len(args) < 2 {
+		return usage
+	}
+
+	existing := m.findCommand(args[0])
+	if existing == nil {
+		return "no command with the trigger " + args[0] + " exists"
+	}
+
+	// Apply the changes to a copy so a failed edit doesn't leave a half-edited command behind
+	c := existing.Copy()
+
+	args, err := parseCommandOptions(c, args[1:])
+	if err != nil {
+		return err.Error()
+	}
+
+	if len(args) > 0 {
+		c.Response = strings.Join(args, " ")
+	}
+
+	if err = m.saveCommand(c); err != nil {
+		return err.Error()
+	}
+
+	return fmt.Sprintf("updated command !%s", strings.Join(c.Triggers, ", !"))
+}
+
+// !pb2delcmd TRIGGER
+func (m *commandsModule) deleteCommand(args []string) string {
+	if len(args) < 1 {
+		return "usage: !pb2delcmd TRIGGER"
+	}
+
+	c := m.findCommand(args[0])
+	if c == nil {
+		return "no command with the trigger " + args[0] + " exists"
+	}
+
+	if err := commands.DeleteTextCommand(m.server.sql, c.ChannelID, c.ID); err != nil {
+		return err.Error()
+	}
+
+	m.mutex.Lock()
+	for i, textCommand := range m.textCommands {
+		if textCommand.ID == c.ID {
+			m.textCommands = append(m.textCommands[:i], m.textCommands[i+1:]...)
+			break
+		}
+	}
+	m.mutex.Unlock()
+
+	return fmt.Sprintf("deleted command !%s", strings.Join(c.Triggers, ", !"))
+}
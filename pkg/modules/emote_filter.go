@@ -22,6 +22,9 @@ type emoteFilter struct {
 
 	emoteLimits    map[string]limitConsequence
 	combinedLimits int
+
+	filterWarning
+	filterExemptions
 }
 
 func newEmoteFilter() pkg.Module {
@@ -29,6 +32,8 @@ func newEmoteFilter() pkg.Module {
 		server: &_server,
 
 		emoteLimits: make(map[string]limitConsequence),
+
+		filterWarning: newFilterWarning(),
 	}
 }
 
@@ -36,11 +41,21 @@ var emoteLimitSpec = moduleSpec{
 	id:    "emote_limit",
 	name:  "Emote limit",
 	maker: newEmoteFilter,
+
+	phase: pkg.ModulePhaseFilter,
+
+	dependencies: []string{"bttv_emote_parser"},
 }
 
 func (m *emoteFilter) Initialize(botChannel pkg.BotChannel, settings []byte) error {
 	m.botChannel = botChannel
 
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
 	m.emoteLimits["NaM"] = limitConsequence{
 		limit:         2,
 		baseDuration:  300,
@@ -91,6 +106,10 @@ func (m *emoteFilter) OnWhisper(bot pkg.Sender, user pkg.User, message pkg.Messa
 	return nil
 }
 
+func (m *emoteFilter) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
 func (m *emoteFilter) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
 	// BTTV Emotes
 	reader := message.GetBTTVReader()
@@ -112,9 +131,17 @@ func (m *emoteFilter) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.Us
 	}
 
 	if timeoutDuration > 0 {
-		action.Set(pkg.Timeout{timeoutDuration, "Don't overuse " + strings.Join(overusedEmotes, ", ")})
+		action.Set(m.warn(bot, m.botChannel, channel, user, pkg.Timeout{
+			Duration: timeoutDuration,
+			Reason:   "Don't overuse " + strings.Join(overusedEmotes, ", "),
+		}))
+		return pkg.ErrStopPropagation
 	} else if combinedLimits > m.combinedLimits {
-		action.Set(pkg.Timeout{combinedLimits * 120, "Don't overuse big emotes"})
+		action.Set(m.warn(bot, m.botChannel, channel, user, pkg.Timeout{
+			Duration: combinedLimits * 120,
+			Reason:   "Don't overuse big emotes",
+		}))
+		return pkg.ErrStopPropagation
 	}
 
 	return nil
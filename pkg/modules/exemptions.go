@@ -0,0 +1,15 @@
+package modules
+
+import (
+	"github.com/pajlada/pajbot2/pkg"
+)
+
+// filterExemptions is embedded in the settings of filters, so channels can exempt different users from different filters
+type filterExemptions struct {
+	// Replaces the exemption policy of the channel for this filter
+	Exemptions *pkg.ExemptionPolicy `json:",omitempty"`
+}
+
+func (e *filterExemptions) ExemptionPolicyOverride() *pkg.ExemptionPolicy {
+	return e.Exemptions
+}
@@ -0,0 +1,179 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/commands"
+	"github.com/pajlada/pajbot2/pkg/pubsub"
+)
+
+const (
+	followAnnouncementsDefaultMessage = "Thank you for following, $(user)!"
+
+	followAnnouncementsDefaultLimit  = 3
+	followAnnouncementsDefaultWindow = 60
+)
+
+type followAnnouncements struct {
+	botChannel pkg.BotChannel
+
+	server *server
+
+	mutex sync.Mutex
+
+	// When we last announced a follower, oldest first. Used to limit how many followers we announce during a follow-bot raid
+	recentAnnouncements []time.Time
+
+	// Number of followers we didn't announce since the last announcement, because of the rate limit
+	suppressed int
+
+	subscription pubsub.Subscription
+
+	// Response template, i.e. "Thank you for following, $(user)!"
+	Message string `json:",omitempty"`
+
+	// Announce at most MaxAnnouncements followers every WindowSeconds
+	MaxAnnouncements int `json:",omitempty"`
+	WindowSeconds    int `json:",omitempty"`
+}
+
+var followAnnouncementsSpec = moduleSpec{
+	id:    "follow_announcements",
+	name:  "Follow announcements",
+	maker: newFollowAnnouncements,
+
+	phase: pkg.ModulePhaseCommand,
+}
+
+func newFollowAnnouncements() pkg.Module {
+	return &followAnnouncements{
+		server: &_server,
+
+		Message:          followAnnouncementsDefaultMessage,
+		MaxAnnouncements: followAnnouncementsDefaultLimit,
+		WindowSeconds:    followAnnouncementsDefaultWindow,
+	}
+}
+
+func (m *followAnnouncements) Initialize(botChannel pkg.BotChannel, settings []byte) error {
+	m.botChannel = botChannel
+
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
+	m.server.pubSub.Subscribe(m, "Follow")
+
+	return nil
+}
+
+func (m *followAnnouncements) Disable() error {
+	m.subscription.Cancel()
+
+	return nil
+}
+
+func (m *followAnnouncements) Spec() pkg.ModuleSpec {
+	return &followAnnouncementsSpec
+}
+
+func (m *followAnnouncements) BotChannel() pkg.BotChannel {
+	return m.botChannel
+}
+
+func (m *followAnnouncements) AuthenticatedUser() pkg.User {
+	return nil
+}
+
+func (m *followAnnouncements) IsApplication() bool {
+	return true
+}
+
+func (m *followAnnouncements) Connection() pkg.PubSubConnection {
+	return m
+}
+
+// allowAnnouncement returns true if announcing another follower now doesn't go over the rate limit
+// We assume that mutex is locked already
+func (m *followAnnouncements) allowAnnouncement(now time.Time) bool {
+	window := time.Duration(m.WindowSeconds) * time.Second
+
+	for len(m.recentAnnouncements) > 0 && now.Sub(m.recentAnnouncements[0]) >= window {
+		m.recentAnnouncements = m.recentAnnouncements[1:]
+	}
+
+	if len(m.recentAnnouncements) >= m.MaxAnnouncements {
+		return false
+	}
+
+	m.recentAnnouncements = append(m.recentAnnouncements, now)
+
+	return true
+}
+
+func (m *followAnnouncements) MessageReceived(source pkg.PubSubSource, topic string, data []byte) error {
+	if err := m.subscription.Check("follow announcements module"); err != nil {
+		return err
+	}
+
+	var msg pkg.PubSubFollowEvent
+	if err := json.Unmarshal(data, &msg); err != nil {
+		fmt.Println("Error unmarshalling:", err)
+		return nil
+	}
+
+	if msg.Channel.ID != m.botChannel.ChannelID() {
+		return nil
+	}
+
+	m.mutex.Lock()
+	if !m.allowAnnouncement(time.Now()) {
+		m.suppressed++
+		fmt.Printf("Not announcing follower %s in %s, %d followers suppressed by the rate limit\n", msg.User.Name, m.botChannel.ChannelName(), m.suppressed)
+		m.mutex.Unlock()
+		return nil
+	}
+	m.suppressed = 0
+	m.mutex.Unlock()
+
+	bot := m.botChannel.Bot()
+	channel := bot.MakeChannel(m.botChannel.ChannelName())
+
+	response, hasUserdata, err := commands.RenderTemplate(m.Message, &commands.TemplateContext{
+		Bot:        bot,
+		BotChannel: m.botChannel,
+		Channel:    channel,
+		User:       bot.MakeUser(msg.User.Name),
+	})
+	if err != nil {
+		fmt.Println("Error rendering follow announcement:", err)
+		return nil
+	}
+
+	// Usernames are picked by the users themselves
+	if hasUserdata && checkBanphrases(channel, response) {
+		return nil
+	}
+
+	bot.Say(channel, response)
+
+	return nil
+}
+
+func (m *followAnnouncements) OnWhisper(bot pkg.Sender, source pkg.User, message pkg.Message) error {
+	return nil
+}
+
+func (m *followAnnouncements) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
+func (m *followAnnouncements) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
+	return nil
+}
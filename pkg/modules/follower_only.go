@@ -0,0 +1,253 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/apirequest"
+	"github.com/pajlada/pajbot2/pkg/pubsub"
+)
+
+const (
+	// How long we remember that a user follows, or doesn't follow, the channel before asking the API again
+	followerCacheDuration    = time.Hour
+	nonFollowerCacheDuration = time.Minute
+
+	followerOnlyDefaultTimeout = 10
+)
+
+type followerCacheEntry struct {
+	// nil if the user doesn't follow the channel
+	followedAt *time.Time
+
+	expiresAt time.Time
+}
+
+type followerOnly struct {
+	botChannel pkg.BotChannel
+
+	server *server
+
+	mutex sync.Mutex
+
+	// by user ID
+	followers map[string]followerCacheEntry
+
+	// When expired entries were last removed from the cache
+	lastPrune time.Time
+
+	// Users whose follow status we're still waiting for the API to tell us, by user ID
+	lookups map[string]bool
+
+	subscription pubsub.Subscription
+
+	// Users need to have followed the channel for this long before they can chat
+	MinimumFollowMinutes int `json:",omitempty"`
+
+	TimeoutDuration int `json:",omitempty"`
+
+	// Let subscribers chat even if they don't follow the channel
+	AllowSubscribers bool `json:",omitempty"`
+
+	filterWarning
+	filterExemptions
+}
+
+var followerOnlySpec = moduleSpec{
+	id:    "follower_only",
+	name:  "Follower-only mode",
+	maker: newFollowerOnly,
+
+	phase: pkg.ModulePhaseFilter,
+}
+
+func newFollowerOnly() pkg.Module {
+	return &followerOnly{
+		server: &_server,
+
+		followers: make(map[string]followerCacheEntry),
+		lookups:   make(map[string]bool),
+
+		TimeoutDuration: followerOnlyDefaultTimeout,
+
+		filterWarning: newFilterWarning(),
+	}
+}
+
+func (m *followerOnly) Initialize(botChannel pkg.BotChannel, settings []byte) error {
+	m.botChannel = botChannel
+
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
+	// New follows are cached right away, so users that just followed don't have to wait for the cache to expire
+	m.server.pubSub.Subscribe(m, "Follow")
+
+	return nil
+}
+
+func (m *followerOnly) Disable() error {
+	m.subscription.Cancel()
+
+	return nil
+}
+
+func (m *followerOnly) Spec() pkg.ModuleSpec {
+	return &followerOnlySpec
+}
+
+func (m *followerOnly) BotChannel() pkg.BotChannel {
+	return m.botChannel
+}
+
+func (m *followerOnly) AuthenticatedUser() pkg.User {
+	return nil
+}
+
+func (m *followerOnly) IsApplication() bool {
+	return true
+}
+
+func (m *followerOnly) Connection() pkg.PubSubConnection {
+	return m
+}
+
+func (m *followerOnly) MessageReceived(source pkg.PubSubSource, topic string, data []byte) error {
+	if err := m.subscription.Check("follower only module"); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var msg pkg.PubSubFollowEvent
+	if err := json.Unmarshal(data, &msg); err != nil {
+		fmt.Println("Error unmarshalling:", err)
+		return nil
+	}
+
+	if msg.Channel.ID != m.botChannel.ChannelID() {
+		return nil
+	}
+
+	followedAt := msg.FollowedAt
+	m.cache(msg.User.ID, followerCacheEntry{
+		followedAt: &followedAt,
+		expiresAt:  time.Now().Add(followerCacheDuration),
+	})
+
+	return nil
+}
+
+// cache remembers the follow status of the user, and removes expired entries from the cache every now and then so it doesn't grow forever
+// We assume that mutex is locked already
+func (m *followerOnly) cache(userID string, entry followerCacheEntry) {
+	now := time.Now()
+
+	if now.Sub(m.lastPrune) >= nonFollowerCacheDuration {
+		for cachedUserID, cachedEntry := range m.followers {
+			if !cachedEntry.expiresAt.After(now) {
+				delete(m.followers, cachedUserID)
+			}
+		}
+
+		m.lastPrune = now
+	}
+
+	m.followers[userID] = entry
+}
+
+// lookup asks the API if the user follows the channel and caches the result. This runs in its own goroutine
+func (m *followerOnly) lookup(channel pkg.Channel, user pkg.User) {
+	defer func() {
+		m.mutex.Lock()
+		delete(m.lookups, user.GetID())
+		m.mutex.Unlock()
+	}()
+
+	follow, err := apirequest.TwitchWrapper.GetFollow(user.GetID(), channel.GetID())
+	if err != nil {
+		// Don't punish users, or disable the module, because the API is having a bad day
+		fmt.Println("Error checking follow status:", err)
+		return
+	}
+
+	now := time.Now()
+	entry := followerCacheEntry{
+		expiresAt: now.Add(nonFollowerCacheDuration),
+	}
+
+	if follow != nil {
+		entry.followedAt = &follow.FollowedAt
+		entry.expiresAt = now.Add(followerCacheDuration)
+	}
+
+	m.mutex.Lock()
+	m.cache(user.GetID(), entry)
+	m.mutex.Unlock()
+}
+
+// followedAt returns when the user followed the channel, or nil if they don't follow it.
+// known is false if the follow status isn't cached. The API is then asked in the background instead of holding up the message,
+// so the users next message is checked against the cache
+func (m *followerOnly) followedAt(channel pkg.Channel, user pkg.User) (followedAt *time.Time, known bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, ok := m.followers[user.GetID()]
+	if ok && entry.expiresAt.After(time.Now()) {
+		return entry.followedAt, true
+	}
+
+	// Users that spam while we're waiting for the API shouldn't start a lookup per message
+	if !m.lookups[user.GetID()] {
+		m.lookups[user.GetID()] = true
+		go m.lookup(channel, user)
+	}
+
+	return nil, false
+}
+
+func (m *followerOnly) OnWhisper(bot pkg.Sender, source pkg.User, message pkg.Message) error {
+	return nil
+}
+
+func (m *followerOnly) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
+func (m *followerOnly) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
+	if m.AllowSubscribers && user.IsSubscriber() {
+		return nil
+	}
+
+	followedAt, known := m.followedAt(channel, user)
+	if !known {
+		return nil
+	}
+
+	if followedAt == nil {
+		action.Set(m.warn(bot, m.botChannel, channel, user, pkg.Timeout{
+			Duration: m.TimeoutDuration,
+			Reason:   "You need to follow the channel to chat",
+		}))
+		return pkg.ErrStopPropagation
+	}
+
+	minimumFollowAge := time.Duration(m.MinimumFollowMinutes) * time.Minute
+	if time.Since(*followedAt) < minimumFollowAge {
+		action.Set(m.warn(bot, m.botChannel, channel, user, pkg.Timeout{
+			Duration: m.TimeoutDuration,
+			Reason:   fmt.Sprintf("You need to follow the channel for %d minutes to chat", m.MinimumFollowMinutes),
+		}))
+		return pkg.ErrStopPropagation
+	}
+
+	return nil
+}
@@ -1,26 +1,76 @@
 package modules
 
 import (
+	"fmt"
 	"math/rand"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/apirequest"
 )
 
+const (
+	giveawayRequirementNone       = ""
+	giveawayRequirementFollower   = "follower"
+	giveawayRequirementSubscriber = "subscriber"
+
+	giveawayMaxWinners = 50
+)
+
+type giveawayEntrant struct {
+	databaseID int64
+
+	userID   string
+	userName string
+
+	winner bool
+}
+
+type giveawayRound struct {
+	databaseID int64
+
+	// Keyword or emote name that users need to type to enter the giveaway
+	keyword string
+
+	requirement string
+
+	// Number of points it costs to enter the giveaway
+	cost uint64
+
+	// Set to false when the giveaway stops accepting new entrants
+	open bool
+
+	entrants []*giveawayEntrant
+
+	// by user ID, includes entrants that are still being processed
+	entered map[string]bool
+
+	// Number of entries that are still being processed
+	pending int
+
+	// Number of entrants that have been accepted but are still being saved to the database
+	saving int
+
+	// winners of the latest draw, used by redraw
+	lastDraw []*giveawayEntrant
+}
+
 type giveaway struct {
 	botChannel pkg.BotChannel
 
 	server *server
 
-	state string
+	mutex sync.Mutex
 
-	entrants []string
+	// nil if no giveaway has been started
+	current *giveawayRound
 }
 
 func newGiveaway() pkg.Module {
 	return &giveaway{
 		server: &_server,
-		state:  "inactive",
 	}
 }
 
@@ -28,6 +78,8 @@ var giveawaySpec = moduleSpec{
 	id:    "giveaway",
 	name:  "Giveaway",
 	maker: newGiveaway,
+
+	phase: pkg.ModulePhaseCommand,
 }
 
 func (m *giveaway) Initialize(botChannel pkg.BotChannel, settings []byte) error {
@@ -37,6 +89,14 @@ func (m *giveaway) Initialize(botChannel pkg.BotChannel, settings []byte) error
 }
 
 func (m *giveaway) Disable() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.current != nil && m.current.open {
+		m.current.open = false
+		m.markStopped(m.current)
+	}
+
 	return nil
 }
 
@@ -48,91 +108,364 @@ func (m *giveaway) BotChannel() pkg.BotChannel {
 	return m.botChannel
 }
 
-const forsen25ID = "1673018"
-const pajlada25ID = "908917"
-const pajaWID = "80481"
+func (m *giveaway) OnWhisper(bot pkg.Sender, user pkg.User, message pkg.Message) error {
+	return nil
+}
 
-func (m giveaway) OnWhisper(bot pkg.Sender, user pkg.User, message pkg.Message) error {
+func (m *giveaway) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
 	return nil
 }
 
 func (m *giveaway) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
-	// if channel.GetChannel() != "forsen" {
-	// 	return nil
-	// }
-
-	const giveawayEmote = forsen25ID
-
-	text := message.GetText()
-
-	// Commands
-	if user.IsModerator() || user.GetName() == "forsen" || user.GetName() == "pajlada" {
-		if strings.HasPrefix(text, "!25start") {
-			if m.state == "inactive" {
-				m.state = "started"
-				m.entrants = []string{}
-				bot.Say(channel, "Started giveaway")
-
-				return nil
-			} else {
-				bot.Say(channel, "Giveaway already started")
-				return nil
-			}
+	parts := strings.Split(message.GetText(), " ")
+
+	if strings.ToLower(parts[0]) == "!giveaway" {
+		if !user.IsModerator() && !user.IsBroadcaster(channel) && !user.HasPermission(channel, pkg.PermissionRaffle) {
+			return nil
 		}
 
-		if strings.HasPrefix(text, "!25stop") {
-			if m.state == "started" {
-				m.state = "inactive"
-				bot.Say(channel, "Stopped accepting people into the giveaway")
+		response := m.handleCommand(bot, channel, user, parts[1:])
+		if response != "" {
+			bot.Mention(channel, user, response)
+		}
 
-				return nil
-			}
+		return nil
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	g := m.current
+	if g == nil || !g.open {
+		return nil
+	}
+
+	if !giveawayEntryMatches(g.keyword, parts) {
+		return nil
+	}
+
+	if g.entered[user.GetID()] {
+		// User has already joined
+		return nil
+	}
+
+	if g.requirement == giveawayRequirementSubscriber && !user.IsSubscriber() {
+		return nil
+	}
+
+	// Reserve the users spot while we check the rest of the requirements, since the follower check requires an API request
+	g.entered[user.GetID()] = true
+	g.pending++
+
+	go m.enter(bot, channel, user, g)
+
+	return nil
+}
+
+// giveawayEntryMatches returns true if the message starts with the giveaway keyword, or contains it as a word.
+// Emotes always show up as separate words in the message, so this covers emote entries too
+func giveawayEntryMatches(keyword string, parts []string) bool {
+	if strings.EqualFold(parts[0], keyword) {
+		return true
+	}
+
+	for _, part := range parts[1:] {
+		if part == keyword {
+			return true
 		}
+	}
 
-		if strings.HasPrefix(text, "!25draw") {
-			if len(m.entrants) == 0 {
-				bot.Say(channel, "No one has joined the giveaway")
-				return nil
-			}
+	return false
+}
 
-			winnerIndex := rand.Intn(len(m.entrants))
-			winnerUsername := m.entrants[winnerIndex]
-			bot.Say(channel, winnerUsername+" just won the sub emote giveaway PogChamp")
+// enter checks the remaining requirements and adds the user to the giveaway. This runs in its own goroutine
+func (m *giveaway) enter(bot pkg.Sender, channel pkg.Channel, user pkg.User, g *giveawayRound) {
+	reject := func(reason string) {
+		m.mutex.Lock()
+		delete(g.entered, user.GetID())
+		g.pending--
+		m.mutex.Unlock()
 
-			m.entrants = append(m.entrants[:winnerIndex], m.entrants[winnerIndex+1:]...)
+		if reason != "" {
+			bot.Whisper(user, reason)
+		}
+	}
 
-			return nil
+	isOpen := func() bool {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		return g.open
+	}
+
+	if g.requirement == giveawayRequirementFollower && !user.IsBroadcaster(channel) {
+		follow, err := apirequest.TwitchWrapper.GetFollow(user.GetID(), channel.GetID())
+		if err != nil {
+			fmt.Println("Error checking follow status for giveaway:", err)
+			reject("")
+			return
+		}
+
+		if follow == nil {
+			reject("you need to follow " + channel.GetChannel() + " to enter the giveaway")
+			return
+		}
+	}
+
+	// The giveaway might have been stopped while we were checking the follow status
+	if !isOpen() {
+		reject("the giveaway closed before you could be entered")
+		return
+	}
+
+	if g.cost > 0 {
+		if ok, _ := bot.RemovePoints(channel, user.GetID(), g.cost); !ok {
+			reject(fmt.Sprintf("you need %d points to enter the giveaway", g.cost))
+			return
 		}
 	}
 
-	if m.state == "started" {
-		enterGiveaway := false
+	entrant := &giveawayEntrant{
+		userID:   user.GetID(),
+		userName: user.GetName(),
+	}
+
+	// Only the state of the giveaway is touched while the mutex is locked, since every chat message in the channel waits for it
+	m.mutex.Lock()
+	open := g.open
+	g.pending--
+	if open {
+		g.entrants = append(g.entrants, entrant)
+		g.saving++
+	} else {
+		delete(g.entered, user.GetID())
+	}
+	m.mutex.Unlock()
+
+	if !open {
+		if g.cost > 0 {
+			bot.AddPoints(channel, user.GetID(), g.cost)
+		}
 
-		reader := message.GetTwitchReader()
-		for reader.Next() {
-			emote := reader.Get()
-			if emote.GetID() == giveawayEmote {
-				// bot.Say(channel, fmt.Sprintf("%#v", emote))
-				// User wants to enter the giveaway
-				enterGiveaway = true
-				break
+		bot.Whisper(user, "the giveaway closed before you could be entered")
+		return
+	}
+
+	var databaseID int64
+
+	const queryF = `INSERT INTO GiveawayEntrant (giveaway_id, user_id, user_name) VALUES (?, ?, ?)`
+	res, err := m.server.sql.Exec(queryF, g.databaseID, entrant.userID, entrant.userName)
+	if err != nil {
+		fmt.Println("Error inserting giveaway entrant:", err)
+	} else {
+		databaseID, _ = res.LastInsertId()
+	}
+
+	m.mutex.Lock()
+	entrant.databaseID = databaseID
+	g.saving--
+	m.mutex.Unlock()
+
+	bot.Mention(channel, user, "you have been entered into the giveaway")
+}
+
+func (m *giveaway) handleCommand(bot pkg.Sender, channel pkg.Channel, user pkg.User, args []string) string {
+	if len(args) == 0 {
+		return "usage: !giveaway start/stop/draw/redraw"
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "start":
+		return m.start(channel, user, args[1:])
+
+	case "stop":
+		return m.stop()
+
+	case "draw":
+		numWinners := 1
+		if len(args) >= 2 {
+			var err error
+			numWinners, err = strconv.Atoi(args[1])
+			if err != nil || numWinners < 1 || numWinners > giveawayMaxWinners {
+				return fmt.Sprintf("number of winners must be between 1 and %d", giveawayMaxWinners)
 			}
 		}
 
-		if enterGiveaway {
-			for _, entrant := range m.entrants {
-				if entrant == user.GetName() {
-					// User has already joined
-					return nil
-				}
+		return m.draw(bot, channel, numWinners, false)
+
+	case "redraw":
+		return m.draw(bot, channel, 0, true)
+	}
+
+	return "usage: !giveaway start/stop/draw/redraw"
+}
+
+// start starts a new giveaway
+// Usage: !giveaway start KEYWORD [follower/sub] [cost=POINTS]
+func (m *giveaway) start(channel pkg.Channel, user pkg.User, args []string) string {
+	const usage = "usage: !giveaway start KEYWORD [follower/sub] [cost=POINTS]"
+
+	if len(args) == 0 || args[0] == "" {
+		return usage
+	}
+
+	g := &giveawayRound{
+		keyword: args[0],
+		open:    true,
+		entered: make(map[string]bool),
+	}
+
+	for _, arg := range args[1:] {
+		arg = strings.ToLower(arg)
+		switch {
+		case arg == "":
+			continue
 
+		case arg == "follower" || arg == "followers" || arg == "follow":
+			g.requirement = giveawayRequirementFollower
+
+		case arg == "sub" || arg == "subs" || arg == "subscriber" || arg == "subscribers":
+			g.requirement = giveawayRequirementSubscriber
+
+		case strings.HasPrefix(arg, "cost="):
+			cost, err := strconv.ParseUint(strings.TrimPrefix(arg, "cost="), 10, 32)
+			if err != nil {
+				return usage
 			}
-			m.entrants = append(m.entrants, user.GetName())
+			g.cost = cost
 
-			bot.Say(channel, "@"+user.GetName()+", you have been entered into the sub emote giveaway")
-			return nil
+		default:
+			return usage
 		}
 	}
 
-	return nil
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.current != nil && m.current.open {
+		return "a giveaway is already running"
+	}
+
+	const queryF = `INSERT INTO Giveaway (channel_id, keyword, requirement, cost, started_by_id) VALUES (?, ?, ?, ?, ?)`
+	res, err := m.server.sql.Exec(queryF, channel.GetID(), g.keyword, g.requirement, g.cost, user.GetID())
+	if err != nil {
+		fmt.Println("Error inserting giveaway:", err)
+		return "error starting giveaway"
+	}
+
+	g.databaseID, err = res.LastInsertId()
+	if err != nil {
+		fmt.Println("Error getting giveaway ID:", err)
+		return "error starting giveaway"
+	}
+
+	m.current = g
+
+	response := "started a giveaway! Type " + g.keyword + " to enter"
+	switch g.requirement {
+	case giveawayRequirementFollower:
+		response += " (followers only)"
+	case giveawayRequirementSubscriber:
+		response += " (subscribers only)"
+	}
+	if g.cost > 0 {
+		response += fmt.Sprintf(", entering costs %d points", g.cost)
+	}
+
+	return response
+}
+
+func (m *giveaway) stop() string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.current == nil || !m.current.open {
+		return "no giveaway is running"
+	}
+
+	m.current.open = false
+	m.markStopped(m.current)
+
+	response := fmt.Sprintf("stopped accepting people into the giveaway. %d users entered", len(m.current.entrants))
+	if m.current.pending > 0 {
+		// These users will be told that the giveaway closed, and get their points back
+		response += fmt.Sprintf(", %d entries that were still being processed have been cancelled", m.current.pending)
+	}
+
+	return response
+}
+
+// We assume that mutex is locked already
+func (m *giveaway) markStopped(g *giveawayRound) {
+	const queryF = `UPDATE Giveaway SET stopped_at=NOW() WHERE id=?`
+	if _, err := m.server.sql.Exec(queryF, g.databaseID); err != nil {
+		fmt.Println("Error stopping giveaway:", err)
+	}
+}
+
+// draw picks numWinners new winners among the entrants that haven't won yet
+// If redraw is true, the winners of the previous draw are discarded and the same number of winners are drawn again
+func (m *giveaway) draw(bot pkg.Sender, channel pkg.Channel, numWinners int, redraw bool) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	g := m.current
+	if g == nil {
+		return "no giveaway has been started"
+	}
+
+	// Entrants that already paid for their entry must get a chance to win, and winners need to be saved before they can be marked as winners
+	if g.pending+g.saving > 0 {
+		return fmt.Sprintf("%d entries are still being processed, try again in a few seconds", g.pending+g.saving)
+	}
+
+	if redraw {
+		if len(g.lastDraw) == 0 {
+			return "no winners have been drawn yet"
+		}
+
+		numWinners = len(g.lastDraw)
+
+		const queryF = `UPDATE GiveawayEntrant SET winner=0, rerolled=1 WHERE id=?`
+		for _, entrant := range g.lastDraw {
+			// Re-drawn winners stay marked as winners in memory so they can't be drawn again
+			if _, err := m.server.sql.Exec(queryF, entrant.databaseID); err != nil {
+				fmt.Println("Error updating giveaway entrant:", err)
+			}
+		}
+	}
+
+	var candidates []*giveawayEntrant
+	for _, entrant := range g.entrants {
+		if !entrant.winner {
+			candidates = append(candidates, entrant)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "there's no one left to draw"
+	}
+
+	if numWinners > len(candidates) {
+		numWinners = len(candidates)
+	}
+
+	g.lastDraw = nil
+	var winnerNames []string
+
+	const queryF = `UPDATE GiveawayEntrant SET winner=1 WHERE id=?`
+	for _, i := range rand.Perm(len(candidates))[:numWinners] {
+		entrant := candidates[i]
+		entrant.winner = true
+		g.lastDraw = append(g.lastDraw, entrant)
+		winnerNames = append(winnerNames, "@"+entrant.userName)
+
+		if _, err := m.server.sql.Exec(queryF, entrant.databaseID); err != nil {
+			fmt.Println("Error updating giveaway entrant:", err)
+		}
+	}
+
+	bot.Say(channel, strings.Join(winnerNames, ", ")+" just won the giveaway PogChamp")
+
+	return ""
 }
@@ -0,0 +1,386 @@
+package modules
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/commands"
+)
+
+type globalModuleSpec struct {
+	maker pkg.GlobalModuleMaker
+
+	// i.e. "nuke". This is used in external calls enabling or disabling the module
+	// the ID is also what's used when storing settings in the database
+	id string
+
+	// i.e. "Nuke"
+	name string
+
+	enabledByDefault bool
+
+	scope pkg.GlobalModuleScope
+
+	priority int
+}
+
+func (s *globalModuleSpec) ID() string {
+	return s.id
+}
+
+func (s *globalModuleSpec) Name() string {
+	return s.name
+}
+
+func (s *globalModuleSpec) EnabledByDefault() bool {
+	return s.enabledByDefault
+}
+
+func (s *globalModuleSpec) Scope() pkg.GlobalModuleScope {
+	return s.scope
+}
+
+func (s *globalModuleSpec) Maker() pkg.GlobalModuleMaker {
+	return s.maker
+}
+
+func (s *globalModuleSpec) Priority() int {
+	return s.priority
+}
+
+var _ pkg.GlobalModuleSpec = &globalModuleSpec{}
+
+type globalModule struct {
+	spec *globalModuleSpec
+
+	enabled  bool
+	settings []byte
+
+	// Instances of the module, by scope key. See globalModuleScopeKey
+	instances map[string]pkg.GlobalModule
+}
+
+var (
+	_globalModulesMutex sync.Mutex
+
+	// Sorted by priority once LoadGlobalModules has been called
+	_globalModules []*globalModule
+)
+
+func RegisterGlobal(spec *globalModuleSpec) {
+	if spec == nil {
+		panic("Trying to register a nil global module spec")
+	}
+
+	if spec.ID() == "" {
+		panic("Missing ID in global module spec")
+	}
+
+	if spec.Name() == "" {
+		panic("Missing Name in global module spec")
+	}
+
+	if spec.Maker() == nil {
+		panic("Missing Maker in global module spec")
+	}
+
+	_globalModulesMutex.Lock()
+	_globalModules = append(_globalModules, &globalModule{
+		spec:      spec,
+		enabled:   spec.enabledByDefault,
+		instances: make(map[string]pkg.GlobalModule),
+	})
+	_globalModulesMutex.Unlock()
+}
+
+// LoadGlobalModules loads the enabled state and settings of the global modules from the database
+// Enabled application-scope modules are instantiated right away, bot-scope modules the first time they receive a message or whisper
+func LoadGlobalModules() error {
+	const queryF = `SELECT module_id, enabled, settings FROM GlobalModule`
+
+	rows, err := _server.sql.Query(queryF)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	_globalModulesMutex.Lock()
+	defer _globalModulesMutex.Unlock()
+
+	for rows.Next() {
+		var moduleID string
+		var enabled sql.NullBool
+		var settings sql.NullString
+
+		if err = rows.Scan(&moduleID, &enabled, &settings); err != nil {
+			return err
+		}
+
+		m := findGlobalModule(moduleID)
+		if m == nil {
+			continue
+		}
+
+		if enabled.Valid {
+			m.enabled = enabled.Bool
+		}
+
+		m.settings = []byte(settings.String)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	sort.SliceStable(_globalModules, func(i, j int) bool {
+		return _globalModules[i].spec.Priority() < _globalModules[j].spec.Priority()
+	})
+
+	for _, m := range _globalModules {
+		m.createApplicationInstance()
+	}
+
+	return nil
+}
+
+// We assume that _globalModulesMutex is locked already
+func findGlobalModule(moduleID string) *globalModule {
+	moduleID = strings.ToLower(moduleID)
+
+	for _, m := range _globalModules {
+		if m.spec.ID() == moduleID {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// globalModuleScopeKey returns the key of the instance that should handle messages from the given bot
+func globalModuleScopeKey(spec pkg.GlobalModuleSpec, bot pkg.Sender) string {
+	if spec.Scope() == pkg.GlobalModuleScopeBot {
+		return bot.TwitchAccount().ID()
+	}
+
+	return ""
+}
+
+// We assume that _globalModulesMutex is locked already
+// Returns nil if the module failed to initialize
+func (m *globalModule) instance(bot pkg.Sender) pkg.GlobalModule {
+	key := globalModuleScopeKey(m.spec, bot)
+
+	if instance, ok := m.instances[key]; ok {
+		return instance
+	}
+
+	instance := m.spec.Maker()()
+	if err := instance.Initialize(m.settings); err != nil {
+		fmt.Printf("Error loading global module '%s': %s\n", m.spec.ID(), err)
+		// Don't try again until the module is re-enabled
+		instance = nil
+	}
+
+	m.instances[key] = instance
+
+	return instance
+}
+
+// We assume that _globalModulesMutex is locked already
+// Application-scope modules can subscribe to pubsub topics when they're initialized, i.e. shared bans listens for bans in every channel,
+// so they can't wait for the first message or whisper to be created
+func (m *globalModule) createApplicationInstance() {
+	if m.enabled && m.spec.Scope() == pkg.GlobalModuleScopeApplication {
+		m.instance(nil)
+	}
+}
+
+// callGlobalModule calls cb with the module, recovering from any panic so a broken module can't bring down the bot
+func callGlobalModule(module pkg.GlobalModule, cb func(module pkg.GlobalModule) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("Recovered from global module panic: %v\n%s\n", r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return cb(module)
+}
+
+// onGlobalModules calls cb for each enabled global module. A module that fails doesn't stop the others from being called
+func onGlobalModules(bot pkg.Sender, cb func(module pkg.GlobalModule) error) {
+	var instances []pkg.GlobalModule
+
+	_globalModulesMutex.Lock()
+	for _, m := range _globalModules {
+		if !m.enabled {
+			continue
+		}
+
+		if instance := m.instance(bot); instance != nil {
+			instances = append(instances, instance)
+		}
+	}
+	_globalModulesMutex.Unlock()
+
+	for _, instance := range instances {
+		if err := callGlobalModule(instance, cb); err != nil {
+			fmt.Printf("Error in global module '%s': %s\n", instance.Spec().ID(), err)
+		}
+	}
+}
+
+// OnGlobalMessage forwards a message from any channel to the enabled global modules
+func OnGlobalMessage(bot pkg.Sender, botChannel pkg.BotChannel, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) {
+	onGlobalModules(bot, func(module pkg.GlobalModule) error {
+		return module.OnMessage(bot, botChannel, channel, user, message, pkg.ActionFromSource(action, module.Spec().ID()))
+	})
+}
+
+// OnGlobalWhisper forwards a whisper to the enabled global modules
+func OnGlobalWhisper(bot pkg.Sender, user pkg.User, message pkg.Message) {
+	onGlobalModules(bot, func(module pkg.GlobalModule) error {
+		return module.OnWhisper(bot, user, message)
+	})
+}
+
+func setGlobalModuleEnabledState(moduleID string, enabled bool) error {
+	const queryF = `
+INSERT INTO
+	GlobalModule
+	(module_id, enabled)
+	VALUES (?, ?)
+ON DUPLICATE KEY UPDATE enabled=?`
+
+	_, err := _server.sql.Exec(queryF, moduleID, enabled, enabled)
+	return err
+}
+
+// EnableGlobalModule enables the global module with the given ID, and remembers that it's enabled
+func EnableGlobalModule(moduleID string) error {
+	_globalModulesMutex.Lock()
+	defer _globalModulesMutex.Unlock()
+
+	m := findGlobalModule(moduleID)
+	if m == nil {
+		return errors.New("invalid global module id")
+	}
+
+	if m.enabled {
+		return errors.New("module is already enabled")
+	}
+
+	if err := setGlobalModuleEnabledState(m.spec.ID(), true); err != nil {
+		return err
+	}
+
+	m.enabled = true
+	m.createApplicationInstance()
+
+	return nil
+}
+
+// DisableGlobalModule disables all instances of the global module with the given ID, and remembers that it's disabled
+func DisableGlobalModule(moduleID string) error {
+	_globalModulesMutex.Lock()
+	defer _globalModulesMutex.Unlock()
+
+	m := findGlobalModule(moduleID)
+	if m == nil {
+		return errors.New("invalid global module id")
+	}
+
+	if !m.enabled {
+		return errors.New("module is already disabled")
+	}
+
+	if err := setGlobalModuleEnabledState(m.spec.ID(), false); err != nil {
+		return err
+	}
+
+	m.enabled = false
+
+	for key, instance := range m.instances {
+		if instance != nil {
+			if err := instance.Disable(); err != nil {
+				fmt.Printf("Error disabling global module '%s': %s\n", m.spec.ID(), err)
+			}
+		}
+
+		delete(m.instances, key)
+	}
+
+	return nil
+}
+
+// GlobalModules returns the specs of all global modules, and whether they're enabled
+func GlobalModules() map[pkg.GlobalModuleSpec]bool {
+	_globalModulesMutex.Lock()
+	defer _globalModulesMutex.Unlock()
+
+	specs := make(map[pkg.GlobalModuleSpec]bool)
+	for _, m := range _globalModules {
+		specs[m.spec] = m.enabled
+	}
+
+	return specs
+}
+
+func newGlobalModuleCommand() *commands.Command {
+	return &commands.Command{
+		Name:        "pb2globalmodule",
+		Description: "list, enable or disable modules that are shared between all channels",
+		Arguments: []commands.Argument{
+			{Name: "list|enable|disable"},
+			{Name: "module_id", Optional: true},
+		},
+		Permission: pkg.PermissionAdmin,
+		Run: func(ctx *commands.Context) string {
+			// Global modules affect every channel, so channel admins are not allowed to manage them
+			if !ctx.User.HasGlobalPermission(pkg.PermissionAdmin) {
+				return "you do not have permission to use this command. Admin permission is required"
+			}
+
+			moduleID := ctx.Args.String("module_id")
+
+			switch strings.ToLower(ctx.Args.String("list|enable|disable")) {
+			case "list":
+				var modules []string
+				for spec, enabled := range GlobalModules() {
+					if enabled {
+						modules = append(modules, spec.ID()+" (enabled)")
+					} else {
+						modules = append(modules, spec.ID())
+					}
+				}
+
+				sort.Strings(modules)
+
+				return "global modules: " + strings.Join(modules, ", ")
+
+			case "enable":
+				if err := EnableGlobalModule(moduleID); err != nil {
+					return err.Error()
+				}
+
+				return fmt.Sprintf("Enabled global module %s", moduleID)
+
+			case "disable":
+				if err := DisableGlobalModule(moduleID); err != nil {
+					return err.Error()
+				}
+
+				return fmt.Sprintf("Disabled global module %s", moduleID)
+			}
+
+			return "usage: " + ctx.Prefix + ctx.Trigger + " list|enable|disable [module_id]"
+		},
+	}
+}
@@ -29,6 +29,8 @@ type latinFilter struct {
 
 	transparentList  *datastructures.TransparentList
 	unicodeWhitelist []UnicodeRange
+
+	filterExemptions
 }
 
 func newLatinFilter() pkg.Module {
@@ -43,6 +45,8 @@ var latinFilterSpec = moduleSpec{
 	id:    "latin_filter",
 	name:  "Latin filter",
 	maker: newLatinFilter,
+
+	phase: pkg.ModulePhaseFilter,
 }
 
 func (m *latinFilter) addToWhitelist(start, end rune) {
@@ -52,6 +56,12 @@ func (m *latinFilter) addToWhitelist(start, end rune) {
 func (m *latinFilter) Initialize(botChannel pkg.BotChannel, settings []byte) error {
 	m.botChannel = botChannel
 
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
 	m.transparentList.Add("(/ﾟДﾟ)/")
 	m.transparentList.Add("(╯°□°）╯︵ ┻━┻")
 	m.transparentList.Add("(╯°Д°）╯︵/(.□ . )")
@@ -116,68 +126,69 @@ func (m *latinFilter) OnWhisper(bot pkg.Sender, source pkg.User, message pkg.Mes
 	return nil
 }
 
+func (m *latinFilter) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
 func (m *latinFilter) OnMessage(bot pkg.Sender, source pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
-	if !user.IsModerator() || true {
-		text := message.GetText()
-
-		lol := struct {
-			FullMessage   string
-			Message       string
-			BadCharacters []rune
-			Username      string
-			Channel       string
-			Timestamp     time.Time
-		}{
-			FullMessage: text,
-			Username:    user.GetName(),
-			Channel:     source.GetChannel(),
-			Timestamp:   time.Now().UTC(),
-		}
-		messageRunes := []rune(text)
-		transparentStart := time.Now()
-		transparentSkipRange := m.transparentList.Find(messageRunes)
-		transparentEnd := time.Now()
-		if pkg.VerboseBenchmark {
-			fmt.Printf("[% 26s] %s", "TransparentList", transparentEnd.Sub(transparentStart))
+	text := message.GetText()
+
+	lol := struct {
+		FullMessage   string
+		Message       string
+		BadCharacters []rune
+		Username      string
+		Channel       string
+		Timestamp     time.Time
+	}{
+		FullMessage: text,
+		Username:    user.GetName(),
+		Channel:     source.GetChannel(),
+		Timestamp:   time.Now().UTC(),
+	}
+	messageRunes := []rune(text)
+	transparentStart := time.Now()
+	transparentSkipRange := m.transparentList.Find(messageRunes)
+	transparentEnd := time.Now()
+	if pkg.VerboseBenchmark {
+		fmt.Printf("[% 26s] %s", "TransparentList", transparentEnd.Sub(transparentStart))
+	}
+	messageLength := len(messageRunes)
+	for i := 0; i < messageLength; {
+		if skipLength := transparentSkipRange.ShouldSkip(i); skipLength > 0 {
+			i = i + skipLength
+			continue
 		}
-		messageLength := len(messageRunes)
-		for i := 0; i < messageLength; {
-			if skipLength := transparentSkipRange.ShouldSkip(i); skipLength > 0 {
-				i = i + skipLength
-				continue
-			}
 
-			r := messageRunes[i]
-			allowed := false
+		r := messageRunes[i]
+		allowed := false
 
-			for _, allowedRange := range m.unicodeWhitelist {
-				if r >= allowedRange.Start && r <= allowedRange.End {
-					allowed = true
-					break
-				}
+		for _, allowedRange := range m.unicodeWhitelist {
+			if r >= allowedRange.Start && r <= allowedRange.End {
+				allowed = true
+				break
 			}
+		}
 
-			if !allowed {
-				if lol.Message == "" {
-					lol.Message = text[maxpenis(0, i-2):len(text)]
-				}
-
-				alreadySet := false
-				for _, bc := range lol.BadCharacters {
-					if bc == r {
-						alreadySet = true
-						break
-					}
-				}
+		if !allowed {
+			if lol.Message == "" {
+				lol.Message = text[maxpenis(0, i-2):len(text)]
+			}
 
-				if !alreadySet {
-					lol.BadCharacters = append(lol.BadCharacters, r)
+			alreadySet := false
+			for _, bc := range lol.BadCharacters {
+				if bc == r {
+					alreadySet = true
+					break
 				}
+			}
 
+			if !alreadySet {
+				lol.BadCharacters = append(lol.BadCharacters, r)
 			}
-			i++
+
 		}
+		i++
 	}
-
 	return nil
 }
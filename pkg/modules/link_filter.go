@@ -1,27 +1,42 @@
 package modules
 
 import (
+	"fmt"
+
 	"github.com/pajlada/pajbot2/pkg"
 	"mvdan.cc/xurls"
 )
 
 type LinkFilter struct {
 	botChannel pkg.BotChannel
+
+	filterWarning
+	filterExemptions
 }
 
 func newLinkFilter() pkg.Module {
-	return &LinkFilter{}
+	return &LinkFilter{
+		filterWarning: newFilterWarning(),
+	}
 }
 
 var linkFilterSpec = moduleSpec{
 	id:    "link_filter",
 	name:  "Link filter",
 	maker: newLinkFilter,
+
+	phase: pkg.ModulePhaseFilter,
 }
 
 func (m *LinkFilter) Initialize(botChannel pkg.BotChannel, settings []byte) error {
 	m.botChannel = botChannel
 
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
 	return nil
 }
 
@@ -45,18 +60,22 @@ func (m LinkFilter) OnWhisper(bot pkg.Sender, source pkg.User, message pkg.Messa
 	return nil
 }
 
-func (m LinkFilter) OnMessage(bot pkg.Sender, channel pkg.Channel, source pkg.User, message pkg.Message, action pkg.Action) error {
-	if source.IsModerator() || source.IsBroadcaster(channel) {
-		return nil
-	}
+func (m LinkFilter) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
 
+func (m LinkFilter) OnMessage(bot pkg.Sender, channel pkg.Channel, source pkg.User, message pkg.Message, action pkg.Action) error {
 	if channel.GetChannel() != "forsen" {
 		return nil
 	}
 
 	links := xurls.Relaxed().FindAllString(message.GetText(), -1)
 	if len(links) > 0 {
-		action.Set(pkg.Timeout{180, "No links allowed"})
+		action.Set(m.warn(bot, m.botChannel, channel, source, pkg.Timeout{
+			Duration: 180,
+			Reason:   "No links allowed",
+		}))
+		return pkg.ErrStopPropagation
 	}
 
 	return nil
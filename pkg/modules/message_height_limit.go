@@ -38,6 +38,8 @@ func init() {
 
 		enabledByDefault: false,
 
+		phase: pkg.ModulePhaseFilter,
+
 		parameters: map[string]*moduleParameterSpec{
 			"HeightLimit": &moduleParameterSpec{
 				description:  "Max height of a message before it's timed out",
@@ -56,6 +58,9 @@ type MessageHeightLimit struct {
 
 	HeightLimit floatParameter `json:",omitempty"`
 
+	filterWarning
+	filterExemptions
+
 	userViolationCount map[string]int
 }
 
@@ -67,6 +72,8 @@ func NewMessageHeightLimit() pkg.Module {
 			defaultValue: messageHeightLimitSpec.parameters["HeightLimit"].defaultValue.(*float32),
 		},
 		userViolationCount: make(map[string]int),
+
+		filterWarning: newFilterWarning(),
 	}
 }
 
@@ -190,6 +197,10 @@ func (m *MessageHeightLimit) OnWhisper(bot pkg.Sender, user pkg.User, message pk
 	return nil
 }
 
+func (m *MessageHeightLimit) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
 func (m *MessageHeightLimit) getHeight(channel pkg.Channel, user pkg.User, message pkg.Message) float32 {
 	channelString := C.CString(channel.GetChannel())
 	input := C.CString(message.GetText())
@@ -242,48 +253,58 @@ func (m *MessageHeightLimit) getHeight(channel pkg.Channel, user pkg.User, messa
 	return float32(height)
 }
 
-func (m *MessageHeightLimit) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
-	if !messageHeightLimitLibraryInitialized {
-		return nil
+// handleCommands lets moderators change and test the height limit from chat. Returns true if the message was a command
+func (m *MessageHeightLimit) handleCommands(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message) bool {
+	if !user.IsModerator() && !user.IsBroadcaster(channel) && !user.HasPermission(channel, pkg.PermissionModeration) {
+		return false
 	}
 
-	if user.GetName() == "gazatu2" {
-		return nil
-	}
+	if strings.HasPrefix(message.GetText(), "!") {
+		parts := strings.Split(message.GetText(), " ")
+		if parts[0] == "!heightlimit" {
+			if len(parts) >= 2 {
+				if err := m.HeightLimit.Parse(parts[1]); err != nil {
+					bot.Mention(channel, user, err.Error())
+					return true
+				}
 
-	if user.GetName() == "supibot" {
-		return nil
+				bot.Mention(channel, user, "Height limit set to "+utils.Float32ToString(m.HeightLimit.Get()))
+				saveModule(m)
+			} else {
+				bot.Mention(channel, user, "Height limit is "+utils.Float32ToString(m.HeightLimit.Get()))
+			}
+
+			return true
+		}
+
+		if parts[0] == "!heighttest" {
+			height := m.getHeight(channel, user, message)
+			bot.Mention(channel, user, fmt.Sprintf("your message height is %.2f", height))
+			return true
+		}
 	}
 
-	if user.GetName() == "titlechange_bot" {
+	return false
+}
+
+// Moderators are usually exempt from the filter, but they still need to be able to use the commands
+func (m *MessageHeightLimit) OnExemptMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message) error {
+	if !messageHeightLimitLibraryInitialized {
 		return nil
 	}
 
-	if user.IsModerator() || user.IsBroadcaster(channel) || user.HasPermission(channel, pkg.PermissionModeration) {
-		if strings.HasPrefix(message.GetText(), "!") {
-			parts := strings.Split(message.GetText(), " ")
-			if parts[0] == "!heightlimit" {
-				if len(parts) >= 2 {
-					if err := m.HeightLimit.Parse(parts[1]); err != nil {
-						bot.Mention(channel, user, err.Error())
-						return nil
-					}
-
-					bot.Mention(channel, user, "Height limit set to "+utils.Float32ToString(m.HeightLimit.Get()))
-					saveModule(m)
-				} else {
-					bot.Mention(channel, user, "Height limit is "+utils.Float32ToString(m.HeightLimit.Get()))
-				}
+	m.handleCommands(bot, channel, user, message)
 
-				return nil
-			}
+	return nil
+}
 
-			if parts[0] == "!heighttest" {
-				height := m.getHeight(channel, user, message)
-				bot.Mention(channel, user, fmt.Sprintf("your message height is %.2f", height))
-				return nil
-			}
-		}
+func (m *MessageHeightLimit) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
+	if !messageHeightLimitLibraryInitialized {
+		return nil
+	}
+
+	if m.handleCommands(bot, channel, user, message) {
+		return nil
 	}
 
 	const minTimeoutLength = 10
@@ -329,10 +350,11 @@ func (m *MessageHeightLimit) OnMessage(bot pkg.Sender, channel pkg.Channel, user
 		}
 
 		reason = fmt.Sprintf("Your message is too tall: %.0f (%d)", height, userViolations)
-		action.Set(pkg.Timeout{
+		action.Set(m.warn(bot, m.botChannel, channel, user, pkg.Timeout{
 			Duration: timeoutDuration,
 			Reason:   reason,
-		})
+		}))
+		return pkg.ErrStopPropagation
 	}
 
 	return nil
@@ -1,6 +1,12 @@
 package modules
 
-import "github.com/pajlada/pajbot2/pkg"
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg"
+)
 
 var _ pkg.Module = &MessageLengthLimit{}
 
@@ -8,11 +14,41 @@ type MessageLengthLimit struct {
 	botChannel pkg.BotChannel
 
 	server *server
+
+	mutex sync.Mutex
+
+	// When users last sent a too long message, by user ID
+	offenses map[string]time.Time
+
+	// Messages longer than this are deleted, or time the user out if they've done it before
+	MaxLength int `json:",omitempty"`
+
+	// Messages longer than this always time the user out
+	TimeoutLength int `json:",omitempty"`
+
+	TimeoutDuration     int `json:",omitempty"`
+	LongTimeoutDuration int `json:",omitempty"`
+
+	// Users that send a too long message within this many seconds of their last one are timed out instead of having the message deleted
+	OffenseMemorySeconds int `json:",omitempty"`
+
+	filterWarning
+	filterExemptions
 }
 
 func newMessageLengthLimit() pkg.Module {
 	return &MessageLengthLimit{
 		server: &_server,
+
+		offenses: make(map[string]time.Time),
+
+		MaxLength:            140,
+		TimeoutLength:        420,
+		TimeoutDuration:      300,
+		LongTimeoutDuration:  600,
+		OffenseMemorySeconds: 3600,
+
+		filterWarning: newFilterWarning(),
 	}
 }
 
@@ -20,11 +56,19 @@ var messageLengthLimitSpec = moduleSpec{
 	id:    "message_length_limit",
 	name:  "Message length limit",
 	maker: newMessageLengthLimit,
+
+	phase: pkg.ModulePhaseFilter,
 }
 
 func (m *MessageLengthLimit) Initialize(botChannel pkg.BotChannel, settings []byte) error {
 	m.botChannel = botChannel
 
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
 	return nil
 }
 
@@ -40,34 +84,58 @@ func (m *MessageLengthLimit) BotChannel() pkg.BotChannel {
 	return m.botChannel
 }
 
-func (m MessageLengthLimit) OnWhisper(bot pkg.Sender, user pkg.User, message pkg.Message) error {
+func (m *MessageLengthLimit) OnWhisper(bot pkg.Sender, user pkg.User, message pkg.Message) error {
 	return nil
 }
 
-func (m MessageLengthLimit) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
-	if channel.GetChannel() != "forsen" {
-		return nil
-	}
-
+func (m *MessageLengthLimit) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
 	return nil
+}
 
-	if user.GetName() == "gazatu2" {
-		return nil
-	}
+// repeatOffense remembers that the user sent a too long message, and returns true if they did it before within OffenseMemorySeconds
+func (m *MessageLengthLimit) repeatOffense(user pkg.User) bool {
+	now := time.Now()
+	memory := time.Duration(m.OffenseMemorySeconds) * time.Second
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
-	if user.GetName() == "supibot" {
-		return nil
+	for userID, offense := range m.offenses {
+		if now.Sub(offense) >= memory {
+			delete(m.offenses, userID)
+		}
 	}
 
+	_, repeat := m.offenses[user.GetID()]
+	m.offenses[user.GetID()] = now
+
+	return repeat
+}
+
+func (m *MessageLengthLimit) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
 	messageLength := len(message.GetText())
-	if messageLength > 140 {
-		if messageLength > 420 {
-			action.Set(pkg.Timeout{600, "Your message is way too long"})
-			return nil
+	if messageLength > m.MaxLength {
+		if messageLength > m.TimeoutLength {
+			action.Set(m.warn(bot, m.botChannel, channel, user, pkg.Timeout{
+				Duration: m.LongTimeoutDuration,
+				Reason:   "Your message is way too long",
+			}))
+			return pkg.ErrStopPropagation
+		}
+
+		if m.repeatOffense(user) {
+			action.Set(m.warn(bot, m.botChannel, channel, user, pkg.Timeout{
+				Duration: m.TimeoutDuration,
+				Reason:   "Your message is too long, shorten it",
+			}))
+			return pkg.ErrStopPropagation
 		}
 
-		action.Set(pkg.Timeout{300, "Your message is too long, shorten it"})
-		return nil
+		action.Set(m.warn(bot, m.botChannel, channel, user, pkg.Delete{
+			MessageID: message.GetID(),
+			Reason:    "Your message is too long, shorten it",
+		}))
+		return pkg.ErrStopPropagation
 	}
 
 	return nil
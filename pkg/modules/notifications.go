@@ -0,0 +1,208 @@
+package modules
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/commands"
+)
+
+// Twitch sends the gifts of a mass gift right after the mass gift itself. If some of them never arrive, we stop waiting for them after this long
+const massGiftTimeout = time.Minute
+
+type pendingGifts struct {
+	remaining int
+	expiresAt time.Time
+}
+
+type notifications struct {
+	botChannel pkg.BotChannel
+
+	mutex sync.Mutex
+
+	// Gifts of announced mass gifts that we still expect to receive, by gifter user ID. They're not announced one by one
+	pendingGifts map[string]*pendingGifts
+
+	lastGiftAnnouncement time.Time
+
+	// Response templates for each kind of event. Leave a template empty to not announce that kind of event
+	// Besides the usual variables, the templates can use $(months), $(streak), $(plan) and $(message) for subs,
+	// $(recipient), $(months) and $(plan) for gifts, $(gifts), $(totalgifts) and $(plan) for mass gifts,
+	// $(viewers) for raids, and $(ritual) and $(message) for rituals
+	SubMessage         string `json:",omitempty"`
+	ResubMessage       string `json:",omitempty"`
+	SubGiftMessage     string `json:",omitempty"`
+	MassSubGiftMessage string `json:",omitempty"`
+	RaidMessage        string `json:",omitempty"`
+	RitualMessage      string `json:",omitempty"`
+
+	// Gifts and mass gifts are announced at most once every GiftCooldownSeconds, so a gift train doesn't flood the chat
+	GiftCooldownSeconds int `json:",omitempty"`
+}
+
+var notificationsSpec = moduleSpec{
+	id:    "notifications",
+	name:  "Notifications",
+	maker: newNotifications,
+
+	phase: pkg.ModulePhaseCommand,
+}
+
+func newNotifications() pkg.Module {
+	return &notifications{
+		pendingGifts: make(map[string]*pendingGifts),
+
+		SubMessage:          "Thank you for subscribing, $(user)! PogChamp",
+		ResubMessage:        "Thank you for resubscribing for $(months) months, $(user)! PogChamp",
+		SubGiftMessage:      "Thank you for gifting a sub to $(recipient), $(user)! PogChamp",
+		MassSubGiftMessage:  "Thank you for gifting $(gifts) subs, $(user)! PogChamp",
+		RaidMessage:         "Thank you for the raid with $(viewers) viewers, $(user)! PogChamp",
+		RitualMessage:       "Welcome to the chat, $(user)! HeyGuys",
+		GiftCooldownSeconds: 10,
+	}
+}
+
+func (m *notifications) Initialize(botChannel pkg.BotChannel, settings []byte) error {
+	m.botChannel = botChannel
+
+	if len(settings) > 0 {
+		if err := loadModule(settings, m); err != nil {
+			fmt.Println("Error loading module:", err)
+		}
+	}
+
+	return nil
+}
+
+func (m *notifications) Disable() error {
+	return nil
+}
+
+func (m *notifications) Spec() pkg.ModuleSpec {
+	return &notificationsSpec
+}
+
+func (m *notifications) BotChannel() pkg.BotChannel {
+	return m.botChannel
+}
+
+func (m *notifications) OnWhisper(bot pkg.Sender, source pkg.User, message pkg.Message) error {
+	return nil
+}
+
+// partOfMassGift returns true if the gift belongs to a mass gift we've already seen
+// We assume that mutex is locked already
+func (m *notifications) partOfMassGift(event *pkg.SubGiftEvent, now time.Time) bool {
+	gifterID := event.User.GetID()
+
+	pending, ok := m.pendingGifts[gifterID]
+	if !ok {
+		return false
+	}
+
+	if now.After(pending.expiresAt) {
+		delete(m.pendingGifts, gifterID)
+		return false
+	}
+
+	pending.remaining--
+	if pending.remaining <= 0 {
+		delete(m.pendingGifts, gifterID)
+	}
+
+	return true
+}
+
+// allowGiftAnnouncement returns true if the gift cooldown is over
+// We assume that mutex is locked already
+func (m *notifications) allowGiftAnnouncement(now time.Time) bool {
+	if now.Sub(m.lastGiftAnnouncement) < time.Duration(m.GiftCooldownSeconds)*time.Second {
+		return false
+	}
+
+	m.lastGiftAnnouncement = now
+
+	return true
+}
+
+// template returns the response template for the event, or an empty string if the event should not be announced
+func (m *notifications) template(event pkg.Event) string {
+	now := time.Now()
+
+	switch event := event.(type) {
+	case *pkg.SubEvent:
+		if event.Resub {
+			return m.ResubMessage
+		}
+
+		return m.SubMessage
+
+	case *pkg.SubGiftEvent:
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		if m.partOfMassGift(event, now) || !m.allowGiftAnnouncement(now) {
+			return ""
+		}
+
+		return m.SubGiftMessage
+
+	case *pkg.MassSubGiftEvent:
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		m.pendingGifts[event.User.GetID()] = &pendingGifts{
+			remaining: event.Count,
+			expiresAt: now.Add(massGiftTimeout),
+		}
+
+		if !m.allowGiftAnnouncement(now) {
+			return ""
+		}
+
+		return m.MassSubGiftMessage
+
+	case *pkg.RaidEvent:
+		return m.RaidMessage
+
+	case *pkg.RitualEvent:
+		return m.RitualMessage
+	}
+
+	return ""
+}
+
+func (m *notifications) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	template := m.template(event)
+	if template == "" {
+		return nil
+	}
+
+	response, hasUserdata, err := commands.RenderTemplate(template, &commands.TemplateContext{
+		Bot:        bot,
+		BotChannel: m.botChannel,
+		Channel:    source,
+		User:       event.GetUser(),
+		Event:      event,
+	})
+	if err != nil {
+		// Not a module failure, resub messages can make the template render into a chat command
+		fmt.Println("Error rendering notification:", err)
+		return nil
+	}
+
+	// Resub messages and usernames are picked by the users themselves
+	if hasUserdata && checkBanphrases(source, response) {
+		return nil
+	}
+
+	bot.Say(source, response)
+
+	return nil
+}
+
+func (m *notifications) OnMessage(bot pkg.Sender, source pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
+	return nil
+}
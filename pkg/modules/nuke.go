@@ -14,14 +14,14 @@ import (
 const garbageCollectionInterval = 1 * time.Minute
 const maxMessageAge = 5 * time.Minute
 
+// nukeModule is a global module so a single message buffer, keyed by channel ID, is shared between all channels
 type nukeModule struct {
-	botChannel pkg.BotChannel
-
 	server        *server
 	messages      map[string][]nukeMessage
 	messagesMutex sync.Mutex
 
 	ticker *time.Ticker
+	done   chan struct{}
 }
 
 type nukeMessage struct {
@@ -30,104 +30,103 @@ type nukeMessage struct {
 	timestamp time.Time
 }
 
-func newNuke() pkg.Module {
-	m := &nukeModule{
+func newNuke() pkg.GlobalModule {
+	return &nukeModule{
 		server:   &_server,
 		messages: make(map[string][]nukeMessage),
 	}
+}
+
+var nukeSpec = globalModuleSpec{
+	id:    "nuke",
+	name:  "Nuke",
+	maker: newNuke,
+
+	enabledByDefault: true,
+}
 
+func (m *nukeModule) Initialize(settings []byte) error {
 	m.ticker = time.NewTicker(garbageCollectionInterval)
+	m.done = make(chan struct{})
 
 	go func() {
 		for {
 			select {
 			case <-m.ticker.C:
 				m.garbageCollect()
+			case <-m.done:
+				return
 			}
 		}
 	}()
 
-	return m
-}
-
-var nukeSpec = moduleSpec{
-	id:    "nuke",
-	name:  "Nuke",
-	maker: newNuke,
-
-	enabledByDefault: true,
-}
-
-func (m *nukeModule) Initialize(botChannel pkg.BotChannel, settings []byte) error {
-	m.botChannel = botChannel
-
 	return nil
 }
 
 func (m *nukeModule) Disable() error {
+	m.ticker.Stop()
+	close(m.done)
+
 	return nil
 }
 
-func (m *nukeModule) Spec() pkg.ModuleSpec {
+func (m *nukeModule) Spec() pkg.GlobalModuleSpec {
 	return &nukeSpec
 }
 
-func (m *nukeModule) BotChannel() pkg.BotChannel {
-	return m.botChannel
-}
-
+// OnWhisper handles nukes that are whispered to the bot, i.e. "#forsen !nuke bad phrase 1m 10m"
 func (m *nukeModule) OnWhisper(bot pkg.Sender, user pkg.User, message pkg.Message) error {
-	const usageString = `Usage: #channel !nuke phrase phrase phrase time`
+	const usageString = `usage: #channel !nuke bad phrase 1m 10m`
 
 	parts := strings.Split(message.GetText(), " ")
-	if len(parts) < 4 {
+	if len(parts) < 5 {
 		return nil
 	}
 
-	fmt.Println("Parts:", parts)
-
-	if parts[0] != "!nuke" {
+	if parts[1] != "!nuke" {
 		return nil
 	}
 
-	channel := bot.MakeChannel(m.botChannel.ChannelName())
-	if !user.HasPermission(channel, pkg.PermissionModeration) {
-		bot.Whisper(user, "you don't have permissions to use the !nuke command")
+	channel := bot.MakeChannel(strings.ToLower(strings.TrimPrefix(parts[0], "#")))
+	if channel.GetID() == "" {
+		bot.Whisper(user, usageString)
 		return nil
 	}
 
 	// TODO: Add another specific global/channel permission to check
-	if !user.IsModerator() && !user.IsBroadcaster(channel) && !user.HasChannelPermission(channel, pkg.PermissionModeration) && !user.HasGlobalPermission(pkg.PermissionModeration) {
+	if !user.IsBroadcaster(channel) && !user.HasPermission(channel, pkg.PermissionModeration) {
+		bot.Whisper(user, "you don't have permissions to use the !nuke command")
 		return nil
 	}
 
+	parts = parts[1:]
+
 	phrase := strings.Join(parts[1:len(parts)-2], " ")
 	scrollbackLength, err := time.ParseDuration(parts[len(parts)-2])
 	if err != nil {
-		bot.Whisper(user, "usage: !nuke bad phrase 1m 10m")
+		bot.Whisper(user, usageString)
 		return err
 	}
 	if scrollbackLength < 0 {
-		bot.Whisper(user, "usage: !nuke bad phrase 1m 10m")
+		bot.Whisper(user, usageString)
 		return errors.New("scrollback length must be positive")
 	}
 	timeoutDuration, err := time.ParseDuration(parts[len(parts)-1])
 	if err != nil {
-		bot.Whisper(user, "usage: !nuke bad phrase 1m 10m")
+		bot.Whisper(user, usageString)
 		return err
 	}
 	if timeoutDuration < 0 {
-		bot.Whisper(user, "usage: !nuke bad phrase 1m 10m")
+		bot.Whisper(user, usageString)
 		return errors.New("timeout duration must be positive")
 	}
 
 	m.nuke(user, bot, channel, phrase, scrollbackLength, timeoutDuration)
 
 	return nil
-
 }
 
-func (m *nukeModule) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
+func (m *nukeModule) OnMessage(bot pkg.Sender, botChannel pkg.BotChannel, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
 	defer func() {
 		m.addMessage(channel, user, message)
 	}()
@@ -26,6 +26,8 @@ var pajbot1CommandsSpec = moduleSpec{
 	id:    "pajbot1_commands",
 	name:  "pajbot1 commands",
 	maker: newPajbot1Commands,
+
+	phase: pkg.ModulePhaseCommand,
 }
 
 func (m *Pajbot1Commands) loadPajbot1Commands() error {
@@ -88,6 +90,10 @@ func (m Pajbot1Commands) OnWhisper(bot pkg.Sender, source pkg.User, message pkg.
 	return nil
 }
 
+func (m Pajbot1Commands) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
 func (m Pajbot1Commands) OnMessage(bot pkg.Sender, source pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
 	if source.GetChannel() != "snusbot" {
 		return nil
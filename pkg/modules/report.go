@@ -28,6 +28,8 @@ var reportSpec = moduleSpec{
 	id:    "report",
 	name:  "Report",
 	maker: newReport,
+
+	phase: pkg.ModulePhaseCommand,
 }
 
 func (m *Report) ProcessReport(bot pkg.Sender, source pkg.Channel, user pkg.User, parts []string) error {
@@ -94,6 +96,10 @@ func (m *Report) OnWhisper(bot pkg.Sender, source pkg.User, message pkg.Message)
 	return nil
 }
 
+func (m *Report) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
 func (m *Report) report(bot pkg.Sender, reporter pkg.User, targetChannel pkg.Channel, targetUsername string, reason string, duration int) {
 	// s := fmt.Sprintf("%s reported %s in #%s (%s) - https://api.gempir.com/channel/forsen/user/%s", reporter.GetName(), targetUsername, targetChannel.GetChannel(), reason, targetUsername)
 
@@ -1,8 +1,10 @@
 package modules
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/pajlada/pajbot2/pkg"
 )
@@ -44,3 +46,25 @@ func loadModule(settings []byte, module pkg.Module) error {
 
 	return json.Unmarshal(settings, module)
 }
+
+// ValidateSettings returns an error if the module can't be configured with the given settings, i.e. because a setting is misspelled
+func ValidateSettings(spec pkg.ModuleSpec, settings []byte) error {
+	module := spec.Maker()()
+
+	decoder := json.NewDecoder(bytes.NewReader(settings))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(module); err != nil {
+		return fmt.Errorf("invalid settings: %s", err)
+	}
+
+	if overrider, ok := module.(pkg.ExemptionPolicyOverrider); ok {
+		if policy := overrider.ExemptionPolicyOverride(); policy != nil {
+			if err := policy.Validate(); err != nil {
+				return fmt.Errorf("invalid exemptions: %s", err)
+			}
+		}
+	}
+
+	return nil
+}
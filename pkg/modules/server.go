@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/commands"
 	"github.com/pajlada/pajbot2/pkg/common/config"
 	"github.com/pajlada/pajbot2/pkg/report"
 )
@@ -14,18 +15,23 @@ type server struct {
 	sql          *sql.DB
 	oldSession   *sql.DB
 	pubSub       pkg.PubSub
+	userStore    pkg.UserStore
 	reportHolder *report.Holder
+
+	whisperCommands *commands.Registry
 }
 
 var _server server
 
-func InitServer(app pkg.Application, pajbot1Config *config.Pajbot1Config, reportHolder *report.Holder) error {
+func InitServer(app pkg.Application, pajbot1Config *config.Pajbot1Config, webConfig *config.WebConfig, reportHolder *report.Holder) error {
 	var err error
 
 	_server.sql = app.SQL()
 	_server.oldSession, err = sql.Open("mysql", pajbot1Config.SQL.DSN)
 	_server.pubSub = app.PubSub()
+	_server.userStore = app.UserStore()
 	_server.reportHolder = reportHolder
+	_server.whisperCommands = newWhisperCommands(webConfig)
 	if err != nil {
 		return err
 	}
@@ -53,6 +59,11 @@ type moduleSpec struct {
 
 	priority int
 
+	phase pkg.ModulePhase
+
+	// IDs of the modules this module needs, i.e. modules that parse data from the message that this module uses
+	dependencies []string
+
 	parameters map[string]*moduleParameterSpec
 }
 
@@ -76,6 +87,14 @@ func (s *moduleSpec) Priority() int {
 	return s.priority
 }
 
+func (s *moduleSpec) Phase() pkg.ModulePhase {
+	return s.phase
+}
+
+func (s *moduleSpec) Dependencies() []string {
+	return s.dependencies
+}
+
 var _ pkg.ModuleSpec = &moduleSpec{}
 
 var _modulesMutex sync.Mutex
@@ -129,23 +148,28 @@ func GetModule(moduleID string) (pkg.ModuleSpec, bool) {
 }
 
 func init() {
-	// TODO: Remove action performer
 	Register(bttvEmoteParserSpec)
 
+	Register(&antiRaidSpec)
 	Register(&badCharacterSpec)
 	Register(&bannedNamesSpec)
 	Register(&pajbot1BanphraseSpec)
 	// TODO: Remove bttv emote parser. This should be done automatically, always
 	// custom commands
 	Register(&emoteLimitSpec)
+	Register(&followAnnouncementsSpec)
+	Register(&followerOnlySpec)
 	Register(&giveawaySpec)
 	Register(&latinFilterSpec)
 	Register(&linkFilterSpec)
 	Register(&messageLengthLimitSpec)
-	Register(&nukeSpec)
+	Register(&notificationsSpec)
 	Register(&pajbot1CommandsSpec)
 	Register(&reportSpec)
 	Register(&testSpec)
 	Register(basicCommandsModuleSpec)
-	Register(actionPerformerModuleSpec)
+	Register(commandsModuleSpec)
+
+	RegisterGlobal(&nukeSpec)
+	RegisterGlobal(&sharedBansSpec)
 }
@@ -0,0 +1,251 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/channels"
+	"github.com/pajlada/pajbot2/pkg/pubsub"
+	"github.com/pajlada/pajbot2/pkg/sharedbans"
+)
+
+// sharedBansModule proposes bans from one channel to the other channels in the same shared ban group.
+// Channels opt into groups with the !pb2sharedbans command, and proposed bans are approved or denied from the web dashboard
+type sharedBansModule struct {
+	server *server
+
+	subscription pubsub.Subscription
+}
+
+var sharedBansSpec = globalModuleSpec{
+	id:    "shared_bans",
+	name:  "Shared bans",
+	maker: newSharedBans,
+
+	enabledByDefault: true,
+}
+
+func newSharedBans() pkg.GlobalModule {
+	return &sharedBansModule{
+		server: &_server,
+	}
+}
+
+func (m *sharedBansModule) Initialize(settings []byte) error {
+	m.server.pubSub.Subscribe(m, "BanEvent")
+	m.server.pubSub.Subscribe(m, "HandleSharedBan")
+
+	return nil
+}
+
+func (m *sharedBansModule) Disable() error {
+	m.subscription.Cancel()
+
+	return nil
+}
+
+func (m *sharedBansModule) Spec() pkg.GlobalModuleSpec {
+	return &sharedBansSpec
+}
+
+func (m *sharedBansModule) OnWhisper(bot pkg.Sender, user pkg.User, message pkg.Message) error {
+	return nil
+}
+
+func (m *sharedBansModule) OnMessage(bot pkg.Sender, botChannel pkg.BotChannel, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
+	return nil
+}
+
+func (m *sharedBansModule) AuthenticatedUser() pkg.User {
+	return nil
+}
+
+func (m *sharedBansModule) IsApplication() bool {
+	return true
+}
+
+func (m *sharedBansModule) Connection() pkg.PubSubConnection {
+	return m
+}
+
+func (m *sharedBansModule) MessageReceived(source pkg.PubSubSource, topic string, data []byte) error {
+	if err := m.subscription.Check("shared bans module"); err != nil {
+		return err
+	}
+
+	switch topic {
+	case "BanEvent":
+		var msg pkg.PubSubBanEvent
+		if err := json.Unmarshal(data, &msg); err != nil {
+			fmt.Println("Error unmarshalling:", err)
+			return nil
+		}
+
+		if err := m.propagate(msg); err != nil {
+			fmt.Println("Error propagating shared ban:", err)
+		}
+
+	case "HandleSharedBan":
+		var msg pkg.PubSubHandleSharedBan
+		if err := json.Unmarshal(data, &msg); err != nil {
+			fmt.Println("Error unmarshalling:", err)
+			return nil
+		}
+
+		if err := m.handle(source, msg); err != nil {
+			fmt.Println("Error handling shared ban:", err)
+		}
+	}
+
+	return nil
+}
+
+// propagate proposes the ban to, or applies it in, every other channel that shares a group with the channel the ban happened in
+func (m *sharedBansModule) propagate(e pkg.PubSubBanEvent) error {
+	db := m.server.sql
+
+	if e.Channel.ID == "" || e.Target.ID == "" {
+		return nil
+	}
+
+	// Don't send bans that we applied ourselves back to the channel they came from
+	applied, err := sharedbans.WasApplied(db, e.Channel.ID, e.Target.ID)
+	if err != nil {
+		return err
+	}
+
+	if applied {
+		return nil
+	}
+
+	peers, err := sharedbans.GroupPeers(db, e.Channel.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(peers) == 0 {
+		return nil
+	}
+
+	originName := e.Channel.Name
+	if originName == "" {
+		originName = m.server.userStore.GetName(e.Channel.ID)
+	}
+
+	details := fmt.Sprintf("%s was banned in %s by %s", e.Target.Name, originName, e.Source.Name)
+	if e.Reason != "" {
+		details += ": " + e.Reason
+	}
+
+	for _, peer := range peers {
+		allowlisted, err := sharedbans.IsAllowlisted(db, peer.ChannelID, e.Target.ID)
+		if err != nil {
+			return err
+		}
+
+		b := &sharedbans.Ban{
+			GroupID:         peer.GroupID,
+			OriginChannelID: e.Channel.ID,
+			ChannelID:       peer.ChannelID,
+			UserID:          e.Target.ID,
+			UserName:        e.Target.Name,
+			SourceID:        e.Source.ID,
+			SourceName:      e.Source.Name,
+			Reason:          e.Reason,
+			State:           sharedbans.StatePending,
+		}
+
+		logAction := sharedbans.LogProposed
+
+		if allowlisted {
+			b.State = sharedbans.StateAllowlisted
+			logAction = sharedbans.LogAllowlisted
+		} else if peer.AutoApply {
+			b.State = sharedbans.StateApplied
+			logAction = sharedbans.LogAutoApplied
+		}
+
+		created, err := sharedbans.Propose(db, b)
+		if err != nil {
+			return err
+		}
+
+		if !created {
+			// The ban has already been proposed to this channel, i.e. from another channel in the group
+			continue
+		}
+
+		if b.State == sharedbans.StateApplied {
+			m.apply(b, originName)
+		}
+
+		if err = sharedbans.Log(db, b.ChannelID, b.ID, logAction, "", "", details); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handle approves or denies a pending shared ban on behalf of a moderator of the channel it was proposed to
+func (m *sharedBansModule) handle(source pkg.PubSubSource, msg pkg.PubSubHandleSharedBan) error {
+	db := m.server.sql
+
+	user := source.AuthenticatedUser()
+	if user == nil {
+		fmt.Println("Missing auth in HandleSharedBan")
+		return nil
+	}
+
+	channel := channels.TwitchChannel{
+		ID: msg.ChannelID,
+	}
+
+	if !user.HasPermission(channel, pkg.PermissionModeration) {
+		fmt.Println("user does not have moderation permission")
+		return nil
+	}
+
+	b, err := sharedbans.LoadBan(db, msg.ChannelID, msg.BanID)
+	if err != nil {
+		return err
+	}
+
+	if b == nil {
+		fmt.Printf("No shared ban found with ID %d\n", msg.BanID)
+		return nil
+	}
+
+	state := sharedbans.StateDenied
+	logAction := sharedbans.LogDenied
+
+	if msg.Approve {
+		state = sharedbans.StateApplied
+		logAction = sharedbans.LogApplied
+	}
+
+	if err = b.Handle(db, state, user.GetID(), user.GetName()); err != nil {
+		return err
+	}
+
+	if msg.Approve {
+		m.apply(b, m.server.userStore.GetName(b.OriginChannelID))
+	}
+
+	return sharedbans.Log(db, b.ChannelID, b.ID, logAction, user.GetID(), user.GetName(), "")
+}
+
+// apply bans the user in the channel the shared ban was proposed to
+func (m *sharedBansModule) apply(b *sharedbans.Ban, originName string) {
+	reason := "Banned in " + originName
+	if b.Reason != "" {
+		reason += ": " + b.Reason
+	}
+
+	m.server.pubSub.Publish(m, "Ban", &pkg.PubSubBan{
+		Channel: m.server.userStore.GetName(b.ChannelID),
+		Target:  b.UserName,
+		Reason:  reason,
+	})
+}
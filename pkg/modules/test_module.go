@@ -20,6 +20,8 @@ var testSpec = moduleSpec{
 	maker: newTest,
 
 	enabledByDefault: false,
+
+	phase: pkg.ModulePhaseCommand,
 }
 
 func (m *test) Initialize(botChannel pkg.BotChannel, settings []byte) error {
@@ -43,6 +45,10 @@ func (m test) OnWhisper(bot pkg.Sender, user pkg.User, message pkg.Message) erro
 	return nil
 }
 
+func (m test) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+
 func (m test) OnMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
 	bot.Mention(channel, user, "test module xd")
 	return nil
@@ -0,0 +1,76 @@
+package modules
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/commands"
+)
+
+// filterWarning is embedded in the settings of filters, so channels can have the bot explain to users why they were punished
+type filterWarning struct {
+	// Tell users why they were punished
+	WarnUsers bool `json:",omitempty"`
+
+	// Response template for the warning. $(reason) is the reason of the punishment, i.e. "No links allowed"
+	WarningMessage string `json:",omitempty"`
+
+	// Whisper the warning instead of mentioning the user in chat
+	WarningWhisper bool `json:",omitempty"`
+
+	// Users are warned at most once every WarningCooldownSeconds, so the warnings don't turn into spam themselves
+	WarningCooldownSeconds int `json:",omitempty"`
+}
+
+func newFilterWarning() filterWarning {
+	return filterWarning{
+		WarningMessage:         "$(reason)",
+		WarningCooldownSeconds: 60,
+	}
+}
+
+// punishmentReason returns the reason given for the punishment
+func punishmentReason(punishment pkg.ActionType) string {
+	switch punishment := punishment.(type) {
+	case pkg.Timeout:
+		return punishment.Reason
+	case pkg.Ban:
+		return punishment.Reason
+	case pkg.Delete:
+		return punishment.Reason
+	}
+
+	return ""
+}
+
+// warn returns the punishment wrapped in a warning that explains it to the user, or the punishment as it is if warnings are disabled
+func (w *filterWarning) warn(bot pkg.Sender, botChannel pkg.BotChannel, channel pkg.Channel, user pkg.User, punishment pkg.ActionType) pkg.ActionType {
+	if !w.WarnUsers {
+		return punishment
+	}
+
+	message, hasUserdata, err := commands.RenderTemplate(w.WarningMessage, &commands.TemplateContext{
+		Bot:        bot,
+		BotChannel: botChannel,
+		Channel:    channel,
+		User:       user,
+		Reason:     punishmentReason(punishment),
+	})
+	if err != nil {
+		fmt.Println("Error rendering warning:", err)
+		return punishment
+	}
+
+	// Mentions are sent to the chat, so they must not contain banned phrases
+	if hasUserdata && !w.WarningWhisper && checkBanphrases(channel, message) {
+		return punishment
+	}
+
+	return pkg.Warn{
+		Action:   punishment,
+		Message:  message,
+		Whisper:  w.WarningWhisper,
+		Cooldown: time.Duration(w.WarningCooldownSeconds) * time.Second,
+	}
+}
@@ -0,0 +1,36 @@
+package modules
+
+import (
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/commands"
+	"github.com/pajlada/pajbot2/pkg/common/config"
+)
+
+// newWhisperCommands returns the commands that can be whispered to the bot without a channel as context
+func newWhisperCommands(webConfig *config.WebConfig) *commands.Registry {
+	scheme := "http://"
+	if webConfig.Secure {
+		scheme = "https://"
+	}
+
+	r := commands.NewRegistry("help")
+
+	// Whispers only reach the user that sent them, so there's no need for a global cooldown
+	r.SetDefaultCooldown(commands.Cooldown{
+		User: 5,
+	})
+
+	r.Register(commands.NewMyPermissions())
+	r.Register(commands.NewLink(scheme + webConfig.Domain + "/api/auth/twitch/user?redirect=/"))
+
+	return r
+}
+
+// HandleWhisper runs the global whisper command in the message if there is one. Returns true if the whisper was handled
+func HandleWhisper(bot pkg.Sender, user pkg.User, message pkg.Message) bool {
+	if _server.whisperCommands == nil {
+		return false
+	}
+
+	return _server.whisperCommands.DispatchWhisper(bot, user, message)
+}
@@ -43,3 +43,26 @@ func GetPermissionBits(permissionNames []string) (permissions Permission) {
 
 	return
 }
+
+var permissionNames = []struct {
+	permission Permission
+	name       string
+}{
+	{PermissionReport, "report"},
+	{PermissionRaffle, "raffle"},
+	{PermissionAdmin, "admin"},
+	{PermissionModeration, "moderation"},
+	{PermissionReportAPI, "reportapi"},
+}
+
+// GetPermissionNames converts a binary value to the names of the permissions it contains.
+// 0b110 returns ["raffle", "admin"]
+func GetPermissionNames(permissions Permission) (names []string) {
+	for _, p := range permissionNames {
+		if permissions&p.permission != 0 {
+			names = append(names, p.name)
+		}
+	}
+
+	return
+}
@@ -1,5 +1,7 @@
 package pkg
 
+import "time"
+
 type PubSub interface {
 	Subscribe(source PubSubSource, topic string)
 	Publish(source PubSubSource, topic string, data interface{})
@@ -62,3 +64,78 @@ type PubSubTimeoutEvent struct {
 	Duration int
 	Reason   string
 }
+
+// PubSubMessageDeletedEvent is published as MessageDeletedEvent whenever a single message is deleted in a channel we've joined
+type PubSubMessageDeletedEvent struct {
+	Channel PubSubUser
+	Target  PubSubUser
+
+	MessageID string
+	Text      string
+}
+
+// PubSubCommandsUpdated is published whenever the commands of a channel have been modified outside of the bot
+type PubSubCommandsUpdated struct {
+	ChannelID string
+}
+
+// PubSubHandleSharedBan is published when a moderator approves or denies a shared ban that was proposed to their channel
+type PubSubHandleSharedBan struct {
+	ChannelID string
+	BanID     int64
+	Approve   bool
+}
+
+// PubSubModuleEvent is published as ModuleEnabled or ModuleDisabled whenever a module is enabled or disabled in a bot channel.
+// Reloading a module publishes ModuleDisabled followed by ModuleEnabled
+type PubSubModuleEvent struct {
+	ChannelID    string
+	BotChannelID int64
+	ModuleID     string
+
+	// Set if the module was disabled because it kept failing
+	AutoDisabled bool `json:",omitempty"`
+}
+
+// PubSubActionCandidate is an action that a module proposed for a chat message
+type PubSubActionCandidate struct {
+	// ID of the module that proposed the action
+	Source string
+	Action string
+	Chosen bool
+}
+
+// PubSubActionEvent is published as ActionPerformed once the action chosen for a chat message has been carried out
+type PubSubActionEvent struct {
+	Channel PubSubUser
+	Target  PubSubUser
+	Message string
+
+	// Every action that was proposed for the message, including the one that was carried out
+	Candidates []PubSubActionCandidate
+}
+
+// PubSubStreamEvent is published as StreamOnline or StreamOffline whenever a stream we've joined goes online or offline
+type PubSubStreamEvent struct {
+	ChannelID string
+
+	// Only set for StreamOnline
+	Title     string     `json:",omitempty"`
+	StartedAt *time.Time `json:",omitempty"`
+}
+
+// PubSubRoomStateEvent is published as RoomStateChanged whenever the chat settings of a channel we've joined change
+type PubSubRoomStateEvent struct {
+	ChannelID    string
+	BotChannelID int64
+
+	RoomState RoomState
+}
+
+// PubSubFollowEvent is published as Follow whenever the followers webhook tells us that a user followed a channel
+type PubSubFollowEvent struct {
+	Channel PubSubUser
+	User    PubSubUser
+
+	FollowedAt time.Time
+}
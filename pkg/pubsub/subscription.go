@@ -0,0 +1,37 @@
+package pubsub
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Subscription lets a connection stop receiving messages from the topics it subscribed to, i.e. once the module it belongs to has been disabled
+type Subscription struct {
+	mutex sync.Mutex
+
+	cancelled bool
+}
+
+// Cancel makes the connection unsubscribe from its topics the next time it receives a message
+func (s *Subscription) Cancel() {
+	s.mutex.Lock()
+	s.cancelled = true
+	s.mutex.Unlock()
+}
+
+func (s *Subscription) Cancelled() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.cancelled
+}
+
+// Check returns an error if the subscription has been cancelled.
+// MessageReceived should return the error as is, since returning an error there unsubscribes the connection from the topic
+func (s *Subscription) Check(name string) error {
+	if s.Cancelled() {
+		return fmt.Errorf("%s has been disabled", name)
+	}
+
+	return nil
+}
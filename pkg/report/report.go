@@ -54,6 +54,7 @@ type Holder struct {
 	db        *sql.DB
 	pubSub    pkg.PubSub
 	userStore pkg.UserStore
+	bots      pkg.BotStore
 
 	reportsMutex *sync.Mutex
 	reports      map[uint32]Report
@@ -68,6 +69,7 @@ func New(app pkg.Application) (*Holder, error) {
 		db:        app.SQL(),
 		pubSub:    app.PubSub(),
 		userStore: app.UserStore(),
+		bots:      app.TwitchBots(),
 
 		reportsMutex: &sync.Mutex{},
 		reports:      make(map[uint32]Report),
@@ -344,18 +346,50 @@ func (h *Holder) dismissReport(reportID uint32) error {
 	return nil
 }
 
-func (h *Holder) handleBanEvent(banEvent pkg.PubSubBanEvent) error {
+// dismissReportsFor dismisses the report of the target user in the channel, if there is one
+func (h *Holder) dismissReportsFor(channel, target pkg.PubSubUser) {
 	h.reportsMutex.Lock()
 	defer h.reportsMutex.Unlock()
 
 	for reportID, report := range h.reports {
-		if report.Channel.ID == banEvent.Channel.ID && report.Target.ID == banEvent.Target.ID {
+		if report.Channel.ID == channel.ID && report.Target.ID == target.ID {
 			// Found matching report
 			h.dismissReport(reportID)
 
 			break
 		}
 	}
+}
+
+// isBot returns true if the user is one of our bots
+func (h *Holder) isBot(user pkg.PubSubUser) bool {
+	if user.ID == "" {
+		return false
+	}
+
+	for it := h.bots.Iterate(); it.Next(); {
+		if it.Value().TwitchAccount().ID() == user.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *Holder) handleBanEvent(banEvent pkg.PubSubBanEvent) error {
+	h.dismissReportsFor(banEvent.Channel, banEvent.Target)
+
+	return nil
+}
+
+// handleTimeoutEvent dismisses the report of a user that a moderator has timed out.
+// Timeouts by our bots are ignored, since reporting a user times them out until the report is handled
+func (h *Holder) handleTimeoutEvent(timeoutEvent pkg.PubSubTimeoutEvent) error {
+	if h.isBot(timeoutEvent.Source) {
+		return nil
+	}
+
+	h.dismissReportsFor(timeoutEvent.Channel, timeoutEvent.Target)
 
 	return nil
 }
@@ -383,6 +417,16 @@ func (h *Holder) MessageReceived(source pkg.PubSubSource, topic string, data []b
 		}
 
 		return h.handleBanEvent(msg)
+
+	case "TimeoutEvent":
+		var msg pkg.PubSubTimeoutEvent
+		err := json.Unmarshal(data, &msg)
+		if err != nil {
+			fmt.Println("Error unmarshalling:", err)
+			return nil
+		}
+
+		return h.handleTimeoutEvent(msg)
 	}
 
 	return nil
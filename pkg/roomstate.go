@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RoomState is the chat settings of a channel, as told by Twitch in ROOMSTATE messages
+type RoomState struct {
+	// Seconds users have to wait between messages. 0 if slow mode is off
+	Slow int
+
+	// Minutes users need to have followed the channel to chat. -1 if followers-only mode is off
+	FollowersOnly int
+
+	EmoteOnly bool
+	R9K       bool
+	SubsOnly  bool
+}
+
+// ChannelMode is a chat setting that can be turned on and off with a chat command
+type ChannelMode string
+
+const (
+	ChannelModeSlow          ChannelMode = "slow"
+	ChannelModeFollowersOnly ChannelMode = "followers"
+	ChannelModeEmoteOnly     ChannelMode = "emoteonly"
+	ChannelModeR9K           ChannelMode = "r9k"
+	ChannelModeSubsOnly      ChannelMode = "subscribers"
+)
+
+var ChannelModes = []ChannelMode{
+	ChannelModeSlow,
+	ChannelModeFollowersOnly,
+	ChannelModeEmoteOnly,
+	ChannelModeR9K,
+	ChannelModeSubsOnly,
+}
+
+// ParseChannelMode returns the channel mode with the given name, i.e. "slow" or "subscribers"
+func ParseChannelMode(name string) (ChannelMode, error) {
+	name = strings.ToLower(name)
+
+	for _, mode := range ChannelModes {
+		if string(mode) == name {
+			return mode, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid mode %s. valid modes: slow, followers, emoteonly, r9k, subscribers", name)
+}
+
+// Enabled returns true if the mode is on in the room state
+func (s RoomState) Enabled(mode ChannelMode) bool {
+	switch mode {
+	case ChannelModeSlow:
+		return s.Slow > 0
+	case ChannelModeFollowersOnly:
+		return s.FollowersOnly >= 0
+	case ChannelModeEmoteOnly:
+		return s.EmoteOnly
+	case ChannelModeR9K:
+		return s.R9K
+	case ChannelModeSubsOnly:
+		return s.SubsOnly
+	}
+
+	return false
+}
+
+// Command returns the chat command that turns the mode on. argument is i.e. the number of seconds for slow mode, and may be empty
+func (m ChannelMode) Command(argument string) string {
+	command := "." + string(m)
+	if m == ChannelModeR9K {
+		command = ".r9kbeta"
+	}
+
+	if argument != "" {
+		command += " " + argument
+	}
+
+	return command
+}
+
+// PermaMode is a channel mode that the bot turns back on whenever it's turned off
+type PermaMode struct {
+	Mode ChannelMode
+
+	// Given to the chat command, i.e. "30" for 30 second slow mode
+	Argument string `json:",omitempty"`
+}
@@ -0,0 +1,22 @@
+package pkg
+
+import "testing"
+
+func TestChannelModeCommand(t *testing.T) {
+	tests := []struct {
+		mode     ChannelMode
+		argument string
+		expected string
+	}{
+		{ChannelModeSlow, "30", ".slow 30"},
+		{ChannelModeFollowersOnly, "", ".followers"},
+		{ChannelModeR9K, "", ".r9kbeta"},
+		{ChannelModeSubsOnly, "", ".subscribers"},
+	}
+
+	for _, test := range tests {
+		if command := test.mode.Command(test.argument); command != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, command)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package sharedbans
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg/common"
+)
+
+// State describes what happened to a shared ban in the channel it was proposed to
+type State uint8
+
+const (
+	// StatePending means the ban is waiting to be approved or denied by a moderator of the channel
+	StatePending State = iota
+
+	// StateApplied means the user has been banned in the channel
+	StateApplied
+
+	// StateDenied means a moderator of the channel decided not to apply the ban
+	StateDenied
+
+	// StateAllowlisted means the ban was ignored because the user is on the allowlist of the channel
+	StateAllowlisted
+)
+
+func (s State) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateApplied:
+		return "applied"
+	case StateDenied:
+		return "denied"
+	case StateAllowlisted:
+		return "allowlisted"
+	}
+
+	return "unknown"
+}
+
+// Actions stored in the audit log
+const (
+	LogProposed    = "proposed"
+	LogApplied     = "applied"
+	LogAutoApplied = "auto_applied"
+	LogDenied      = "denied"
+	LogAllowlisted = "allowlisted"
+
+	LogJoinedGroup     = "joined_group"
+	LogLeftGroup       = "left_group"
+	LogAllowlistAdd    = "allowlist_add"
+	LogAllowlistRemove = "allowlist_remove"
+)
+
+// Ban is a ban from one channel that is proposed to, or applied in, another channel of the same group
+type Ban struct {
+	ID      int64
+	GroupID int64
+
+	OriginChannelID string
+	ChannelID       string
+
+	UserID   string
+	UserName string
+
+	SourceID   string
+	SourceName string
+
+	Reason string
+
+	State State
+	Time  time.Time
+
+	HandlerID   *string    `json:",omitempty"`
+	HandlerName *string    `json:",omitempty"`
+	TimeHandled *time.Time `json:",omitempty"`
+}
+
+// Membership is a channels membership in a shared ban group
+type Membership struct {
+	GroupID   int64
+	GroupName string
+	ChannelID string
+	AutoApply bool
+}
+
+// AllowlistEntry is a user that will never be banned in a channel by a shared ban
+type AllowlistEntry struct {
+	UserID    string
+	UserName  string
+	AddedByID string
+	Time      time.Time
+}
+
+// LogEntry is an entry in the shared ban audit log
+type LogEntry struct {
+	ID          int64
+	ChannelID   string
+	SharedBanID *int64 `json:",omitempty"`
+	Action      string
+	ActorID     string
+	ActorName   string
+	Details     string
+	Time        time.Time
+}
+
+// Log adds an entry to the audit log. sharedBanID is 0 if the entry isn't about a specific ban
+func Log(db *sql.DB, channelID string, sharedBanID int64, action, actorID, actorName, details string) error {
+	const queryF = `
+INSERT INTO
+	SharedBanLog
+	(channel_id, shared_ban_id, action, actor_id, actor_name, details)
+	VALUES (?, ?, ?, ?, ?, ?)`
+
+	var banID *int64
+	if sharedBanID != 0 {
+		banID = &sharedBanID
+	}
+
+	_, err := db.Exec(queryF, channelID, banID, action, actorID, actorName, details)
+	return err
+}
+
+// LoadLog loads the latest audit log entries for the given channel
+func LoadLog(db *sql.DB, channelID string, limit int) ([]*LogEntry, error) {
+	const queryF = `
+SELECT
+	id, channel_id, shared_ban_id, action, actor_id, actor_name, details, time
+FROM
+	SharedBanLog
+WHERE
+	channel_id=?
+ORDER BY id DESC
+LIMIT ?`
+
+	rows, err := db.Query(queryF, channelID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var entries []*LogEntry
+
+	for rows.Next() {
+		e := &LogEntry{}
+		if err = rows.Scan(&e.ID, &e.ChannelID, &e.SharedBanID, &e.Action, &e.ActorID, &e.ActorName, &e.Details, &e.Time); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// JoinGroup makes the channel a member of the group with the given name. The group is created if it doesn't exist yet
+func JoinGroup(db *sql.DB, channelID, groupName string, autoApply bool) error {
+	groupName = strings.ToLower(groupName)
+
+	if _, err := db.Exec(`INSERT IGNORE INTO SharedBanGroup (name) VALUES (?)`, groupName); err != nil {
+		return err
+	}
+
+	const queryF = `
+INSERT INTO
+	SharedBanGroupChannel
+	(group_id, channel_id, auto_apply)
+	SELECT id, ?, ? FROM SharedBanGroup WHERE name=?
+ON DUPLICATE KEY UPDATE auto_apply=?`
+
+	_, err := db.Exec(queryF, channelID, autoApply, groupName, autoApply)
+	return err
+}
+
+// LeaveGroup removes the channel from the group with the given name
+func LeaveGroup(db *sql.DB, channelID, groupName string) error {
+	const queryF = `
+DELETE
+	SharedBanGroupChannel
+FROM
+	SharedBanGroupChannel
+INNER JOIN SharedBanGroup ON SharedBanGroup.id=SharedBanGroupChannel.group_id
+WHERE
+	SharedBanGroupChannel.channel_id=? AND SharedBanGroup.name=?`
+
+	res, err := db.Exec(queryF, channelID, strings.ToLower(groupName))
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return errors.New("channel is not in that group")
+	}
+
+	return nil
+}
+
+// Groups returns the groups the given channel is a member of
+func Groups(db *sql.DB, channelID string) ([]Membership, error) {
+	const queryF = `
+SELECT
+	g.id, g.name, c.channel_id, c.auto_apply
+FROM
+	SharedBanGroupChannel c
+INNER JOIN SharedBanGroup g ON g.id=c.group_id
+WHERE
+	c.channel_id=?
+ORDER BY g.name`
+
+	return loadMemberships(db, queryF, channelID)
+}
+
+// GroupPeers returns the memberships of all other channels that share a group with the given channel
+func GroupPeers(db *sql.DB, channelID string) ([]Membership, error) {
+	const queryF = `
+SELECT
+	g.id, g.name, peer.channel_id, peer.auto_apply
+FROM
+	SharedBanGroupChannel c
+INNER JOIN SharedBanGroup g ON g.id=c.group_id
+INNER JOIN SharedBanGroupChannel peer ON peer.group_id=c.group_id AND peer.channel_id<>c.channel_id
+WHERE
+	c.channel_id=?`
+
+	return loadMemberships(db, queryF, channelID)
+}
+
+func loadMemberships(db *sql.DB, queryF string, args ...interface{}) ([]Membership, error) {
+	rows, err := db.Query(queryF, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var memberships []Membership
+
+	for rows.Next() {
+		var m Membership
+		if err = rows.Scan(&m.GroupID, &m.GroupName, &m.ChannelID, &m.AutoApply); err != nil {
+			return nil, err
+		}
+
+		memberships = append(memberships, m)
+	}
+
+	return memberships, rows.Err()
+}
+
+// IsAllowlisted returns true if the user may not be banned in the channel by a shared ban
+func IsAllowlisted(db *sql.DB, channelID, userID string) (bool, error) {
+	const queryF = `SELECT COUNT(*) FROM SharedBanAllowlist WHERE channel_id=? AND user_id=?`
+
+	var count int
+	if err := db.QueryRow(queryF, channelID, userID).Scan(&count); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// LoadAllowlist loads the allowlist of the given channel
+func LoadAllowlist(db *sql.DB, channelID string) ([]*AllowlistEntry, error) {
+	const queryF = `SELECT user_id, user_name, added_by_id, time FROM SharedBanAllowlist WHERE channel_id=? ORDER BY user_name`
+
+	rows, err := db.Query(queryF, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var entries []*AllowlistEntry
+
+	for rows.Next() {
+		e := &AllowlistEntry{}
+		if err = rows.Scan(&e.UserID, &e.UserName, &e.AddedByID, &e.Time); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// Allow adds the user to the allowlist of the channel
+func Allow(db *sql.DB, channelID, userID, userName, addedByID string) error {
+	const queryF = `
+INSERT INTO
+	SharedBanAllowlist
+	(channel_id, user_id, user_name, added_by_id)
+	VALUES (?, ?, ?, ?)`
+
+	_, err := db.Exec(queryF, channelID, userID, userName, addedByID)
+	if common.IsDuplicateKey(err) {
+		return errors.New("user is already on the allowlist")
+	}
+
+	return err
+}
+
+// Disallow removes the user from the allowlist of the channel
+func Disallow(db *sql.DB, channelID, userID string) error {
+	const queryF = `DELETE FROM SharedBanAllowlist WHERE channel_id=? AND user_id=?`
+
+	res, err := db.Exec(queryF, channelID, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return errors.New("user is not on the allowlist")
+	}
+
+	return nil
+}
+
+const banColumns = "id, group_id, origin_channel_id, channel_id, user_id, user_name, source_id, source_name, reason, state, time, handler_id, handler_name, time_handled"
+
+func (b *Ban) scan(rows *sql.Rows) error {
+	return rows.Scan(&b.ID, &b.GroupID, &b.OriginChannelID, &b.ChannelID, &b.UserID, &b.UserName, &b.SourceID, &b.SourceName, &b.Reason, &b.State, &b.Time, &b.HandlerID, &b.HandlerName, &b.TimeHandled)
+}
+
+// Propose inserts the ban into the database. If a shared ban for the user is already pending in the channel, false is returned and nothing is inserted.
+// Bans that have been handled don't count, so a user whose ban was denied or lifted can be proposed again
+func Propose(db *sql.DB, b *Ban) (bool, error) {
+	const queryF = `
+INSERT INTO
+	SharedBan
+	(group_id, origin_channel_id, channel_id, user_id, user_name, source_id, source_name, reason, state)
+	SELECT ?, ?, ?, ?, ?, ?, ?, ?, ? FROM DUAL
+	WHERE NOT EXISTS (SELECT 1 FROM SharedBan WHERE channel_id=? AND user_id=? AND state=?)`
+
+	res, err := db.Exec(queryF, b.GroupID, b.OriginChannelID, b.ChannelID, b.UserID, b.UserName, b.SourceID, b.SourceName, b.Reason, b.State, b.ChannelID, b.UserID, StatePending)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if n == 0 {
+		return false, nil
+	}
+
+	b.ID, err = res.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+
+	b.Time = time.Now()
+
+	return true, nil
+}
+
+// WasApplied returns true if the user was banned in the channel because of a shared ban
+func WasApplied(db *sql.DB, channelID, userID string) (bool, error) {
+	const queryF = `SELECT COUNT(*) FROM SharedBan WHERE channel_id=? AND user_id=? AND state=?`
+
+	var count int
+	if err := db.QueryRow(queryF, channelID, userID, StateApplied).Scan(&count); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// LoadBans loads the latest shared bans proposed to the given channel, pending bans first
+func LoadBans(db *sql.DB, channelID string, limit int) ([]*Ban, error) {
+	const queryF = "SELECT " + banColumns + " FROM SharedBan WHERE channel_id=? ORDER BY state=0 DESC, id DESC LIMIT ?"
+
+	rows, err := db.Query(queryF, channelID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var bans []*Ban
+
+	for rows.Next() {
+		b := &Ban{}
+		if err = b.scan(rows); err != nil {
+			return nil, err
+		}
+
+		bans = append(bans, b)
+	}
+
+	return bans, rows.Err()
+}
+
+// LoadBan loads the shared ban with the given ID in the given channel. Returns nil if no such ban exists
+func LoadBan(db *sql.DB, channelID string, id int64) (*Ban, error) {
+	const queryF = "SELECT " + banColumns + " FROM SharedBan WHERE channel_id=? AND id=?"
+
+	rows, err := db.Query(queryF, channelID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	b := &Ban{}
+	if err = b.scan(rows); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Handle moves a pending ban to the given state
+func (b *Ban) Handle(db *sql.DB, state State, handlerID, handlerName string) error {
+	const queryF = `
+UPDATE
+	SharedBan
+SET
+	state=?, handler_id=?, handler_name=?, time_handled=NOW()
+WHERE
+	id=? AND state=?`
+
+	res, err := db.Exec(queryF, state, handlerID, handlerName, b.ID, StatePending)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return errors.New("shared ban has already been handled")
+	}
+
+	now := time.Now()
+
+	b.State = state
+	b.HandlerID = &handlerID
+	b.HandlerName = &handlerName
+	b.TimeHandled = &now
+
+	return nil
+}
@@ -7,6 +7,16 @@ type StreamStatus interface {
 	StartedAt() time.Time
 }
 
+// StreamData describes a stream that's live
+type StreamData struct {
+	Title  string
+	GameID string
+
+	ViewerCount int
+
+	StartedAt time.Time
+}
+
 type Stream interface {
 	Status() StreamStatus
 }
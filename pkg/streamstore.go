@@ -4,4 +4,8 @@ type StreamStore interface {
 	GetStream(Account) Stream
 
 	JoinStream(Account)
+
+	// UpdateStream sets the status of the stream with the given channel ID, i.e. when the streams webhook tells us about a change.
+	// A nil stream means the stream went offline
+	UpdateStream(channelID string, stream *StreamData)
 }
@@ -10,6 +10,7 @@ import (
 	"io"
 	"log"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -20,25 +21,14 @@ import (
 	"github.com/pajlada/pajbot2/pkg"
 	"github.com/pajlada/pajbot2/pkg/channels"
 	"github.com/pajlada/pajbot2/pkg/common"
+	"github.com/pajlada/pajbot2/pkg/modules"
 	"github.com/pajlada/pajbot2/pkg/users"
 	"github.com/pajlada/pajbot2/pkg/utils"
 	"golang.org/x/oauth2"
 )
 
-type ModeState int
-
 var _ pkg.Sender = &Bot{}
 
-const (
-	ModeUnset = iota
-	ModeEnabled
-	ModeDisabled
-)
-
-type botFlags struct {
-	PermaSubMode bool
-}
-
 type BotCredentials struct {
 	AccessToken  string
 	RefreshToken string
@@ -57,8 +47,6 @@ type Bot struct {
 
 	QuitChannel chan string
 
-	Flags botFlags
-
 	// Filled in with user IDs when bot is loaded, from SQL
 	channelsMutex *sync.Mutex
 	channels      []*BotChannel
@@ -66,6 +54,11 @@ type Bot struct {
 	// TODO: Store one point server per channel the bot is in. share between bots
 	pointServer *PointServer
 
+	// Channels that users have been seen moderating, by user ID and channel ID
+	// Used to figure out which channel a whisper is about if no channel was given
+	moderatorsMutex *sync.Mutex
+	moderators      map[string]map[string]bool
+
 	ticker *time.Ticker
 
 	userStore   pkg.UserStore
@@ -100,6 +93,9 @@ func NewBot(databaseID int, twitchAccount pkg.TwitchAccount, tokenSource oauth2.
 
 		channelsMutex: &sync.Mutex{},
 
+		moderatorsMutex: &sync.Mutex{},
+		moderators:      make(map[string]map[string]bool),
+
 		userStore:   app.UserStore(),
 		userContext: app.UserContext(),
 		streamStore: app.StreamStore(),
@@ -154,6 +150,15 @@ func (b *Bot) getBotChannel(channelID string) (int, *BotChannel) {
 	return -1, nil
 }
 
+func (b *Bot) GetBotChannel(channelID string) pkg.BotChannel {
+	_, botChannel := b.getBotChannel(channelID)
+	if botChannel == nil {
+		return nil
+	}
+
+	return botChannel
+}
+
 // channelsMutex needs to be locked before calling this function
 func (b *Bot) removeBotChannelAtIndex(index int) {
 	b.channels = append(b.channels[:index], b.channels[index+1:]...)
@@ -289,6 +294,10 @@ func (m TwitchMessage) GetText() string {
 	return m.Text
 }
 
+func (m TwitchMessage) GetID() string {
+	return m.Tags["id"]
+}
+
 func (m TwitchMessage) GetTwitchReader() pkg.EmoteReader {
 	return m.twitchEmoteReader
 }
@@ -332,16 +341,62 @@ func (b *Bot) Whisper(user pkg.User, message string) {
 	b.Client.Whisper(user.GetName(), message)
 }
 
+// moderate remembers a timeout or ban performed by the bot itself. It's logged and published once Twitch confirms it through CLEARCHAT,
+// which doesn't tell us who performed the action or why
+func (b *Bot) moderate(channel pkg.Channel, user pkg.User, action int, duration int, reason string) {
+	expectModerationAction(&ModerationAction{
+		Channel: pkg.PubSubUser{
+			ID:   channel.GetID(),
+			Name: channel.GetChannel(),
+		},
+		Source: pkg.PubSubUser{
+			ID:   b.TwitchAccount().ID(),
+			Name: b.TwitchAccount().Name(),
+		},
+		Target: pkg.PubSubUser{
+			ID:   user.GetID(),
+			Name: user.GetName(),
+		},
+		Action:   action,
+		Duration: duration,
+		Reason:   reason,
+	}, time.Now())
+}
+
+// canModerate returns false if Twitch won't let us act on the user. Whether users should be left alone is up to the exemption policy of the channel, see BotChannel.ExemptionPolicy
+func (b *Bot) canModerate(channel pkg.Channel, user pkg.User, action string) bool {
+	if user.IsModerator() || user.IsBroadcaster(channel) {
+		fmt.Printf("Unable to %s %s in %s: moderators and broadcasters can't be moderated\n", action, user.GetName(), channel.GetChannel())
+		return false
+	}
+
+	return true
+}
+
 func (b *Bot) Timeout(channel pkg.Channel, user pkg.User, duration int, reason string) {
-	if !user.IsModerator() {
-		b.Say(channel, fmt.Sprintf(".timeout %s %d %s", user.GetName(), duration, reason))
+	if !b.canModerate(channel, user, "time out") {
+		return
 	}
+
+	b.moderate(channel, user, pkg.ModerationActionTimeout, duration, reason)
+	b.Say(channel, fmt.Sprintf(".timeout %s %d %s", user.GetName(), duration, reason))
 }
 
 func (b *Bot) Ban(channel pkg.Channel, user pkg.User, reason string) {
-	if !user.IsModerator() {
-		b.Say(channel, fmt.Sprintf(".ban %s %s", user.GetName(), reason))
+	if !b.canModerate(channel, user, "ban") {
+		return
+	}
+
+	b.moderate(channel, user, pkg.ModerationActionBan, 0, reason)
+	b.Say(channel, fmt.Sprintf(".ban %s %s", user.GetName(), reason))
+}
+
+func (b *Bot) Delete(channel pkg.Channel, user pkg.User, messageID string) {
+	if messageID == "" || !b.canModerate(channel, user, "delete the message of") {
+		return
 	}
+
+	b.Say(channel, fmt.Sprintf(".delete %s", messageID))
 }
 
 func (b *Bot) Untimeout(channel pkg.Channel, user pkg.User) {
@@ -350,9 +405,19 @@ func (b *Bot) Untimeout(channel pkg.Channel, user pkg.User) {
 	}
 }
 
+// whisperContextHelp explains how to give a channel as context in a whisper
+const whisperContextHelp = "start your whisper with the name of the channel you want to use commands in, i.e. \"pajlada !pb2points\". whisper me \"help\" for commands that don't need a channel"
+
 func (b *Bot) HandleWhisper(user twitch.User, rawMessage twitch.Message) {
 	twitchUser := users.NewTwitchUser(user, rawMessage.Tags["user-id"])
 
+	modules.OnGlobalWhisper(b, twitchUser, NewTwitchMessage(rawMessage))
+
+	// Commands that are not related to a channel, i.e. help
+	if modules.HandleWhisper(b, twitchUser, NewTwitchMessage(rawMessage)) {
+		return
+	}
+
 	// Find out what bot channel this whisper is related to
 
 	parts := strings.Split(rawMessage.Text, " ")
@@ -361,34 +426,107 @@ func (b *Bot) HandleWhisper(user twitch.User, rawMessage twitch.Message) {
 	}
 
 	channelName := strings.ToLower(utils.FilterChannelName(parts[0]))
-	if channelName == "" {
-		// No valid channel name was given as context
-		// TODO: Pass through to some sort of "global modules"?
-		return
-	}
+	if channelName != "" {
+		if channelID := b.userStore.GetID(channelName); channelID != "" {
+			if _, botChannel := b.getBotChannel(channelID); botChannel != nil {
+				rawMessage.Text = strings.Join(parts[1:], " ")
+				b.forwardWhisper(botChannel, twitchUser, rawMessage)
+				return
+			}
+		}
 
-	channelID := b.userStore.GetID(channelName)
-	if channelID == "" {
-		// Context wasn't a valid channel name
+		// The user meant to give a channel as context, so the default channel would get a command it doesn't understand
+		b.Whisper(twitchUser, fmt.Sprintf("I'm not in a channel called %s. %s", channelName, whisperContextHelp))
 		return
 	}
 
-	_, botChannel := b.getBotChannel(channelID)
-	if botChannel == nil {
-		fmt.Println("Whisper received with context channel being", channelName, "without having a BotChannel there")
+	// No channel was given as context, i.e. "!pb2points". If the user only moderates one of our channels, that channel is assumed
+	if botChannel := b.defaultWhisperChannel(twitchUser); botChannel != nil {
+		b.forwardWhisper(botChannel, twitchUser, rawMessage)
 		return
 	}
 
-	rawMessage.Text = strings.Join(parts[1:], " ")
+	b.Whisper(twitchUser, "I don't know which channel you mean. "+whisperContextHelp)
+}
 
+func (b *Bot) forwardWhisper(botChannel *BotChannel, user pkg.User, rawMessage twitch.Message) {
 	message := NewTwitchMessage(rawMessage)
 
-	err := botChannel.handleWhisper(b, twitchUser, message)
+	err := botChannel.handleWhisper(b, user, message)
 	if err != nil {
 		fmt.Println("Error occured while forwarding whisper to bot channel:", err)
 	}
 }
 
+// trackModerator remembers whether the user moderates the given channel, based on the badges of their latest message there
+func (b *Bot) trackModerator(channel pkg.Channel, user pkg.User) {
+	isModerator := user.IsModerator() || user.IsBroadcaster(channel)
+
+	b.moderatorsMutex.Lock()
+	defer b.moderatorsMutex.Unlock()
+
+	channels := b.moderators[user.GetID()]
+
+	if !isModerator {
+		if channels != nil {
+			delete(channels, channel.GetID())
+		}
+
+		return
+	}
+
+	if channels == nil {
+		channels = make(map[string]bool)
+		b.moderators[user.GetID()] = channels
+	}
+
+	channels[channel.GetID()] = true
+}
+
+func (b *Bot) moderates(user pkg.User, botChannel *BotChannel) bool {
+	if user.GetID() == botChannel.ChannelID() {
+		return true
+	}
+
+	b.moderatorsMutex.Lock()
+	seenModerating := b.moderators[user.GetID()][botChannel.ChannelID()]
+	b.moderatorsMutex.Unlock()
+
+	if seenModerating {
+		return true
+	}
+
+	channel := &channels.TwitchChannel{
+		Channel: botChannel.ChannelName(),
+		ID:      botChannel.ChannelID(),
+	}
+
+	return user.HasChannelPermission(channel, pkg.PermissionModeration)
+}
+
+// defaultWhisperChannel returns the bot channel that the user moderates, or nil if they moderate none or several of our channels
+func (b *Bot) defaultWhisperChannel(user pkg.User) *BotChannel {
+	b.channelsMutex.Lock()
+	botChannels := append([]*BotChannel{}, b.channels...)
+	b.channelsMutex.Unlock()
+
+	var defaultChannel *BotChannel
+
+	for _, botChannel := range botChannels {
+		if !b.moderates(user, botChannel) {
+			continue
+		}
+
+		if defaultChannel != nil {
+			return nil
+		}
+
+		defaultChannel = botChannel
+	}
+
+	return defaultChannel
+}
+
 func (b *Bot) HandleMessage(channelName string, user twitch.User, rawMessage twitch.Message) {
 	message := NewTwitchMessage(rawMessage)
 
@@ -405,65 +543,109 @@ func (b *Bot) HandleMessage(channelName string, user twitch.User, rawMessage twi
 		User:    twitchUser,
 	}
 
-	for _, emote := range rawMessage.Emotes {
-		parsedEmote := &common.Emote{
-			Name:  emote.Name,
-			ID:    emote.ID,
-			Count: emote.Count,
-			Type:  "twitch",
-		}
-		message.twitchEmotes = append(message.twitchEmotes, parsedEmote)
-	}
-
 	_, botChannel := b.getBotChannel(channel.GetID())
 	if botChannel == nil {
 		fmt.Println("Message received in channel with id", channel.GetID(), "without having a BotChannel there")
 		return
 	}
 
-	err := botChannel.handleMessage(b, channel, twitchUser, message, action)
-	if err != nil {
-		fmt.Println("Error occured while forwarding message to bot channel:", err)
-	}
+	b.handleMessagePipeline(botChannel, channel, twitchUser, message, action)
 }
 
 func (b *Bot) HandleRoomstateMessage(channelName string, user twitch.User, rawMessage twitch.Message) {
+	channelID, ok := rawMessage.Tags["room-id"]
+	if !ok {
+		fmt.Println("room-id not set in roomstate message:", rawMessage.Raw)
+		return
+	}
+
 	if len(rawMessage.Tags) > 2 {
-		if channelID, ok := rawMessage.Tags["room-id"]; ok {
-			// Joined channel
-			b.streamStore.JoinStream(&SimpleAccount{channelID, channelName})
-		} else {
-			fmt.Println("room-id not set in roomstate message:", rawMessage.Raw)
-		}
+		// Joined channel
+		b.streamStore.JoinStream(&SimpleAccount{channelID, channelName})
+	}
+
+	_, botChannel := b.getBotChannel(channelID)
+	if botChannel == nil {
+		return
+	}
+
+	botChannel.handleRoomState(rawMessage.Tags)
+}
+
+// HandleUserNoticeMessage turns USERNOTICE messages, i.e. subs and raids, into events for the channels modules
+func (b *Bot) HandleUserNoticeMessage(channelName string, user twitch.User, rawMessage twitch.Message) {
+	event := parseUserNotice(user, rawMessage)
+	if event == nil {
+		return
 	}
-	subMode := ModeUnset
 
 	channel := &channels.TwitchChannel{
 		Channel: channelName,
+		ID:      rawMessage.Tags["room-id"],
 	}
 
-	if readSubMode, ok := rawMessage.Tags["subs-only"]; ok {
-		if readSubMode == "1" {
-			subMode = ModeEnabled
-		} else {
-			subMode = ModeDisabled
-		}
+	_, botChannel := b.getBotChannel(channel.GetID())
+	if botChannel == nil {
+		fmt.Println("Event received in channel with id", channel.GetID(), "without having a BotChannel there")
+		return
 	}
 
-	if subMode != ModeUnset {
-		if subMode == ModeEnabled {
-			// fmt.Println("Submode enabled")
-		} else {
-			// fmt.Println("Submode disabled")
+	botChannel.handleEvent(b, channel, event)
+}
 
-			if b.Flags.PermaSubMode {
-				b.Say(channel, "Perma sub mode is enabled. A mod can type !suboff to disable perma sub mode")
-				b.Say(channel, ".subscribers")
-			}
-		}
+// HandleClearChatMessage logs and publishes timeouts and bans, no matter who performed them
+func (b *Bot) HandleClearChatMessage(channelName string, user twitch.User, rawMessage twitch.Message) {
+	targetID, ok := rawMessage.Tags["target-user-id"]
+	if !ok {
+		// The whole chat was cleared
+		return
+	}
+
+	action := ModerationAction{
+		Channel: pkg.PubSubUser{
+			ID:   rawMessage.Tags["room-id"],
+			Name: channelName,
+		},
+		Target: pkg.PubSubUser{
+			ID:   targetID,
+			Name: rawMessage.Text,
+		},
+		Action: pkg.ModerationActionBan,
+	}
+
+	if banDuration, ok := rawMessage.Tags["ban-duration"]; ok {
+		action.Action = pkg.ModerationActionTimeout
+		action.Duration, _ = strconv.Atoi(banDuration)
 	}
 
-	// fmt.Printf("%s - #%s: %#v: %#v\n", b.Name(), channel, user, rawMessage)
+	HandleModerationAction(b.sql, b.userContext, b.pubSub, b, action)
+}
+
+// HandleUnsetMessage handles the messages that go-twitch-irc doesn't parse, i.e. CLEARMSG
+func (b *Bot) HandleUnsetMessage(line string) {
+	message := parseRawMessage(line)
+
+	switch message.Command {
+	case "CLEARMSG":
+		login := message.Tags["login"]
+
+		HandleModerationAction(b.sql, b.userContext, b.pubSub, b, ModerationAction{
+			Channel: pkg.PubSubUser{
+				ID:   b.userStore.GetID(message.Channel),
+				Name: message.Channel,
+			},
+			Target: pkg.PubSubUser{
+				ID:   b.userStore.GetID(login),
+				Name: login,
+			},
+			Action:      pkg.ModerationActionDelete,
+			MessageID:   message.Tags["target-msg-id"],
+			MessageText: message.Text,
+		})
+
+	default:
+		fmt.Println("Unparsed message:", line)
+	}
 }
 
 // Quit quits the entire application
@@ -737,10 +919,6 @@ func (b *Bot) PointRank(channel pkg.Channel, userID string) uint64 {
 	return rank
 }
 
-func FinalMiddleware(bot *Bot, channel pkg.Channel, user pkg.User, message *TwitchMessage, action pkg.Action) {
-	// fmt.Printf("Found %d BTTV emotes! %#v", len(message.BTTVEmotes), message.BTTVEmotes)
-}
-
 func (b *Bot) MakeUser(username string) pkg.User {
 	return users.NewTwitchUser(twitch.User{
 		Username:    username,
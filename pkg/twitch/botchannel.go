@@ -4,9 +4,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"runtime/debug"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pajlada/pajbot2/pkg"
 	"github.com/pajlada/pajbot2/pkg/modules"
@@ -15,6 +17,9 @@ import (
 
 var _ pkg.BotChannel = &BotChannel{}
 
+// After this many errors or panics in a row, a module is disabled until it's reloaded or enabled again
+const moduleFailureLimit = 10
+
 type BotChannel struct {
 	streamStore pkg.StreamStore
 
@@ -29,6 +34,30 @@ type BotChannel struct {
 	modules      []pkg.Module
 	modulesMutex sync.Mutex
 
+	// Number of messages or whispers currently being handled by the modules
+	handlingMessages int
+
+	// Changes to the enabled modules that were requested while handling a message. See changeModules
+	pendingModuleChanges []func() error
+
+	// Health of enabled and automatically disabled modules, by module ID
+	moduleHealth      map[string]*pkg.ModuleHealth
+	moduleHealthMutex sync.Mutex
+
+	// Chat settings as of the last ROOMSTATE message, and the modes we turn back on whenever they're turned off
+	roomState      pkg.RoomState
+	roomStateKnown bool
+	permaModes     []pkg.PermaMode
+	roomStateMutex sync.Mutex
+
+	// Decides which users the filters leave alone, unless a filter has its own policy
+	exemptionPolicy      pkg.ExemptionPolicy
+	exemptionPolicyMutex sync.Mutex
+
+	// The bot that joined the channel. Also used as the source of our pubsub events
+	bot    *Bot
+	pubSub pkg.PubSub
+
 	sql *sql.DB
 }
 
@@ -44,15 +73,58 @@ func (c *BotChannel) ChannelName() string {
 	return c.Channel.Name()
 }
 
+func (c *BotChannel) Bot() pkg.Sender {
+	return c.bot
+}
+
 func (c *BotChannel) Stream() pkg.Stream {
 	return c.streamStore.GetStream(&c.Channel)
 }
 
+// lessModuleSpec decides the order modules see messages in: by phase first, then by priority
+func lessModuleSpec(a, b pkg.ModuleSpec) bool {
+	if a.Phase() != b.Phase() {
+		return a.Phase() < b.Phase()
+	}
+
+	return a.Priority() < b.Priority()
+}
+
 // We assume that modulesMutex is locked already
+// Modules are sorted by phase and priority, and then moved so they run after the modules they depend on
 func (c *BotChannel) sortModules() {
-	sort.Slice(c.modules, func(i, j int) bool {
-		return c.modules[i].Spec().Priority() < c.modules[j].Spec().Priority()
+	sort.SliceStable(c.modules, func(i, j int) bool {
+		return lessModuleSpec(c.modules[i].Spec(), c.modules[j].Spec())
 	})
+
+	sorted := make([]pkg.Module, 0, len(c.modules))
+	placed := make(map[string]bool)
+
+	var place func(module pkg.Module)
+	place = func(module pkg.Module) {
+		spec := module.Spec()
+		if placed[spec.ID()] {
+			return
+		}
+
+		placed[spec.ID()] = true
+
+		for _, dependencyID := range spec.Dependencies() {
+			dependency := c.findModule(dependencyID)
+			// A dependency in an earlier phase already runs before this module
+			if dependency != nil && dependency.Spec().Phase() == spec.Phase() {
+				place(dependency)
+			}
+		}
+
+		sorted = append(sorted, module)
+	}
+
+	for _, module := range c.modules {
+		place(module)
+	}
+
+	c.modules = sorted
 }
 
 func (c *BotChannel) getSettingsForModule(moduleID string) ([]byte, error) {
@@ -79,18 +151,85 @@ WHERE
 	return []byte(s.String), nil
 }
 
-// We assume that modulesMutex is locked already
-func (c *BotChannel) enableModule(spec pkg.ModuleSpec, settings []byte) error {
+// initializeModule creates a new instance of the module with the given settings. The instance isn't enabled yet, see addModule
+func (c *BotChannel) initializeModule(spec pkg.ModuleSpec, settings []byte) (pkg.Module, error) {
 	module := spec.Maker()()
-	err := module.Initialize(c, settings)
+
+	panicked, err := safeModuleCall(func() error {
+		return module.Initialize(c, settings)
+	})
+	if panicked {
+		fmt.Printf("Module '%s' panicked while initializing: %s\n", spec.ID(), err)
+	}
 	if err != nil {
-		return errors.New(fmt.Sprintf("Error loading module '%s': %s\n", spec.ID(), err.Error()))
+		return nil, errors.New(fmt.Sprintf("Error loading module '%s': %s\n", spec.ID(), err.Error()))
 	}
 
+	return module, nil
+}
+
+// We assume that modulesMutex is locked already
+func (c *BotChannel) addModule(module pkg.Module) {
 	c.modules = append(c.modules, module)
 
 	c.sortModules()
 
+	c.resetModuleHealth(module.Spec().ID())
+}
+
+// We assume that modulesMutex is locked already
+func (c *BotChannel) enableModule(spec pkg.ModuleSpec, settings []byte) error {
+	module, err := c.initializeModule(spec, settings)
+	if err != nil {
+		return err
+	}
+
+	c.addModule(module)
+
+	return nil
+}
+
+// We assume that modulesMutex is locked already
+// Returns nil if the module isn't enabled
+func (c *BotChannel) findModule(moduleID string) pkg.Module {
+	for _, m := range c.modules {
+		if m.Spec().ID() == moduleID {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// We assume that modulesMutex is locked already
+// Returns the ID of an enabled module that depends on the given module, or an empty string if there is none
+func (c *BotChannel) findDependentModule(moduleID string) string {
+	for _, m := range c.modules {
+		for _, dependencyID := range m.Spec().Dependencies() {
+			if dependencyID == moduleID {
+				return m.Spec().ID()
+			}
+		}
+	}
+
+	return ""
+}
+
+// We assume that modulesMutex is locked already
+// Returns nil if the module isn't enabled
+func (c *BotChannel) removeModule(moduleID string) pkg.Module {
+	for i, m := range c.modules {
+		if m.Spec().ID() == moduleID {
+			c.modules = append(c.modules[:i], c.modules[i+1:]...)
+
+			if _, err := safeModuleCall(m.Disable); err != nil {
+				fmt.Printf("Error disabling module '%s': %s\n", moduleID, err)
+			}
+
+			return m
+		}
+	}
+
 	return nil
 }
 
@@ -109,7 +248,53 @@ ON DUPLICATE KEY UPDATE enabled=?`
 	return err
 }
 
+func (c *BotChannel) publishModuleEvent(topic string, moduleID string, autoDisabled bool) {
+	c.pubSub.Publish(c.bot, topic, &pkg.PubSubModuleEvent{
+		ChannelID:    c.ChannelID(),
+		BotChannelID: c.DatabaseID(),
+		ModuleID:     moduleID,
+		AutoDisabled: autoDisabled,
+	})
+}
+
+// changeModules serializes changes to the enabled modules with message handling.
+// check is called right away so the caller can be told if the change is invalid.
+// If the modules are in the middle of handling a message, i.e. a module is enabled by a chat command,
+// change is applied once every module has handled the message. Otherwise it's applied right away
+// Both check and change are called with modulesMutex locked
+func (c *BotChannel) changeModules(check, change func() error) error {
+	c.modulesMutex.Lock()
+	defer c.modulesMutex.Unlock()
+
+	if err := check(); err != nil {
+		return err
+	}
+
+	if c.handlingMessages > 0 {
+		c.pendingModuleChanges = append(c.pendingModuleChanges, func() error {
+			// Another pending change might have made this one invalid
+			if err := check(); err != nil {
+				return err
+			}
+
+			return change()
+		})
+		return nil
+	}
+
+	return change()
+}
+
 // We assume that modulesMutex is locked already
+func (c *BotChannel) checkModuleEnabled(moduleID string) error {
+	if c.findModule(moduleID) == nil && !c.moduleAutoDisabled(moduleID) {
+		return errors.New("module isn't enabled")
+	}
+
+	return nil
+}
+
+// EnableModule enables the module and remembers that it's enabled
 func (c *BotChannel) EnableModule(moduleID string) error {
 	moduleID = strings.ToLower(moduleID)
 
@@ -118,51 +303,203 @@ func (c *BotChannel) EnableModule(moduleID string) error {
 		return errors.New("invalid module id")
 	}
 
-	// Check if module is enabled already
-
-	for _, m := range c.modules {
-		if m.Spec().ID() == moduleID {
+	check := func() error {
+		if c.findModule(moduleID) != nil {
 			return errors.New("module already enabled")
 		}
+
+		for _, dependencyID := range spec.Dependencies() {
+			if c.findModule(dependencyID) == nil {
+				return fmt.Errorf("module requires module %s to be enabled", dependencyID)
+			}
+		}
+
+		return nil
 	}
 
-	// Save enabled state
-	if err := c.setModuleEnabledState(moduleID, utils.BoolPtr(true)); err != nil {
-		return err
+	return c.changeModules(check, func() error {
+		// Save enabled state
+		if err := c.setModuleEnabledState(moduleID, utils.BoolPtr(true)); err != nil {
+			return err
+		}
+
+		settings, err := c.getSettingsForModule(moduleID)
+		if err != nil {
+			return err
+		}
+
+		if err = c.enableModule(spec, settings); err != nil {
+			return err
+		}
+
+		c.publishModuleEvent("ModuleEnabled", moduleID, false)
+
+		return nil
+	})
+}
+
+// DisableModule disables the module and remembers that it's disabled
+func (c *BotChannel) DisableModule(moduleID string) error {
+	moduleID = strings.ToLower(moduleID)
+
+	_, ok := modules.GetModule(moduleID)
+	if !ok {
+		return errors.New("invalid module id")
+	}
+
+	check := func() error {
+		if err := c.checkModuleEnabled(moduleID); err != nil {
+			return err
+		}
+
+		if dependentID := c.findDependentModule(moduleID); dependentID != "" {
+			return fmt.Errorf("module %s depends on this module, disable it first", dependentID)
+		}
+
+		return nil
 	}
 
-	settings, err := c.getSettingsForModule(moduleID)
+	return c.changeModules(check, func() error {
+		c.removeModule(moduleID)
+
+		c.moduleHealthMutex.Lock()
+		delete(c.moduleHealth, moduleID)
+		c.moduleHealthMutex.Unlock()
+
+		// Save disabled state
+		if err := c.setModuleEnabledState(moduleID, utils.BoolPtr(false)); err != nil {
+			return err
+		}
+
+		c.publishModuleEvent("ModuleDisabled", moduleID, false)
+
+		return nil
+	})
+}
+
+// We assume that modulesMutex is locked already
+// The new instance is initialized before it replaces the running one, so if it fails to load the module keeps running as it was
+func (c *BotChannel) reloadModule(spec pkg.ModuleSpec, settings []byte) error {
+	module, err := c.initializeModule(spec, settings)
 	if err != nil {
 		return err
 	}
 
-	return c.enableModule(spec, settings)
+	c.replaceModule(module)
+
+	return nil
 }
 
 // We assume that modulesMutex is locked already
-func (c *BotChannel) DisableModule(moduleID string) error {
+func (c *BotChannel) replaceModule(module pkg.Module) {
+	moduleID := module.Spec().ID()
+
+	if c.removeModule(moduleID) != nil {
+		c.publishModuleEvent("ModuleDisabled", moduleID, false)
+	}
+
+	c.addModule(module)
+
+	c.publishModuleEvent("ModuleEnabled", moduleID, false)
+}
+
+// ReloadModule disables the module and enables it again with the settings currently stored in the database.
+// Modules that were disabled automatically because they kept failing are enabled again
+func (c *BotChannel) ReloadModule(moduleID string) error {
 	moduleID = strings.ToLower(moduleID)
 
-	_, ok := modules.GetModule(moduleID)
+	spec, ok := modules.GetModule(moduleID)
 	if !ok {
 		return errors.New("invalid module id")
 	}
 
-	for i, m := range c.modules {
-		if m.Spec().ID() == moduleID {
-			m.Disable()
-			c.modules = append(c.modules[:i], c.modules[i+1:]...)
+	check := func() error {
+		return c.checkModuleEnabled(moduleID)
+	}
 
-			// Save disabled state
-			if err := c.setModuleEnabledState(moduleID, utils.BoolPtr(false)); err != nil {
-				return err
+	return c.changeModules(check, func() error {
+		settings, err := c.getSettingsForModule(moduleID)
+		if err != nil {
+			return err
+		}
+
+		return c.reloadModule(spec, settings)
+	})
+}
+
+// ConfigureModule stores new settings for the module. If the module is enabled, it's reloaded with the new settings
+func (c *BotChannel) ConfigureModule(moduleID string, settings []byte) error {
+	moduleID = strings.ToLower(moduleID)
+
+	spec, ok := modules.GetModule(moduleID)
+	if !ok {
+		return errors.New("invalid module id")
+	}
+
+	if err := modules.ValidateSettings(spec, settings); err != nil {
+		return err
+	}
+
+	check := func() error {
+		return nil
+	}
+
+	return c.changeModules(check, func() error {
+		if c.findModule(moduleID) == nil {
+			return c.saveModuleSettings(moduleID, settings)
+		}
+
+		// The settings are only stored once we know the module loads with them
+		module, err := c.initializeModule(spec, settings)
+		if err != nil {
+			return err
+		}
+
+		if err = c.saveModuleSettings(moduleID, settings); err != nil {
+			if _, disableErr := safeModuleCall(module.Disable); disableErr != nil {
+				fmt.Printf("Error disabling module '%s': %s\n", moduleID, disableErr)
 			}
 
-			return nil
+			return err
 		}
+
+		c.replaceModule(module)
+
+		return nil
+	})
+}
+
+func (c *BotChannel) saveModuleSettings(moduleID string, settings []byte) error {
+	const queryF = `
+INSERT INTO
+	BotChannelModule
+	(bot_channel_id, module_id, settings)
+	VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE settings=?`
+
+	_, err := c.sql.Exec(queryF, c.DatabaseID(), moduleID, settings, settings)
+	return err
+}
+
+// Modules returns all available modules sorted by phase and priority, and whether they're enabled in this channel
+func (c *BotChannel) Modules() []pkg.ModuleStatus {
+	c.modulesMutex.Lock()
+	defer c.modulesMutex.Unlock()
+
+	var statuses []pkg.ModuleStatus
+
+	for _, spec := range modules.Modules() {
+		statuses = append(statuses, pkg.ModuleStatus{
+			Spec:    spec,
+			Enabled: c.findModule(spec.ID()) != nil,
+		})
 	}
 
-	return errors.New("module isn't enabled")
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return lessModuleSpec(statuses[i].Spec, statuses[j].Spec)
+	})
+
+	return statuses
 }
 
 func (c *BotChannel) Initialize(b *Bot) error {
@@ -172,9 +509,22 @@ func (c *BotChannel) Initialize(b *Bot) error {
 
 	c.sql = b.sql
 	c.streamStore = b.streamStore
+	c.bot = b
+	c.pubSub = b.pubSub
+
+	c.roomState.FollowersOnly = -1
+	c.exemptionPolicy = pkg.DefaultExemptionPolicy()
 
 	c.initialized = true
 
+	if err := c.loadPermaModes(); err != nil {
+		fmt.Printf("Error loading perma modes in channel %s: %s\n", c.ChannelName(), err)
+	}
+
+	if err := c.loadExemptionPolicy(); err != nil {
+		fmt.Printf("Error loading exemption policy in channel %s: %s\n", c.ChannelName(), err)
+	}
+
 	c.loadModules()
 
 	return nil
@@ -229,6 +579,9 @@ func (c *BotChannel) loadModules() {
 	c.modulesMutex.Lock()
 	defer c.modulesMutex.Unlock()
 
+	var enabledSpecs []pkg.ModuleSpec
+	enabledSettings := make(map[string][]byte)
+
 	for _, spec := range availableModules {
 		enabled := spec.EnabledByDefault()
 		var settings []byte
@@ -251,23 +604,187 @@ func (c *BotChannel) loadModules() {
 		}
 
 		if enabled {
-			c.enableModule(spec, settings)
+			enabledSpecs = append(enabledSpecs, spec)
+			enabledSettings[spec.ID()] = settings
 		}
 		// Fetch config for this module from SQL
 	}
+
+	for _, spec := range enabledSpecs {
+		if dependencyID := missingDependency(spec, enabledSpecs); dependencyID != "" {
+			fmt.Printf("Not enabling module '%s' in channel %s: it requires module '%s' to be enabled\n", spec.ID(), c.ChannelName(), dependencyID)
+			continue
+		}
+
+		c.enableModule(spec, enabledSettings[spec.ID()])
+	}
+}
+
+// missingDependency returns the ID of the first dependency of spec that's not in enabledSpecs, or an empty string if all of them are
+func missingDependency(spec pkg.ModuleSpec, enabledSpecs []pkg.ModuleSpec) string {
+	for _, dependencyID := range spec.Dependencies() {
+		found := false
+		for _, enabledSpec := range enabledSpecs {
+			if enabledSpec.ID() == dependencyID {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return dependencyID
+		}
+	}
+
+	return ""
+}
+
+// safeModuleCall calls cb, turning a panic into an error so a broken module can't bring down the bot
+func safeModuleCall(cb func() error) (panicked bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("Recovered from module panic: %v\n%s\n", r, debug.Stack())
+			panicked = true
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return false, cb()
+}
+
+func (c *BotChannel) resetModuleHealth(moduleID string) {
+	c.moduleHealthMutex.Lock()
+	defer c.moduleHealthMutex.Unlock()
+
+	if c.moduleHealth == nil {
+		c.moduleHealth = make(map[string]*pkg.ModuleHealth)
+	}
+
+	c.moduleHealth[moduleID] = &pkg.ModuleHealth{
+		ModuleID: moduleID,
+	}
 }
 
-func (c *BotChannel) onModules(cb func(module pkg.Module) error) (err error) {
+func (c *BotChannel) moduleAutoDisabled(moduleID string) bool {
+	c.moduleHealthMutex.Lock()
+	defer c.moduleHealthMutex.Unlock()
+
+	health, ok := c.moduleHealth[moduleID]
+	return ok && health.AutoDisabled
+}
+
+// recordModuleResult updates the health of the module. Returns true if the module has failed too many times in a row and should be disabled
+func (c *BotChannel) recordModuleResult(moduleID string, panicked bool, err error) bool {
+	c.moduleHealthMutex.Lock()
+	defer c.moduleHealthMutex.Unlock()
+
+	health, ok := c.moduleHealth[moduleID]
+	if !ok {
+		return false
+	}
+
+	if err == nil {
+		health.ConsecutiveFailures = 0
+		return false
+	}
+
+	now := time.Now()
+
+	health.Errors++
+	if panicked {
+		health.Panics++
+	}
+	health.ConsecutiveFailures++
+	health.LastError = err.Error()
+	health.LastErrorTime = &now
+
+	if health.ConsecutiveFailures >= moduleFailureLimit {
+		health.AutoDisabled = true
+		return true
+	}
+
+	return false
+}
+
+// ModuleHealth returns the health of all enabled modules, and of the modules that have been disabled automatically
+func (c *BotChannel) ModuleHealth() []pkg.ModuleHealth {
+	c.moduleHealthMutex.Lock()
+	defer c.moduleHealthMutex.Unlock()
+
+	var health []pkg.ModuleHealth
+	for _, h := range c.moduleHealth {
+		health = append(health, *h)
+	}
+
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].ModuleID < health[j].ModuleID
+	})
+
+	return health
+}
+
+// onModules calls cb for each enabled module. A module that fails doesn't stop the message from reaching the other modules
+func (c *BotChannel) onModules(cb func(module pkg.Module) error) {
 	c.modulesMutex.Lock()
-	defer c.modulesMutex.Unlock()
+	c.handlingMessages++
+	// Modules may enable or disable modules while handling the message, so we iterate over a copy. See changeModules
+	enabledModules := append([]pkg.Module{}, c.modules...)
+	c.modulesMutex.Unlock()
 
-	for _, module := range c.modules {
-		if err = cb(module); err != nil {
+	var failingModules []string
+
+	defer func() {
+		c.modulesMutex.Lock()
+		defer c.modulesMutex.Unlock()
+
+		c.handlingMessages--
+
+		for _, moduleID := range failingModules {
+			fmt.Printf("Module '%s' failed %d times in a row in channel %s, disabling it until it's reloaded\n", moduleID, moduleFailureLimit, c.ChannelName())
+			if c.removeModule(moduleID) != nil {
+				c.publishModuleEvent("ModuleDisabled", moduleID, true)
+			}
+		}
+
+		if c.handlingMessages > 0 {
 			return
 		}
-	}
 
-	return
+		pendingModuleChanges := c.pendingModuleChanges
+		c.pendingModuleChanges = nil
+
+		for _, change := range pendingModuleChanges {
+			if err := change(); err != nil {
+				fmt.Printf("Error changing modules in channel %s: %s\n", c.ChannelName(), err)
+			}
+		}
+	}()
+
+	propagationStopped := false
+
+	for _, module := range enabledModules {
+		moduleID := module.Spec().ID()
+
+		// Only command modules are skipped. Later filters may still pick a stronger action, and act modules need to react to it
+		if propagationStopped && module.Spec().Phase() == pkg.ModulePhaseCommand {
+			continue
+		}
+
+		panicked, err := safeModuleCall(func() error {
+			return cb(module)
+		})
+		if err == pkg.ErrStopPropagation {
+			propagationStopped = true
+			err = nil
+		}
+		if err != nil {
+			fmt.Printf("Error in module '%s' in channel %s: %s\n", moduleID, c.ChannelName(), err)
+		}
+
+		if c.recordModuleResult(moduleID, panicked, err) {
+			failingModules = append(failingModules, moduleID)
+		}
+	}
 }
 
 func (c *BotChannel) handleMessage(bot pkg.Sender, channel pkg.Channel, user pkg.User, message *TwitchMessage, action pkg.Action) error {
@@ -275,14 +792,32 @@ func (c *BotChannel) handleMessage(bot pkg.Sender, channel pkg.Channel, user pkg
 		return errors.New("channel may not be nil")
 	}
 
-	return c.onModules(func(module pkg.Module) error {
-		return module.OnMessage(bot, channel, user, message, action)
+	exempt := c.exemptionChecker(channel, user)
+
+	c.onModules(func(module pkg.Module) error {
+		if exempt(module) {
+			if handler, ok := module.(pkg.ExemptMessageHandler); ok {
+				return handler.OnExemptMessage(bot, channel, user, message)
+			}
+
+			return nil
+		}
+
+		return module.OnMessage(bot, channel, user, message, pkg.ActionFromSource(action, module.Spec().ID()))
+	})
+
+	return nil
+}
+
+func (c *BotChannel) handleEvent(bot pkg.Sender, channel pkg.Channel, event pkg.Event) {
+	c.onModules(func(module pkg.Module) error {
+		return module.OnEvent(bot, channel, event)
 	})
 }
 
 func (c *BotChannel) handleWhisper(bot pkg.Sender, user pkg.User, message *TwitchMessage) error {
 	fmt.Println("handle whisper", message.GetText())
-	return c.onModules(func(module pkg.Module) error {
+	c.onModules(func(module pkg.Module) error {
 		return module.OnWhisper(bot, user, message)
 	})
 
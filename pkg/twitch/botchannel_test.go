@@ -0,0 +1,251 @@
+package twitch
+
+import (
+	"errors"
+	"testing"
+
+	twitch "github.com/gempir/go-twitch-irc"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/channels"
+	"github.com/pajlada/pajbot2/pkg/users"
+)
+
+func TestSafeModuleCall(t *testing.T) {
+	panicked, err := safeModuleCall(func() error {
+		panic("xd")
+	})
+	if !panicked || err == nil {
+		t.Fatalf("Panic should be recovered and returned as an error, got %v %v", panicked, err)
+	}
+
+	panicked, err = safeModuleCall(func() error {
+		return errors.New("xd")
+	})
+	if panicked || err == nil {
+		t.Fatalf("Error should be returned without a panic, got %v %v", panicked, err)
+	}
+}
+
+func TestRecordModuleResult(t *testing.T) {
+	c := &BotChannel{}
+	c.resetModuleHealth("test")
+
+	for i := 1; i < moduleFailureLimit; i++ {
+		if c.recordModuleResult("test", false, errors.New("xd")) {
+			t.Fatalf("Module should not be disabled after %d failures", i)
+		}
+	}
+
+	// A success resets the consecutive failures
+	c.recordModuleResult("test", false, nil)
+
+	for i := 1; i < moduleFailureLimit; i++ {
+		c.recordModuleResult("test", false, errors.New("xd"))
+	}
+
+	if !c.recordModuleResult("test", true, errors.New("xd")) {
+		t.Fatalf("Module should be disabled after %d failures in a row", moduleFailureLimit)
+	}
+
+	health := c.ModuleHealth()
+	if len(health) != 1 || !health[0].AutoDisabled || health[0].Panics != 1 || health[0].Errors != 2*uint64(moduleFailureLimit)-1 {
+		t.Errorf("Unexpected module health: %+v", health)
+	}
+}
+
+func TestChangeModulesWhileHandlingMessage(t *testing.T) {
+	c := &BotChannel{}
+
+	applied := false
+	check := func() error {
+		return nil
+	}
+	change := func() error {
+		applied = true
+		return nil
+	}
+
+	c.handlingMessages = 1
+	c.changeModules(check, change)
+	c.handlingMessages = 0
+
+	if applied {
+		t.Fatalf("Change should not be applied while a message is being handled")
+	}
+
+	c.onModules(func(module pkg.Module) error {
+		return nil
+	})
+
+	if !applied {
+		t.Errorf("Change should be applied once the message has been handled")
+	}
+
+	if err := c.changeModules(func() error { return errors.New("xd") }, change); err == nil {
+		t.Errorf("Error from check should be returned")
+	}
+}
+
+type testModuleSpec struct {
+	id           string
+	phase        pkg.ModulePhase
+	priority     int
+	dependencies []string
+	maker        pkg.ModuleMaker
+}
+
+func (s *testModuleSpec) ID() string             { return s.id }
+func (s *testModuleSpec) Name() string           { return s.id }
+func (s *testModuleSpec) EnabledByDefault() bool { return false }
+func (s *testModuleSpec) Maker() pkg.ModuleMaker { return s.maker }
+func (s *testModuleSpec) Priority() int          { return s.priority }
+func (s *testModuleSpec) Phase() pkg.ModulePhase { return s.phase }
+func (s *testModuleSpec) Dependencies() []string { return s.dependencies }
+
+type testModule struct {
+	spec *testModuleSpec
+}
+
+func (m *testModule) Initialize(pkg.BotChannel, []byte) error { return nil }
+func (m *testModule) Disable() error                          { return nil }
+func (m *testModule) Spec() pkg.ModuleSpec                    { return m.spec }
+func (m *testModule) BotChannel() pkg.BotChannel              { return nil }
+func (m *testModule) OnWhisper(bot pkg.Sender, source pkg.User, message pkg.Message) error {
+	return nil
+}
+
+func (m *testModule) OnEvent(bot pkg.Sender, source pkg.Channel, event pkg.Event) error {
+	return nil
+}
+func (m *testModule) OnMessage(bot pkg.Sender, source pkg.Channel, user pkg.User, message pkg.Message, action pkg.Action) error {
+	return nil
+}
+
+func moduleIDs(modules []pkg.Module) (ids []string) {
+	for _, m := range modules {
+		ids = append(ids, m.Spec().ID())
+	}
+
+	return
+}
+
+func TestSortModules(t *testing.T) {
+	c := &BotChannel{}
+	c.modules = []pkg.Module{
+		&testModule{&testModuleSpec{id: "command", phase: pkg.ModulePhaseCommand}},
+		&testModule{&testModuleSpec{id: "act", phase: pkg.ModulePhaseAct}},
+		&testModule{&testModuleSpec{id: "filter_dependent", phase: pkg.ModulePhaseFilter, priority: -10, dependencies: []string{"filter", "parse"}}},
+		&testModule{&testModuleSpec{id: "filter", phase: pkg.ModulePhaseFilter}},
+		&testModule{&testModuleSpec{id: "parse", phase: pkg.ModulePhaseParse, priority: 100}},
+	}
+
+	c.sortModules()
+
+	expected := []string{"parse", "filter", "filter_dependent", "act", "command"}
+	ids := moduleIDs(c.modules)
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected modules %v, got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("Expected modules %v, got %v", expected, ids)
+		}
+	}
+}
+
+func TestStopPropagation(t *testing.T) {
+	c := &BotChannel{}
+	c.modules = []pkg.Module{
+		&testModule{&testModuleSpec{id: "filter", phase: pkg.ModulePhaseFilter}},
+		&testModule{&testModuleSpec{id: "filter2", phase: pkg.ModulePhaseFilter}},
+		&testModule{&testModuleSpec{id: "act", phase: pkg.ModulePhaseAct}},
+		&testModule{&testModuleSpec{id: "command", phase: pkg.ModulePhaseCommand}},
+	}
+	c.resetModuleHealth("filter")
+
+	var called []string
+	c.onModules(func(module pkg.Module) error {
+		called = append(called, module.Spec().ID())
+		if module.Spec().ID() == "filter" {
+			return pkg.ErrStopPropagation
+		}
+		return nil
+	})
+
+	if len(called) != 3 || called[0] != "filter" || called[1] != "filter2" || called[2] != "act" {
+		t.Errorf("Only the command modules should be skipped, got %v", called)
+	}
+
+	if health := c.ModuleHealth(); len(health) != 1 || health[0].Errors != 0 {
+		t.Errorf("Stopping propagation should not count as a failure: %+v", health)
+	}
+}
+
+type testExemptModule struct {
+	testModule
+
+	policy *pkg.ExemptionPolicy
+}
+
+func (m *testExemptModule) ExemptionPolicyOverride() *pkg.ExemptionPolicy {
+	return m.policy
+}
+
+func TestExemptionChecker(t *testing.T) {
+	c := &BotChannel{}
+	c.exemptionPolicy = pkg.DefaultExemptionPolicy()
+
+	channel := &channels.TwitchChannel{Channel: "pajlada", ID: "11148817"}
+	moderator := users.NewTwitchUser(twitch.User{Username: "testman", UserType: "mod"}, "1")
+
+	filter := &testModule{&testModuleSpec{id: "filter", phase: pkg.ModulePhaseFilter}}
+	command := &testModule{&testModuleSpec{id: "command", phase: pkg.ModulePhaseCommand}}
+	strictFilter := &testExemptModule{
+		testModule: testModule{&testModuleSpec{id: "strict_filter", phase: pkg.ModulePhaseFilter}},
+		policy:     &pkg.ExemptionPolicy{},
+	}
+	defaultFilter := &testExemptModule{
+		testModule: testModule{&testModuleSpec{id: "default_filter", phase: pkg.ModulePhaseFilter}},
+	}
+
+	exempt := c.exemptionChecker(channel, moderator)
+
+	if !exempt(filter) || !exempt(defaultFilter) {
+		t.Error("Moderators should be exempt from filters by default")
+	}
+
+	if exempt(command) {
+		t.Error("Exemptions should only apply to filters")
+	}
+
+	if exempt(strictFilter) {
+		t.Error("The policy of the filter should replace the policy of the channel")
+	}
+}
+
+type testFailingModule struct {
+	testModule
+}
+
+func (m *testFailingModule) Initialize(pkg.BotChannel, []byte) error { return errors.New("xd") }
+
+func TestReloadModuleFailure(t *testing.T) {
+	spec := &testModuleSpec{id: "filter", phase: pkg.ModulePhaseFilter}
+	spec.maker = func() pkg.Module {
+		return &testFailingModule{testModule{spec}}
+	}
+
+	running := &testModule{spec}
+
+	c := &BotChannel{}
+	c.modules = []pkg.Module{running}
+	c.resetModuleHealth("filter")
+
+	if err := c.reloadModule(spec, nil); err == nil {
+		t.Fatal("Reloading should fail when the new instance fails to initialize")
+	}
+
+	if len(c.modules) != 1 || c.modules[0] != running {
+		t.Errorf("The running module should be kept when reloading fails, got %v", moduleIDs(c.modules))
+	}
+}
@@ -0,0 +1,83 @@
+package twitch
+
+import (
+	"strconv"
+
+	twitch "github.com/gempir/go-twitch-irc"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/users"
+)
+
+// tagInt returns the value of a numeric tag, or 0 if the tag is missing or not a number
+func tagInt(tags map[string]string, tag string) int {
+	value, _ := strconv.Atoi(tags[tag])
+	return value
+}
+
+// parseUserNotice turns a USERNOTICE message into an event. Returns nil for kinds of USERNOTICE messages we don't handle
+func parseUserNotice(user twitch.User, rawMessage twitch.Message) pkg.Event {
+	tags := rawMessage.Tags
+
+	eventUser := users.NewTwitchUser(user, tags["user-id"])
+
+	switch tags["msg-id"] {
+	case "sub", "resub":
+		months := tagInt(tags, "msg-param-cumulative-months")
+		if months == 0 {
+			// Older resub messages only have msg-param-months
+			months = tagInt(tags, "msg-param-months")
+		}
+
+		event := &pkg.SubEvent{
+			User:             eventUser,
+			Resub:            tags["msg-id"] == "resub",
+			Plan:             tags["msg-param-sub-plan"],
+			CumulativeMonths: months,
+			Message:          rawMessage.Text,
+		}
+
+		if tags["msg-param-should-share-streak"] == "1" {
+			event.StreakMonths = tagInt(tags, "msg-param-streak-months")
+		}
+
+		return event
+
+	case "subgift", "anonsubgift":
+		recipient := users.NewSimpleTwitchUser(tags["msg-param-recipient-id"], tags["msg-param-recipient-user-name"])
+		if displayName := tags["msg-param-recipient-display-name"]; displayName != "" {
+			recipient.DisplayName = displayName
+		}
+
+		return &pkg.SubGiftEvent{
+			User:      eventUser,
+			Anonymous: tags["msg-id"] == "anonsubgift" || user.Username == "ananonymousgifter",
+			Recipient: recipient,
+			Plan:      tags["msg-param-sub-plan"],
+			Months:    tagInt(tags, "msg-param-months"),
+		}
+
+	case "submysterygift", "anonsubmysterygift":
+		return &pkg.MassSubGiftEvent{
+			User:        eventUser,
+			Anonymous:   tags["msg-id"] == "anonsubmysterygift" || user.Username == "ananonymousgifter",
+			Plan:        tags["msg-param-sub-plan"],
+			Count:       tagInt(tags, "msg-param-mass-gift-count"),
+			SenderTotal: tagInt(tags, "msg-param-sender-count"),
+		}
+
+	case "raid":
+		return &pkg.RaidEvent{
+			User:    eventUser,
+			Viewers: tagInt(tags, "msg-param-viewerCount"),
+		}
+
+	case "ritual":
+		return &pkg.RitualEvent{
+			User:    eventUser,
+			Name:    tags["msg-param-ritual-name"],
+			Message: rawMessage.Text,
+		}
+	}
+
+	return nil
+}
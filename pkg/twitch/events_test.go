@@ -0,0 +1,72 @@
+package twitch
+
+import (
+	"testing"
+
+	twitch "github.com/gempir/go-twitch-irc"
+	"github.com/pajlada/pajbot2/pkg"
+)
+
+func TestParseUserNotice(t *testing.T) {
+	user := twitch.User{
+		Username:    "pajlada",
+		DisplayName: "pajlada",
+	}
+
+	event := parseUserNotice(user, twitch.Message{
+		Tags: map[string]string{
+			"msg-id":                        "resub",
+			"user-id":                       "11148817",
+			"msg-param-cumulative-months":   "12",
+			"msg-param-should-share-streak": "1",
+			"msg-param-streak-months":       "3",
+			"msg-param-sub-plan":            "Prime",
+		},
+		Text: "forsenE",
+	})
+
+	sub, ok := event.(*pkg.SubEvent)
+	if !ok {
+		t.Fatalf("Expected a sub event, got %#v", event)
+	}
+
+	if sub.Type() != pkg.EventTypeResub || sub.CumulativeMonths != 12 || sub.StreakMonths != 3 || sub.Message != "forsenE" {
+		t.Errorf("Unexpected sub event %+v", sub)
+	}
+
+	if sub.GetUser().GetID() != "11148817" {
+		t.Errorf("Expected user ID 11148817, got %s", sub.GetUser().GetID())
+	}
+
+	event = parseUserNotice(user, twitch.Message{
+		Tags: map[string]string{
+			"msg-id":                        "subgift",
+			"msg-param-recipient-id":        "123",
+			"msg-param-recipient-user-name": "forsen",
+		},
+	})
+
+	gift, ok := event.(*pkg.SubGiftEvent)
+	if !ok {
+		t.Fatalf("Expected a gift event, got %#v", event)
+	}
+
+	if gift.Anonymous || gift.Recipient.GetID() != "123" || gift.Recipient.GetName() != "forsen" {
+		t.Errorf("Unexpected gift event %+v", gift)
+	}
+
+	event = parseUserNotice(user, twitch.Message{
+		Tags: map[string]string{
+			"msg-id":                "raid",
+			"msg-param-viewerCount": "1337",
+		},
+	})
+
+	if raid, ok := event.(*pkg.RaidEvent); !ok || raid.Viewers != 1337 {
+		t.Errorf("Unexpected raid event %#v", event)
+	}
+
+	if event = parseUserNotice(user, twitch.Message{Tags: map[string]string{"msg-id": "charity"}}); event != nil {
+		t.Errorf("Expected no event for unknown msg-id, got %#v", event)
+	}
+}
@@ -0,0 +1,97 @@
+package twitch
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/pajlada/pajbot2/pkg"
+)
+
+func (c *BotChannel) loadExemptionPolicy() error {
+	const queryF = `SELECT policy FROM BotChannelExemptionPolicy WHERE bot_channel_id=?`
+
+	policy := pkg.DefaultExemptionPolicy()
+
+	var bytes []byte
+	err := c.sql.QueryRow(queryF, c.DatabaseID()).Scan(&bytes)
+	switch err {
+	case nil:
+		if err = json.Unmarshal(bytes, &policy); err != nil {
+			return err
+		}
+
+	case sql.ErrNoRows:
+
+	default:
+		return err
+	}
+
+	c.exemptionPolicyMutex.Lock()
+	c.exemptionPolicy = policy
+	c.exemptionPolicyMutex.Unlock()
+
+	return nil
+}
+
+// ExemptionPolicy returns the policy that decides which users the filters of the channel leave alone
+func (c *BotChannel) ExemptionPolicy() pkg.ExemptionPolicy {
+	c.exemptionPolicyMutex.Lock()
+	defer c.exemptionPolicyMutex.Unlock()
+
+	return c.exemptionPolicy
+}
+
+func (c *BotChannel) SetExemptionPolicy(policy pkg.ExemptionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	bytes, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+
+	const queryF = `
+INSERT INTO
+	BotChannelExemptionPolicy
+	(bot_channel_id, policy)
+	VALUES (?, ?)
+ON DUPLICATE KEY UPDATE policy=?`
+
+	if _, err := c.sql.Exec(queryF, c.DatabaseID(), bytes, bytes); err != nil {
+		return err
+	}
+
+	c.exemptionPolicyMutex.Lock()
+	c.exemptionPolicy = policy
+	c.exemptionPolicyMutex.Unlock()
+
+	return nil
+}
+
+// exemptionChecker returns a function that tells if a filter module should leave the user alone.
+// Most filters use the policy of the channel, so it's only evaluated once per message
+func (c *BotChannel) exemptionChecker(channel pkg.Channel, user pkg.User) func(module pkg.Module) bool {
+	channelPolicy := c.ExemptionPolicy()
+	channelExempt := false
+	channelChecked := false
+
+	return func(module pkg.Module) bool {
+		if module.Spec().Phase() != pkg.ModulePhaseFilter {
+			return false
+		}
+
+		if overrider, ok := module.(pkg.ExemptionPolicyOverrider); ok {
+			if policy := overrider.ExemptionPolicyOverride(); policy != nil {
+				return policy.Exempt(channel, user)
+			}
+		}
+
+		if !channelChecked {
+			channelExempt = channelPolicy.Exempt(channel, user)
+			channelChecked = true
+		}
+
+		return channelExempt
+	}
+}
@@ -0,0 +1,246 @@
+package twitch
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg"
+)
+
+// The same moderation action can reach us several times: through the CLEARCHAT message that each of our bots in the channel receives,
+// through the Twitch PubSub moderation action listener, and from the bot itself when it times out or bans someone.
+// Reports of the same action within this window are only handled once
+const moderationActionWindow = 10 * time.Second
+
+// ModerationAction describes a timeout, ban, unban or message deletion
+type ModerationAction struct {
+	Channel pkg.PubSubUser
+
+	// The moderator that performed the action. Empty if we don't know, i.e. for actions we learned about through CLEARCHAT
+	Source pkg.PubSubUser
+
+	Target pkg.PubSubUser
+
+	// One of the pkg.ModerationAction constants
+	Action int
+
+	// Only set for timeouts
+	Duration int
+
+	Reason string
+
+	// Only set for message deletions
+	MessageID   string
+	MessageText string
+}
+
+type trackedModerationAction struct {
+	databaseID int64
+
+	// The moderator we logged, or learned about while the action was still being logged
+	sourceID string
+
+	seen time.Time
+}
+
+// expectedModerationAction is a timeout or ban that one of our bots has sent, but that Twitch hasn't confirmed yet
+type expectedModerationAction struct {
+	source pkg.PubSubUser
+	reason string
+
+	sent time.Time
+}
+
+var (
+	recentModerationActionsMutex sync.Mutex
+	recentModerationActions      = make(map[string]*trackedModerationAction)
+	expectedModerationActions    = make(map[string]*expectedModerationAction)
+)
+
+func (a *ModerationAction) key() string {
+	return fmt.Sprintf("%s:%s:%d:%d:%s", a.Channel.ID, a.Target.ID, a.Action, a.Duration, a.MessageID)
+}
+
+// trackModerationAction returns the earlier report of the same action, or nil if this is the first time we hear about it
+// We assume that recentModerationActionsMutex is locked already
+func trackModerationAction(action *ModerationAction, now time.Time) *trackedModerationAction {
+	for key, tracked := range recentModerationActions {
+		if now.Sub(tracked.seen) >= moderationActionWindow {
+			delete(recentModerationActions, key)
+		}
+	}
+
+	key := action.key()
+
+	if tracked, ok := recentModerationActions[key]; ok {
+		return tracked
+	}
+
+	recentModerationActions[key] = &trackedModerationAction{
+		sourceID: action.Source.ID,
+		seen:     now,
+	}
+
+	return nil
+}
+
+// expectModerationAction remembers who sent the action and why, so we can fill that in once Twitch confirms the action through CLEARCHAT
+func expectModerationAction(action *ModerationAction, now time.Time) {
+	recentModerationActionsMutex.Lock()
+	defer recentModerationActionsMutex.Unlock()
+
+	for key, expected := range expectedModerationActions {
+		if now.Sub(expected.sent) >= moderationActionWindow {
+			delete(expectedModerationActions, key)
+		}
+	}
+
+	expectedModerationActions[action.key()] = &expectedModerationAction{
+		source: action.Source,
+		reason: action.Reason,
+		sent:   now,
+	}
+}
+
+// fillExpectedModerationAction fills in the moderator and reason of an action that one of our bots sent
+// We assume that recentModerationActionsMutex is locked already
+func fillExpectedModerationAction(action *ModerationAction, now time.Time) {
+	key := action.key()
+
+	expected, ok := expectedModerationActions[key]
+	if !ok {
+		return
+	}
+
+	delete(expectedModerationActions, key)
+
+	if now.Sub(expected.sent) >= moderationActionWindow {
+		return
+	}
+
+	if action.Source.ID == "" {
+		action.Source = expected.source
+	}
+
+	if action.Reason == "" {
+		action.Reason = expected.reason
+	}
+}
+
+// moderationContext returns what we logged right before the action, i.e. the last messages of the user that was timed out
+func moderationContext(userContext pkg.UserContext, action *ModerationAction) *string {
+	var context string
+
+	if action.Action == pkg.ModerationActionDelete {
+		context = action.MessageText
+	} else if userContext != nil {
+		context = strings.Join(userContext.GetContext(action.Channel.ID, action.Target.ID), "\n")
+	}
+
+	if context == "" {
+		return nil
+	}
+
+	return &context
+}
+
+func publishModerationAction(pubSub pkg.PubSub, source pkg.PubSubSource, action *ModerationAction) {
+	switch action.Action {
+	case pkg.ModerationActionTimeout:
+		pubSub.Publish(source, "TimeoutEvent", &pkg.PubSubTimeoutEvent{
+			Channel:  action.Channel,
+			Target:   action.Target,
+			Source:   action.Source,
+			Duration: action.Duration,
+			Reason:   action.Reason,
+		})
+
+	case pkg.ModerationActionBan:
+		pubSub.Publish(source, "BanEvent", &pkg.PubSubBanEvent{
+			Channel: action.Channel,
+			Target:  action.Target,
+			Source:  action.Source,
+			Reason:  action.Reason,
+		})
+
+	case pkg.ModerationActionDelete:
+		pubSub.Publish(source, "MessageDeletedEvent", &pkg.PubSubMessageDeletedEvent{
+			Channel:   action.Channel,
+			Target:    action.Target,
+			MessageID: action.MessageID,
+			Text:      action.MessageText,
+		})
+	}
+}
+
+// HandleModerationAction logs the action in the ModerationAction table, together with the context of the target user, and publishes it
+// as a TimeoutEvent, BanEvent or MessageDeletedEvent.
+// If the action has already been reported, it's only used to fill in the moderator if the first report didn't know who it was
+func HandleModerationAction(db *sql.DB, userContext pkg.UserContext, pubSub pkg.PubSub, source pkg.PubSubSource, action ModerationAction) {
+	now := time.Now()
+
+	recentModerationActionsMutex.Lock()
+
+	if tracked := trackModerationAction(&action, now); tracked != nil {
+		if tracked.sourceID != "" || action.Source.ID == "" {
+			recentModerationActionsMutex.Unlock()
+			return
+		}
+
+		tracked.sourceID = action.Source.ID
+		databaseID := tracked.databaseID
+
+		recentModerationActionsMutex.Unlock()
+
+		if databaseID == 0 {
+			// The first report is still being logged, it fills in the moderator once it's done
+			return
+		}
+
+		fillModerationActionSource(db, databaseID, action.Source.ID)
+
+		return
+	}
+
+	tracked := recentModerationActions[action.key()]
+
+	fillExpectedModerationAction(&action, now)
+	tracked.sourceID = action.Source.ID
+
+	recentModerationActionsMutex.Unlock()
+
+	publishModerationAction(pubSub, source, &action)
+
+	const queryF = "INSERT INTO `ModerationAction` (ChannelID, UserID, Action, Duration, TargetID, Reason, Context) VALUES (?, ?, ?, ?, ?, ?, ?);"
+
+	res, err := db.Exec(queryF, action.Channel.ID, action.Source.ID, action.Action, action.Duration, action.Target.ID, action.Reason, moderationContext(userContext, &action))
+	if err != nil {
+		fmt.Println("Error logging moderation action:", err)
+		return
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return
+	}
+
+	recentModerationActionsMutex.Lock()
+	tracked.databaseID = id
+	sourceID := tracked.sourceID
+	recentModerationActionsMutex.Unlock()
+
+	if sourceID != action.Source.ID {
+		fillModerationActionSource(db, id, sourceID)
+	}
+}
+
+func fillModerationActionSource(db *sql.DB, databaseID int64, sourceID string) {
+	const queryF = "UPDATE `ModerationAction` SET `UserID`=? WHERE `id`=?;"
+
+	if _, err := db.Exec(queryF, sourceID, databaseID); err != nil {
+		fmt.Println("Error filling in moderator of moderation action:", err)
+	}
+}
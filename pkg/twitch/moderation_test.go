@@ -0,0 +1,98 @@
+package twitch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pajlada/pajbot2/pkg"
+)
+
+func TestParseRawMessage(t *testing.T) {
+	message := parseRawMessage(`@login=forsen;room-id=;target-msg-id=abc-123;tmi-sent-ts=1550868292494 :tmi.twitch.tv CLEARMSG #pajlada :hello world\s`)
+
+	if message.Command != "CLEARMSG" || message.Channel != "pajlada" || message.Text != `hello world\s` {
+		t.Errorf("Unexpected message %+v", message)
+	}
+
+	if message.Tags["login"] != "forsen" || message.Tags["target-msg-id"] != "abc-123" {
+		t.Errorf("Unexpected tags %+v", message.Tags)
+	}
+
+	if value, ok := message.Tags["room-id"]; !ok || value != "" {
+		t.Errorf("Expected empty room-id tag, got %q", value)
+	}
+
+	message = parseRawMessage(`@msg-id=ban_success;system-msg=forsen\sis\sbanned :tmi.twitch.tv NOTICE #pajlada`)
+
+	if message.Command != "NOTICE" || message.Channel != "pajlada" || message.Tags["system-msg"] != "forsen is banned" {
+		t.Errorf("Unexpected message %+v", message)
+	}
+}
+
+func TestTrackModerationAction(t *testing.T) {
+	recentModerationActionsMutex.Lock()
+	defer recentModerationActionsMutex.Unlock()
+
+	now := time.Now()
+
+	action := ModerationAction{
+		Channel:  pkg.PubSubUser{ID: "11148817"},
+		Target:   pkg.PubSubUser{ID: "22484632"},
+		Action:   pkg.ModerationActionTimeout,
+		Duration: 600,
+	}
+
+	if trackModerationAction(&action, now) != nil {
+		t.Fatal("First report of the action was seen as a duplicate")
+	}
+
+	// i.e. the same timeout received by another of our bots
+	if trackModerationAction(&action, now.Add(time.Second)) == nil {
+		t.Error("Second report of the action was not seen as a duplicate")
+	}
+
+	otherDuration := action
+	otherDuration.Duration = 1
+	if trackModerationAction(&otherDuration, now.Add(time.Second)) != nil {
+		t.Error("Timeout with a different duration was seen as a duplicate")
+	}
+
+	if trackModerationAction(&action, now.Add(moderationActionWindow+time.Second)) != nil {
+		t.Error("Action reported again after the window was seen as a duplicate")
+	}
+}
+
+func TestFillExpectedModerationAction(t *testing.T) {
+	now := time.Now()
+
+	action := ModerationAction{
+		Channel: pkg.PubSubUser{ID: "11148817"},
+		Target:  pkg.PubSubUser{ID: "22484632"},
+		Action:  pkg.ModerationActionBan,
+	}
+
+	sent := action
+	sent.Source = pkg.PubSubUser{ID: "82008718", Name: "pajbot"}
+	sent.Reason = "xd"
+	expectModerationAction(&sent, now)
+
+	recentModerationActionsMutex.Lock()
+	defer recentModerationActionsMutex.Unlock()
+
+	// i.e. the ban confirmed through CLEARCHAT
+	confirmed := action
+	fillExpectedModerationAction(&confirmed, now.Add(time.Second))
+
+	if confirmed.Source != sent.Source || confirmed.Reason != sent.Reason {
+		t.Errorf("Expected the moderator and reason of the sent ban to be filled in, got %+v", confirmed)
+	}
+
+	other := action
+	other.Action = pkg.ModerationActionTimeout
+	other.Duration = 600
+	fillExpectedModerationAction(&other, now.Add(time.Second))
+
+	if other.Source.ID != "" || other.Reason != "" {
+		t.Errorf("Timeout was filled in from the sent ban: %+v", other)
+	}
+}
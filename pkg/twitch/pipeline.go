@@ -0,0 +1,106 @@
+package twitch
+
+import (
+	"fmt"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/common"
+	"github.com/pajlada/pajbot2/pkg/modules"
+)
+
+// A chat message goes through the following steps:
+// 1. Pre-module middleware prepares the message, i.e. parsing twitch emotes. Any middleware can drop the message
+// 2. Global modules and the channels modules see the message, and may propose actions for it
+// 3. The highest priority action is carried out, logged and published as ActionPerformed
+
+// messageMiddleware is run on every chat message before any module sees it. Returning false drops the message
+type messageMiddleware func(b *Bot, botChannel *BotChannel, channel pkg.Channel, user pkg.User, message *TwitchMessage) bool
+
+var preModuleMiddleware = []messageMiddleware{
+	parseTwitchEmotes,
+	trackModerators,
+}
+
+func parseTwitchEmotes(b *Bot, botChannel *BotChannel, channel pkg.Channel, user pkg.User, message *TwitchMessage) bool {
+	for _, emote := range message.Emotes {
+		parsedEmote := &common.Emote{
+			Name:  emote.Name,
+			ID:    emote.ID,
+			Count: emote.Count,
+			Type:  "twitch",
+		}
+		message.twitchEmotes = append(message.twitchEmotes, parsedEmote)
+	}
+
+	return true
+}
+
+func trackModerators(b *Bot, botChannel *BotChannel, channel pkg.Channel, user pkg.User, message *TwitchMessage) bool {
+	b.trackModerator(channel, user)
+
+	return true
+}
+
+func (b *Bot) handleMessagePipeline(botChannel *BotChannel, channel pkg.Channel, user pkg.User, message *TwitchMessage, action pkg.Action) {
+	for _, middleware := range preModuleMiddleware {
+		if !middleware(b, botChannel, channel, user, message) {
+			return
+		}
+	}
+
+	// Global modules see the message before the channels own modules
+	modules.OnGlobalMessage(b, botChannel, channel, user, message, action)
+
+	err := botChannel.handleMessage(b, channel, user, message, action)
+	if err != nil {
+		fmt.Println("Error occured while forwarding message to bot channel:", err)
+	}
+
+	b.finalizeAction(channel, user, message, action)
+}
+
+// finalizeAction carries out the action that was chosen for the message, if any
+func (b *Bot) finalizeAction(channel pkg.Channel, user pkg.User, message *TwitchMessage, action pkg.Action) {
+	candidates := action.Candidates()
+
+	var chosen *pkg.ActionCandidate
+	for i := range candidates {
+		if candidates[i].Chosen {
+			chosen = &candidates[i]
+			break
+		}
+	}
+
+	if chosen == nil {
+		return
+	}
+
+	if err := action.Do(); err != nil {
+		fmt.Printf("Error performing action '%s' on %s in %s: %s\n", chosen.Action, user.GetName(), channel.GetChannel(), err)
+		return
+	}
+
+	fmt.Printf("Performed action '%s' on %s in %s, proposed by '%s' (%d candidates)\n", chosen.Action, user.GetName(), channel.GetChannel(), chosen.Source, len(candidates))
+
+	event := &pkg.PubSubActionEvent{
+		Channel: pkg.PubSubUser{
+			ID:   channel.GetID(),
+			Name: channel.GetChannel(),
+		},
+		Target: pkg.PubSubUser{
+			ID:   user.GetID(),
+			Name: user.GetName(),
+		},
+		Message: message.GetText(),
+	}
+
+	for _, candidate := range candidates {
+		event.Candidates = append(event.Candidates, pkg.PubSubActionCandidate{
+			Source: candidate.Source,
+			Action: candidate.Action.String(),
+			Chosen: candidate.Chosen,
+		})
+	}
+
+	b.pubSub.Publish(b, "ActionPerformed", event)
+}
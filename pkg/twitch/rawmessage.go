@@ -0,0 +1,65 @@
+package twitch
+
+import "strings"
+
+// rawMessage is an IRC message that go-twitch-irc doesn't parse for us, i.e. CLEARMSG
+type rawMessage struct {
+	Tags    map[string]string
+	Command string
+
+	// Channel name without the #
+	Channel string
+
+	Text string
+}
+
+var tagValueReplacer = strings.NewReplacer(`\s`, " ", `\:`, ";", `\\`, `\`, `\r`, "\r", `\n`, "\n")
+
+// parseRawMessage parses a raw IRC message such as "@login=forsen;target-msg-id=abc :tmi.twitch.tv CLEARMSG #pajlada :xD"
+func parseRawMessage(line string) rawMessage {
+	var message rawMessage
+
+	message.Tags = make(map[string]string)
+
+	if strings.HasPrefix(line, "@") {
+		var tags string
+		if i := strings.IndexByte(line, ' '); i != -1 {
+			tags, line = line[1:i], line[i+1:]
+		} else {
+			tags, line = line[1:], ""
+		}
+
+		for _, tag := range strings.Split(tags, ";") {
+			parts := strings.SplitN(tag, "=", 2)
+			if len(parts) == 2 {
+				message.Tags[parts[0]] = tagValueReplacer.Replace(parts[1])
+			} else {
+				message.Tags[parts[0]] = ""
+			}
+		}
+	}
+
+	// Skip the prefix
+	if strings.HasPrefix(line, ":") {
+		if i := strings.IndexByte(line, ' '); i != -1 {
+			line = line[i+1:]
+		} else {
+			line = ""
+		}
+	}
+
+	if i := strings.Index(line, " :"); i != -1 {
+		message.Text = line[i+2:]
+		line = line[:i]
+	}
+
+	parts := strings.Fields(line)
+	if len(parts) > 0 {
+		message.Command = parts[0]
+	}
+	if len(parts) > 1 {
+		message.Channel = strings.TrimPrefix(parts[1], "#")
+	}
+
+	return message
+}
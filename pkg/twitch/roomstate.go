@@ -0,0 +1,220 @@
+package twitch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/channels"
+)
+
+// roomStateTags are the tags of a ROOMSTATE message that describe the chat settings. The first ROOMSTATE message after
+// joining a channel has all of them, after that Twitch only sends the tag of the setting that changed
+var roomStateTags = []string{"slow", "followers-only", "emote-only", "r9k", "subs-only"}
+
+// applyRoomStateTags returns the room state with the chat settings found in tags applied
+func applyRoomStateTags(state pkg.RoomState, tags map[string]string) pkg.RoomState {
+	for _, tag := range roomStateTags {
+		value, ok := tags[tag]
+		if !ok {
+			continue
+		}
+
+		switch tag {
+		case "slow":
+			state.Slow, _ = strconv.Atoi(value)
+		case "followers-only":
+			followersOnly, err := strconv.Atoi(value)
+			if err != nil {
+				followersOnly = -1
+			}
+			state.FollowersOnly = followersOnly
+		case "emote-only":
+			state.EmoteOnly = value == "1"
+		case "r9k":
+			state.R9K = value == "1"
+		case "subs-only":
+			state.SubsOnly = value == "1"
+		}
+	}
+
+	return state
+}
+
+func (c *BotChannel) channel() pkg.Channel {
+	return channels.TwitchChannel{
+		Channel: c.ChannelName(),
+		ID:      c.ChannelID(),
+	}
+}
+
+// RoomState returns the chat settings of the channel as of the last ROOMSTATE message
+func (c *BotChannel) RoomState() pkg.RoomState {
+	c.roomStateMutex.Lock()
+	defer c.roomStateMutex.Unlock()
+
+	return c.roomState
+}
+
+// updateRoomState applies the chat settings of a ROOMSTATE message. Returns false if none of the settings changed.
+// The first ROOMSTATE always counts as a change, even if every mode is off like in the state we start out with
+func (c *BotChannel) updateRoomState(tags map[string]string) (pkg.RoomState, bool) {
+	c.roomStateMutex.Lock()
+	defer c.roomStateMutex.Unlock()
+
+	state := applyRoomStateTags(c.roomState, tags)
+	if c.roomStateKnown && state == c.roomState {
+		return state, false
+	}
+
+	c.roomState = state
+	c.roomStateKnown = true
+
+	return state, true
+}
+
+func (c *BotChannel) loadPermaModes() error {
+	const queryF = `SELECT mode, argument FROM BotChannelPermaMode WHERE bot_channel_id=?`
+
+	rows, err := c.sql.Query(queryF, c.DatabaseID())
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	var permaModes []pkg.PermaMode
+
+	for rows.Next() {
+		var mode string
+		var permaMode pkg.PermaMode
+		if err = rows.Scan(&mode, &permaMode.Argument); err != nil {
+			return err
+		}
+
+		if permaMode.Mode, err = pkg.ParseChannelMode(mode); err != nil {
+			fmt.Printf("Ignoring perma mode in channel %s: %s\n", c.ChannelName(), err)
+			continue
+		}
+
+		permaModes = append(permaModes, permaMode)
+	}
+
+	c.roomStateMutex.Lock()
+	c.permaModes = permaModes
+	c.roomStateMutex.Unlock()
+
+	return nil
+}
+
+// PermaModes returns the channel modes that the bot turns back on whenever they're turned off
+func (c *BotChannel) PermaModes() []pkg.PermaMode {
+	c.roomStateMutex.Lock()
+	defer c.roomStateMutex.Unlock()
+
+	return append([]pkg.PermaMode{}, c.permaModes...)
+}
+
+// EnablePermaMode remembers that the mode should always be on in this channel, and turns it on if it's off
+func (c *BotChannel) EnablePermaMode(mode pkg.ChannelMode, argument string) error {
+	const queryF = `
+INSERT INTO
+	BotChannelPermaMode
+	(bot_channel_id, mode, argument)
+	VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE argument=?`
+
+	if _, err := c.sql.Exec(queryF, c.DatabaseID(), string(mode), argument, argument); err != nil {
+		return err
+	}
+
+	permaMode := pkg.PermaMode{
+		Mode:     mode,
+		Argument: argument,
+	}
+
+	c.roomStateMutex.Lock()
+	found := false
+	for i, existing := range c.permaModes {
+		if existing.Mode == mode {
+			c.permaModes[i] = permaMode
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.permaModes = append(c.permaModes, permaMode)
+	}
+	// If we haven't seen the room state yet, the mode is turned on once we do
+	turnOn := c.roomStateKnown && !c.roomState.Enabled(mode)
+	c.roomStateMutex.Unlock()
+
+	if turnOn {
+		c.bot.Say(c.channel(), mode.Command(argument))
+	}
+
+	return nil
+}
+
+// DisablePermaMode stops the bot from turning the mode back on. The mode itself is left as it is
+func (c *BotChannel) DisablePermaMode(mode pkg.ChannelMode) error {
+	c.roomStateMutex.Lock()
+	index := -1
+	for i, existing := range c.permaModes {
+		if existing.Mode == mode {
+			index = i
+			break
+		}
+	}
+	c.roomStateMutex.Unlock()
+
+	if index == -1 {
+		return errors.New("perma mode isn't enabled")
+	}
+
+	const queryF = `DELETE FROM BotChannelPermaMode WHERE bot_channel_id=? AND mode=?`
+
+	if _, err := c.sql.Exec(queryF, c.DatabaseID(), string(mode)); err != nil {
+		return err
+	}
+
+	c.roomStateMutex.Lock()
+	for i, existing := range c.permaModes {
+		if existing.Mode == mode {
+			c.permaModes = append(c.permaModes[:i], c.permaModes[i+1:]...)
+			break
+		}
+	}
+	c.roomStateMutex.Unlock()
+
+	return nil
+}
+
+// enforcePermaModes turns the perma modes that are off in the given room state back on
+func (c *BotChannel) enforcePermaModes(state pkg.RoomState) {
+	for _, permaMode := range c.PermaModes() {
+		if state.Enabled(permaMode.Mode) {
+			continue
+		}
+
+		c.bot.Say(c.channel(), fmt.Sprintf("Perma %s mode is enabled. A mod can type !pb2permamode disable %s to disable it", permaMode.Mode, permaMode.Mode))
+		c.bot.Say(c.channel(), permaMode.Mode.Command(permaMode.Argument))
+	}
+}
+
+// handleRoomState is called with the tags of every ROOMSTATE message for this channel
+func (c *BotChannel) handleRoomState(tags map[string]string) {
+	state, changed := c.updateRoomState(tags)
+	if !changed {
+		return
+	}
+
+	c.pubSub.Publish(c.bot, "RoomStateChanged", &pkg.PubSubRoomStateEvent{
+		ChannelID:    c.ChannelID(),
+		BotChannelID: c.DatabaseID(),
+		RoomState:    state,
+	})
+
+	c.enforcePermaModes(state)
+}
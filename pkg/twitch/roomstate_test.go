@@ -0,0 +1,74 @@
+package twitch
+
+import (
+	"testing"
+
+	"github.com/pajlada/pajbot2/pkg"
+)
+
+func TestApplyRoomStateTags(t *testing.T) {
+	state := pkg.RoomState{FollowersOnly: -1}
+
+	// First ROOMSTATE after joining a channel
+	state = applyRoomStateTags(state, map[string]string{
+		"room-id":        "11148817",
+		"slow":           "0",
+		"followers-only": "-1",
+		"emote-only":     "0",
+		"r9k":            "0",
+		"subs-only":      "1",
+	})
+
+	expected := pkg.RoomState{FollowersOnly: -1, SubsOnly: true}
+	if state != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, state)
+	}
+
+	// Only the setting that changed is sent after that
+	state = applyRoomStateTags(state, map[string]string{
+		"room-id": "11148817",
+		"slow":    "30",
+	})
+
+	expected.Slow = 30
+	if state != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, state)
+	}
+
+	if !state.Enabled(pkg.ChannelModeSlow) || state.Enabled(pkg.ChannelModeFollowersOnly) {
+		t.Errorf("Unexpected enabled modes in %+v", state)
+	}
+
+	state = applyRoomStateTags(state, map[string]string{
+		"followers-only": "10",
+	})
+
+	if state.FollowersOnly != 10 || !state.Enabled(pkg.ChannelModeFollowersOnly) {
+		t.Errorf("Expected followers-only mode to be 10 minutes, got %+v", state)
+	}
+
+	// First ROOMSTATE in a channel with every mode off is the same as the state we start out with
+	allOff := map[string]string{
+		"room-id":        "11148817",
+		"slow":           "0",
+		"followers-only": "-1",
+		"emote-only":     "0",
+		"r9k":            "0",
+		"subs-only":      "0",
+	}
+
+	c := &BotChannel{}
+	c.roomState.FollowersOnly = -1
+
+	if state := applyRoomStateTags(c.roomState, allOff); state != c.roomState {
+		t.Fatalf("Expected every mode to be off, got %+v", state)
+	}
+
+	if _, changed := c.updateRoomState(allOff); !changed || !c.roomStateKnown {
+		t.Errorf("The first ROOMSTATE should make the room state known, even if every mode is off")
+	}
+
+	if _, changed := c.updateRoomState(allOff); changed {
+		t.Errorf("The same ROOMSTATE again should not count as a change")
+	}
+}
@@ -10,43 +10,69 @@ import (
 
 var _ pkg.Stream = &Stream{}
 
+// NewStreamData converts a stream from the Twitch API. A nil stream means the stream is offline
+func NewStreamData(stream *gotwitch.Stream) *pkg.StreamData {
+	if stream == nil {
+		return nil
+	}
+
+	return &pkg.StreamData{
+		Title:       stream.Title,
+		GameID:      stream.GameID,
+		ViewerCount: stream.ViewerCount,
+		StartedAt:   stream.StartedAt,
+	}
+}
+
 type StreamStatus struct {
-	*gotwitch.Stream
+	*pkg.StreamData
 
 	mutex *sync.RWMutex
 }
 
-func (s *StreamStatus) Update(streamData *gotwitch.Stream) {
+// Update sets the new status of the stream. Returns true if the stream went online or offline
+func (s *StreamStatus) Update(streamData *pkg.StreamData) bool {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	s.Stream = streamData
+	wasLive := s.StreamData != nil
+	s.StreamData = streamData
+
+	return wasLive != (streamData != nil)
 }
 
 func (s *StreamStatus) Live() bool {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	return s.Stream != nil
+	return s.StreamData != nil
 }
 
 func (s *StreamStatus) StartedAt() (r time.Time) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if s.Stream != nil {
-		r = s.Stream.StartedAt
+	if s.StreamData != nil {
+		r = s.StreamData.StartedAt
 	}
 
 	return
 }
 
+// RepollInterval is how often we poll the status of a stream even if the streams webhook doesn't tell us about any changes
+const RepollInterval = 2 * time.Minute
+
 type Stream struct {
 	ID string
 
 	status StreamStatus
 
 	needsInitialPoll bool
+
+	// Set once we've received the status of the stream for the first time
+	known bool
+
+	lastUpdated time.Time
 }
 
 func NewTwitchStream(account pkg.Account) *Stream {
@@ -67,8 +93,27 @@ func (s *Stream) Status() pkg.StreamStatus {
 	return &s.status
 }
 
-func (s *Stream) Update(stream *gotwitch.Stream) {
-	s.status.Update(stream)
+// Update sets the new status of the stream, from the API or the streams webhook. A nil stream means the stream is offline.
+// Returns true if the stream went online or offline. The first update never counts as a change, since we don't know what the status was before
+func (s *Stream) Update(stream *pkg.StreamData) bool {
+	changed := s.status.Update(stream) && s.known
+
+	s.known = true
+	s.lastUpdated = time.Now()
+
+	return changed
+}
+
+// NeedsPoll returns true if we haven't heard about the status of the stream for a while.
+// The stream is treated as updated right away, so it's not polled again while the poll is in progress
+func (s *Stream) NeedsPoll() bool {
+	if time.Since(s.lastUpdated) < RepollInterval {
+		return false
+	}
+
+	s.lastUpdated = time.Now()
+
+	return true
 }
 
 func (s *Stream) NeedsInitialPoll() bool {
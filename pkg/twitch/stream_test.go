@@ -0,0 +1,31 @@
+package twitch
+
+import (
+	"testing"
+
+	"github.com/pajlada/pajbot2/pkg"
+)
+
+func TestStreamUpdate(t *testing.T) {
+	s := NewTwitchStream(&SimpleAccount{"11148817", "pajlada"})
+
+	if s.Update(&pkg.StreamData{}) {
+		t.Errorf("The first update should not count as the stream going online")
+	}
+
+	if s.Update(&pkg.StreamData{}) {
+		t.Errorf("Stream was already online")
+	}
+
+	if !s.Update(nil) {
+		t.Errorf("Stream should have gone offline")
+	}
+
+	if !s.Update(&pkg.StreamData{}) || !s.Status().Live() {
+		t.Errorf("Stream should have gone online")
+	}
+
+	if s.NeedsPoll() {
+		t.Errorf("Stream was just updated and should not need to be polled")
+	}
+}
@@ -15,5 +15,6 @@ type User interface {
 	GetID() string
 	IsModerator() bool
 	IsBroadcaster(Channel) bool
+	IsSubscriber() bool
 	GetBadges() map[string]int
 }
@@ -140,6 +140,15 @@ func (u TwitchUser) IsBroadcaster(channel pkg.Channel) bool {
 	return u.GetName() == channel.GetChannel()
 }
 
+func (u TwitchUser) IsSubscriber() bool {
+	if _, ok := u.Badges["subscriber"]; ok {
+		return true
+	}
+
+	_, ok := u.Badges["founder"]
+	return ok
+}
+
 func (u TwitchUser) GetBadges() map[string]int {
 	return u.Badges
 }
@@ -214,6 +223,36 @@ func GetUserChannelPermissions(userID, channelID string) (pkg.Permission, error)
 	return permissions, nil
 }
 
+// GetUserChannelPermissionsByChannel returns the channel permissions of the user in every channel they have any, by channel ID
+func GetUserChannelPermissionsByChannel(userID string) (map[string]pkg.Permission, error) {
+	if userID == "" {
+		return nil, errors.New("missing user id")
+	}
+
+	const queryF = "SELECT channel_id, permissions FROM `TwitchUserChannelPermission` WHERE `twitch_user_id`=?;"
+
+	rows, err := _server.sql.Query(queryF, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := make(map[string]pkg.Permission)
+
+	for rows.Next() {
+		var channelID string
+		var permissionsBytes []uint8
+		err := rows.Scan(&channelID, &permissionsBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions[channelID] = pkg.Permission(utils.BytesToUint64(permissionsBytes))
+	}
+
+	return permissions, rows.Err()
+}
+
 func SetUserChannelPermissions(userID, channelID string, permission pkg.Permission) error {
 	if userID == "" || channelID == "" {
 		return errors.New("missing user id or channel id")
@@ -26,9 +26,9 @@ func Load(a pkg.Application, cfg *config.Config) {
 
 	auth.Load(m, a, cfg)
 
-	channel.Load(m)
+	channel.Load(m, a)
 
 	report.Load(m)
 
-	webhook.Load(m)
+	webhook.Load(m, a, cfg)
 }
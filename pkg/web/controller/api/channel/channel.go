@@ -2,15 +2,28 @@ package channel
 
 import (
 	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
 	"github.com/pajlada/pajbot2/pkg/web/controller/api/channel/banphrases"
+	"github.com/pajlada/pajbot2/pkg/web/controller/api/channel/commands"
+	"github.com/pajlada/pajbot2/pkg/web/controller/api/channel/exemptions"
+	"github.com/pajlada/pajbot2/pkg/web/controller/api/channel/giveaway"
 	"github.com/pajlada/pajbot2/pkg/web/controller/api/channel/moderation"
+	"github.com/pajlada/pajbot2/pkg/web/controller/api/channel/modes"
+	"github.com/pajlada/pajbot2/pkg/web/controller/api/channel/modules"
+	"github.com/pajlada/pajbot2/pkg/web/controller/api/channel/sharedbans"
 )
 
-func Load(parent *mux.Router) {
+func Load(parent *mux.Router, a pkg.Application) {
 	m := parent.PathPrefix(`/channel/{channelID:\w+}`).Subrouter()
 
 	moderation.Load(m)
 	banphrases.Load(m)
+	giveaway.Load(m)
+	commands.Load(m)
+	sharedbans.Load(m)
+	modules.Load(m, a)
+	modes.Load(m, a)
+	exemptions.Load(m, a)
 
 	// m.HandleFunc(`/channel/{channel:\w+}/{rest:.*}`, APIHandler)
 }
@@ -0,0 +1,15 @@
+package commands
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg/web/router"
+)
+
+func Load(parent *mux.Router) {
+	m := parent.PathPrefix("/commands").Subrouter()
+
+	router.RGet(m, `/list`, handleList)
+	router.RPost(m, `/create`, handleCreate)
+	router.RPost(m, `/{commandID:[0-9]+}/update`, handleUpdate)
+	router.RPost(m, `/{commandID:[0-9]+}/delete`, handleDelete)
+}
@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/commands"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+// apply copies the values from the request body into the given text command
+func (c *command) apply(textCommand *commands.TextCommand) (err error) {
+	textCommand.Triggers, err = commands.ParseTriggers(c.Triggers)
+	if err != nil {
+		return
+	}
+
+	textCommand.Response = c.Response
+	textCommand.ResponseType = c.ResponseType
+	textCommand.Level = c.Level
+	textCommand.GlobalCooldown = c.GlobalCooldown
+	textCommand.UserCooldown = c.UserCooldown
+	textCommand.PointCost = c.PointCost
+	textCommand.Enabled = c.Enabled
+
+	return textCommand.Validate()
+}
+
+// checkTriggerConflict returns an error if any of the triggers of the given command are already used by another command in the channel
+func checkTriggerConflict(c state.State, textCommand *commands.TextCommand) error {
+	textCommands, err := commands.LoadTextCommands(c.SQL, textCommand.ChannelID)
+	if err != nil {
+		return err
+	}
+
+	for _, trigger := range textCommand.Triggers {
+		for _, other := range textCommands {
+			if other.ID != textCommand.ID && other.HasTrigger(trigger) {
+				return fmt.Errorf("a command with the trigger !%s already exists", trigger)
+			}
+		}
+	}
+
+	return nil
+}
+
+func publishCommandsUpdated(c state.State, channelID string) {
+	c.PubSub.Publish(c.PubSubSource(), "CommandsUpdated", &pkg.PubSubCommandsUpdated{
+		ChannelID: channelID,
+	})
+}
+
+func saveCommand(w http.ResponseWriter, r *http.Request, c state.State, textCommand *commands.TextCommand) {
+	var body command
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.WebWriteError(w, 400, "Invalid request body")
+		return
+	}
+
+	if err := body.apply(textCommand); err != nil {
+		utils.WebWriteError(w, 400, err.Error())
+		return
+	}
+
+	if err := checkTriggerConflict(c, textCommand); err != nil {
+		utils.WebWriteError(w, 400, err.Error())
+		return
+	}
+
+	if err := textCommand.Save(c.SQL); err != nil {
+		fmt.Println("Error saving command:", err)
+		utils.WebWriteError(w, 500, "Internal error")
+		return
+	}
+
+	publishCommandsUpdated(c, textCommand.ChannelID)
+
+	utils.WebWrite(w, newCommand(textCommand))
+}
+
+func handleCreate(w http.ResponseWriter, r *http.Request) {
+	c := state.Context(w, r)
+
+	if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	saveCommand(w, r, c, commands.NewTextCommand(vars["channelID"]))
+}
+
+// loadCommand loads the command specified in the url. If the command couldn't be loaded, an error is written and nil is returned
+func loadCommand(w http.ResponseWriter, r *http.Request, c state.State) *commands.TextCommand {
+	vars := mux.Vars(r)
+
+	commandID, err := strconv.ParseInt(vars["commandID"], 10, 64)
+	if err != nil {
+		utils.WebWriteError(w, 400, "Invalid command ID")
+		return nil
+	}
+
+	textCommand, err := commands.LoadTextCommand(c.SQL, vars["channelID"], commandID)
+	if err != nil {
+		fmt.Println("Error loading command:", err)
+		utils.WebWriteError(w, 500, "Internal error")
+		return nil
+	}
+
+	if textCommand == nil {
+		utils.WebWriteError(w, 404, "No command with that ID exists")
+		return nil
+	}
+
+	return textCommand
+}
+
+func handleUpdate(w http.ResponseWriter, r *http.Request) {
+	c := state.Context(w, r)
+
+	if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+		return
+	}
+
+	textCommand := loadCommand(w, r, c)
+	if textCommand == nil {
+		return
+	}
+
+	saveCommand(w, r, c, textCommand)
+}
+
+func handleDelete(w http.ResponseWriter, r *http.Request) {
+	c := state.Context(w, r)
+
+	if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+		return
+	}
+
+	textCommand := loadCommand(w, r, c)
+	if textCommand == nil {
+		return
+	}
+
+	if err := commands.DeleteTextCommand(c.SQL, textCommand.ChannelID, textCommand.ID); err != nil {
+		fmt.Println("Error deleting command:", err)
+		utils.WebWriteError(w, 500, "Internal error")
+		return
+	}
+
+	publishCommandsUpdated(c, textCommand.ChannelID)
+
+	utils.WebWrite(w, newCommand(textCommand))
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/commands"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+type command struct {
+	ID             int64
+	Triggers       string
+	Response       string
+	ResponseType   string
+	Level          int
+	GlobalCooldown int
+	UserCooldown   int
+	PointCost      uint64
+	Enabled        bool
+}
+
+func newCommand(c *commands.TextCommand) command {
+	return command{
+		ID:             c.ID,
+		Triggers:       strings.Join(c.Triggers, "|"),
+		Response:       c.Response,
+		ResponseType:   c.ResponseType,
+		Level:          c.Level,
+		GlobalCooldown: c.GlobalCooldown,
+		UserCooldown:   c.UserCooldown,
+		PointCost:      c.PointCost,
+		Enabled:        c.Enabled,
+	}
+}
+
+type listResponse struct {
+	Commands  []command
+	ChannelID string
+}
+
+func handleList(w http.ResponseWriter, r *http.Request) {
+	c := state.Context(w, r)
+
+	if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	var response listResponse
+
+	response.ChannelID = vars["channelID"]
+
+	textCommands, err := commands.LoadTextCommands(c.SQL, response.ChannelID)
+	if err != nil {
+		fmt.Println("Error loading commands:", err)
+		utils.WebWriteError(w, 500, "Internal error")
+		return
+	}
+
+	response.Commands = []command{}
+	for _, textCommand := range textCommands {
+		response.Commands = append(response.Commands, newCommand(textCommand))
+	}
+
+	utils.WebWrite(w, response)
+}
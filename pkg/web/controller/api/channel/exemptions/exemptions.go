@@ -0,0 +1,14 @@
+package exemptions
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/web/router"
+)
+
+func Load(parent *mux.Router, a pkg.Application) {
+	m := parent.PathPrefix("/exemptions").Subrouter()
+
+	router.RGet(m, ``, handleGet(a))
+	router.RPost(m, ``, handleSet(a))
+}
@@ -0,0 +1,54 @@
+package exemptions
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+type botExemptions struct {
+	BotName string
+
+	Policy pkg.ExemptionPolicy
+}
+
+type getResponse struct {
+	ChannelID string
+
+	// One entry for each of our bots that has joined the channel
+	Bots []botExemptions
+}
+
+func handleGet(a pkg.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := state.Context(w, r)
+
+		if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+			return
+		}
+
+		vars := mux.Vars(r)
+		var response getResponse
+
+		response.ChannelID = vars["channelID"]
+		response.Bots = []botExemptions{}
+
+		for botName, botChannel := range webutils.BotChannels(a, response.ChannelID) {
+			response.Bots = append(response.Bots, botExemptions{
+				BotName: botName,
+				Policy:  botChannel.ExemptionPolicy(),
+			})
+		}
+
+		if len(response.Bots) == 0 {
+			utils.WebWriteError(w, 404, "No bot has joined that channel")
+			return
+		}
+
+		utils.WebWrite(w, response)
+	}
+}
@@ -0,0 +1,55 @@
+package exemptions
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+type setResponse struct {
+	ChannelID string
+
+	webutils.EditResult
+}
+
+// handleSet replaces the exemption policy of the channel with the json encoded policy in the request body
+func handleSet(a pkg.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := state.Context(w, r)
+
+		if !webutils.RequirePermission(w, c, pkg.PermissionAdmin) {
+			return
+		}
+
+		var policy pkg.ExemptionPolicy
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			utils.WebWriteError(w, 400, "Invalid request body")
+			return
+		}
+
+		if err := policy.Validate(); err != nil {
+			utils.WebWriteError(w, 400, err.Error())
+			return
+		}
+
+		vars := mux.Vars(r)
+		response := setResponse{
+			ChannelID: vars["channelID"],
+		}
+
+		var ok bool
+		response.EditResult, ok = webutils.EditBotChannels(w, a, response.ChannelID, 500, func(botChannel pkg.BotChannel) error {
+			return botChannel.SetExemptionPolicy(policy)
+		})
+		if !ok {
+			return
+		}
+
+		utils.WebWrite(w, response)
+	}
+}
@@ -0,0 +1,91 @@
+package giveaway
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+type giveaway struct {
+	ID          int64
+	Keyword     string
+	Requirement string
+	Cost        uint64
+	StartedByID string
+	StartedAt   time.Time
+	StoppedAt   *time.Time
+}
+
+type entrant struct {
+	UserID    string
+	UserName  string
+	EnteredAt time.Time
+	Winner    bool
+	Rerolled  bool
+}
+
+type entrantsResponse struct {
+	ChannelID string
+
+	Giveaway giveaway
+	Entrants []entrant
+}
+
+// apiEntrants lists the entrants of the given giveaway, or of the latest giveaway in the channel if no giveaway ID is given
+func apiEntrants(w http.ResponseWriter, r *http.Request) {
+	c := state.Context(w, r)
+
+	if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	var response entrantsResponse
+
+	response.ChannelID = vars["channelID"]
+
+	const giveawayQueryF = "SELECT `id`, `keyword`, `requirement`, `cost`, `started_by_id`, `started_at`, `stopped_at` FROM `Giveaway` WHERE `channel_id`=? AND (?='' OR `id`=?) ORDER BY `id` DESC LIMIT 1"
+
+	var stoppedAt mysql.NullTime
+	row := c.SQL.QueryRow(giveawayQueryF, response.ChannelID, vars["giveaway_id"], vars["giveaway_id"])
+	err := row.Scan(&response.Giveaway.ID, &response.Giveaway.Keyword, &response.Giveaway.Requirement, &response.Giveaway.Cost, &response.Giveaway.StartedByID, &response.Giveaway.StartedAt, &stoppedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.WebWriteError(w, 404, "No giveaway found")
+			return
+		}
+
+		panic(err)
+	}
+
+	if stoppedAt.Valid {
+		response.Giveaway.StoppedAt = &stoppedAt.Time
+	}
+
+	const entrantsQueryF = "SELECT `user_id`, `user_name`, `entered_at`, `winner`, `rerolled` FROM `GiveawayEntrant` WHERE `giveaway_id`=? ORDER BY `id` ASC"
+
+	rows, err := c.SQL.Query(entrantsQueryF, response.Giveaway.ID)
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var e entrant
+		if err := rows.Scan(&e.UserID, &e.UserName, &e.EnteredAt, &e.Winner, &e.Rerolled); err != nil {
+			panic(err)
+		}
+
+		response.Entrants = append(response.Entrants, e)
+	}
+
+	utils.WebWrite(w, response)
+}
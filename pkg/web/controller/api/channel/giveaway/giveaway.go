@@ -0,0 +1,13 @@
+package giveaway
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg/web/router"
+)
+
+func Load(parent *mux.Router) {
+	m := parent.PathPrefix("/giveaway").Subrouter()
+
+	router.RGet(m, `/entrants`, apiEntrants).Queries("giveaway_id", `{giveaway_id:[0-9]+}`)
+	router.RGet(m, `/entrants`, apiEntrants)
+}
@@ -6,25 +6,24 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
 	"github.com/pajlada/pajbot2/pkg/utils"
 	"github.com/pajlada/pajbot2/pkg/web/state"
 )
 
-const ActionUnknown = 0
-const ActionTimeout = 1
-const ActionBan = 2
-const ActionUnban = 3
-
 func getActionString(action int) string {
 	switch action {
-	case ActionTimeout:
+	case pkg.ModerationActionTimeout:
 		return "timeout"
 
-	case ActionBan:
+	case pkg.ModerationActionBan:
 		return "ban"
 
-	case ActionUnban:
+	case pkg.ModerationActionUnban:
 		return "unban"
+
+	case pkg.ModerationActionDelete:
+		return "delete"
 	}
 
 	return ""
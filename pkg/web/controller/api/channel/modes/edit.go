@@ -0,0 +1,65 @@
+package modes
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+type editResponse struct {
+	ChannelID string
+	Mode      pkg.ChannelMode
+
+	webutils.EditResult
+}
+
+// editPermaMode calls cb with the bot channel of each of our bots that has joined the channel in the url
+func editPermaMode(a pkg.Application, cb func(botChannel pkg.BotChannel, mode pkg.ChannelMode, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := state.Context(w, r)
+
+		if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+			return
+		}
+
+		vars := mux.Vars(r)
+
+		mode, err := pkg.ParseChannelMode(vars["mode"])
+		if err != nil {
+			utils.WebWriteError(w, 400, err.Error())
+			return
+		}
+
+		response := editResponse{
+			ChannelID: vars["channelID"],
+			Mode:      mode,
+		}
+
+		var ok bool
+		response.EditResult, ok = webutils.EditBotChannels(w, a, response.ChannelID, 400, func(botChannel pkg.BotChannel) error {
+			return cb(botChannel, mode, r)
+		})
+		if !ok {
+			return
+		}
+
+		utils.WebWrite(w, response)
+	}
+}
+
+// handleEnable enables the perma mode. The argument to the chat command, i.e. the number of seconds for slow mode, is read from the argument query parameter
+func handleEnable(a pkg.Application) http.HandlerFunc {
+	return editPermaMode(a, func(botChannel pkg.BotChannel, mode pkg.ChannelMode, r *http.Request) error {
+		return botChannel.EnablePermaMode(mode, r.URL.Query().Get("argument"))
+	})
+}
+
+func handleDisable(a pkg.Application) http.HandlerFunc {
+	return editPermaMode(a, func(botChannel pkg.BotChannel, mode pkg.ChannelMode, r *http.Request) error {
+		return botChannel.DisablePermaMode(mode)
+	})
+}
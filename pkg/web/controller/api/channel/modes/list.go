@@ -0,0 +1,56 @@
+package modes
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+type botModes struct {
+	BotName string
+
+	RoomState  pkg.RoomState
+	PermaModes []pkg.PermaMode
+}
+
+type listResponse struct {
+	ChannelID string
+
+	// One entry for each of our bots that has joined the channel
+	Bots []botModes
+}
+
+func handleList(a pkg.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := state.Context(w, r)
+
+		if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+			return
+		}
+
+		vars := mux.Vars(r)
+		var response listResponse
+
+		response.ChannelID = vars["channelID"]
+		response.Bots = []botModes{}
+
+		for botName, botChannel := range webutils.BotChannels(a, response.ChannelID) {
+			response.Bots = append(response.Bots, botModes{
+				BotName:    botName,
+				RoomState:  botChannel.RoomState(),
+				PermaModes: botChannel.PermaModes(),
+			})
+		}
+
+		if len(response.Bots) == 0 {
+			utils.WebWriteError(w, 404, "No bot has joined that channel")
+			return
+		}
+
+		utils.WebWrite(w, response)
+	}
+}
@@ -0,0 +1,15 @@
+package modes
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/web/router"
+)
+
+func Load(parent *mux.Router, a pkg.Application) {
+	m := parent.PathPrefix("/modes").Subrouter()
+
+	router.RGet(m, ``, handleList(a))
+	router.RPost(m, `/{mode:\w+}/enable`, handleEnable(a))
+	router.RPost(m, `/{mode:\w+}/disable`, handleDisable(a))
+}
@@ -0,0 +1,90 @@
+package modules
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	botmodules "github.com/pajlada/pajbot2/pkg/modules"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+type editResponse struct {
+	ChannelID string
+	ModuleID  string
+
+	webutils.EditResult
+}
+
+// editModule calls cb with the bot channel of each of our bots that has joined the channel in the url.
+// validate, if it's not nil, is called before any bot is touched so invalid requests don't change anything
+func editModule(a pkg.Application, validate func(spec pkg.ModuleSpec) error, cb func(botChannel pkg.BotChannel, moduleID string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := state.Context(w, r)
+
+		if !webutils.RequirePermission(w, c, pkg.PermissionAdmin) {
+			return
+		}
+
+		vars := mux.Vars(r)
+		response := editResponse{
+			ChannelID: vars["channelID"],
+			ModuleID:  vars["moduleID"],
+		}
+
+		spec, ok := botmodules.GetModule(response.ModuleID)
+		if !ok {
+			utils.WebWriteError(w, 400, "invalid module id")
+			return
+		}
+
+		if validate != nil {
+			if err := validate(spec); err != nil {
+				utils.WebWriteError(w, 400, err.Error())
+				return
+			}
+		}
+
+		response.EditResult, ok = webutils.EditBotChannels(w, a, response.ChannelID, 400, func(botChannel pkg.BotChannel) error {
+			return cb(botChannel, response.ModuleID)
+		})
+		if !ok {
+			return
+		}
+
+		utils.WebWrite(w, response)
+	}
+}
+
+func handleEnable(a pkg.Application) http.HandlerFunc {
+	return editModule(a, nil, func(botChannel pkg.BotChannel, moduleID string) error {
+		return botChannel.EnableModule(moduleID)
+	})
+}
+
+func handleDisable(a pkg.Application) http.HandlerFunc {
+	return editModule(a, nil, func(botChannel pkg.BotChannel, moduleID string) error {
+		return botChannel.DisableModule(moduleID)
+	})
+}
+
+func handleConfigure(a pkg.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		settings, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			utils.WebWriteError(w, 400, "Invalid request body")
+			return
+		}
+
+		validate := func(spec pkg.ModuleSpec) error {
+			return botmodules.ValidateSettings(spec, settings)
+		}
+
+		editModule(a, validate, func(botChannel pkg.BotChannel, moduleID string) error {
+			return botChannel.ConfigureModule(moduleID, settings)
+		})(w, r)
+	}
+}
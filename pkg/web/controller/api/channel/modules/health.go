@@ -0,0 +1,57 @@
+package modules
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+type botHealth struct {
+	BotName string
+
+	Modules []pkg.ModuleHealth
+}
+
+type healthResponse struct {
+	ChannelID string
+
+	// One entry for each of our bots that has joined the channel
+	Bots []botHealth
+}
+
+func handleHealth(a pkg.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := state.Context(w, r)
+
+		if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+			return
+		}
+
+		vars := mux.Vars(r)
+		var response healthResponse
+
+		response.ChannelID = vars["channelID"]
+		response.Bots = []botHealth{}
+
+		for botName, botChannel := range webutils.BotChannels(a, response.ChannelID) {
+			health := botHealth{
+				BotName: botName,
+				Modules: []pkg.ModuleHealth{},
+			}
+			health.Modules = append(health.Modules, botChannel.ModuleHealth()...)
+
+			response.Bots = append(response.Bots, health)
+		}
+
+		if len(response.Bots) == 0 {
+			utils.WebWriteError(w, 404, "No bot has joined that channel")
+			return
+		}
+
+		utils.WebWrite(w, response)
+	}
+}
@@ -0,0 +1,118 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+type module struct {
+	ID               string
+	Name             string
+	Phase            string
+	Priority         int
+	Dependencies     []string `json:",omitempty"`
+	EnabledByDefault bool
+	Enabled          bool
+
+	Settings json.RawMessage `json:",omitempty"`
+}
+
+type botModules struct {
+	BotName string
+
+	Modules []module
+}
+
+type listResponse struct {
+	ChannelID string
+
+	// One entry for each of our bots that has joined the channel
+	Bots []botModules
+}
+
+// loadSettings loads the stored settings of each module in the bot channel, by module ID
+func loadSettings(c state.State, botChannel pkg.BotChannel) (map[string]json.RawMessage, error) {
+	const queryF = `SELECT module_id, settings FROM BotChannelModule WHERE bot_channel_id=? AND settings IS NOT NULL`
+
+	rows, err := c.SQL.Query(queryF, botChannel.DatabaseID())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	settings := make(map[string]json.RawMessage)
+
+	for rows.Next() {
+		var moduleID string
+		var s []byte
+		if err = rows.Scan(&moduleID, &s); err != nil {
+			return nil, err
+		}
+
+		if len(s) > 0 && json.Valid(s) {
+			settings[moduleID] = s
+		}
+	}
+
+	return settings, rows.Err()
+}
+
+func handleList(a pkg.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := state.Context(w, r)
+
+		if !webutils.RequirePermission(w, c, pkg.PermissionAdmin) {
+			return
+		}
+
+		vars := mux.Vars(r)
+		var response listResponse
+
+		response.ChannelID = vars["channelID"]
+		response.Bots = []botModules{}
+
+		for botName, botChannel := range webutils.BotChannels(a, response.ChannelID) {
+			settings, err := loadSettings(c, botChannel)
+			if err != nil {
+				fmt.Println("Error loading module settings:", err)
+				utils.WebWriteError(w, 500, "Internal error")
+				return
+			}
+
+			bm := botModules{
+				BotName: botName,
+				Modules: []module{},
+			}
+
+			for _, status := range botChannel.Modules() {
+				bm.Modules = append(bm.Modules, module{
+					ID:               status.Spec.ID(),
+					Name:             status.Spec.Name(),
+					Phase:            status.Spec.Phase().String(),
+					Priority:         status.Spec.Priority(),
+					Dependencies:     status.Spec.Dependencies(),
+					EnabledByDefault: status.Spec.EnabledByDefault(),
+					Enabled:          status.Enabled,
+					Settings:         settings[status.Spec.ID()],
+				})
+			}
+
+			response.Bots = append(response.Bots, bm)
+		}
+
+		if len(response.Bots) == 0 {
+			utils.WebWriteError(w, 404, "No bot has joined that channel")
+			return
+		}
+
+		utils.WebWrite(w, response)
+	}
+}
@@ -0,0 +1,17 @@
+package modules
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/web/router"
+)
+
+func Load(parent *mux.Router, a pkg.Application) {
+	m := parent.PathPrefix("/modules").Subrouter()
+
+	router.RGet(m, ``, handleList(a))
+	router.RGet(m, `/health`, handleHealth(a))
+	router.RPost(m, `/{moduleID:\w+}/enable`, handleEnable(a))
+	router.RPost(m, `/{moduleID:\w+}/disable`, handleDisable(a))
+	router.RPost(m, `/{moduleID:\w+}/configure`, handleConfigure(a))
+}
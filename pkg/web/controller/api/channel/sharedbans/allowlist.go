@@ -0,0 +1,100 @@
+package sharedbans
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/sharedbans"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+type allowlistResponse struct {
+	ChannelID string
+
+	Users []*sharedbans.AllowlistEntry
+}
+
+func handleAllowlist(w http.ResponseWriter, r *http.Request) {
+	c := state.Context(w, r)
+
+	if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	var response allowlistResponse
+
+	response.ChannelID = vars["channelID"]
+
+	entries, err := sharedbans.LoadAllowlist(c.SQL, response.ChannelID)
+	if err != nil {
+		fmt.Println("Error loading shared ban allowlist:", err)
+		utils.WebWriteError(w, 500, "Internal error")
+		return
+	}
+
+	response.Users = []*sharedbans.AllowlistEntry{}
+	response.Users = append(response.Users, entries...)
+
+	utils.WebWrite(w, response)
+}
+
+func handleAllowlistAdd(w http.ResponseWriter, r *http.Request) {
+	c := state.Context(w, r)
+
+	if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	channelID := vars["channelID"]
+	userName := vars["user_name"]
+
+	userID := c.TwitchUserStore.GetID(userName)
+	if userID == "" {
+		utils.WebWriteError(w, 400, "Provided user name did not return a valid user ID")
+		return
+	}
+
+	if err := sharedbans.Allow(c.SQL, channelID, userID, userName, c.Session.TwitchUserID); err != nil {
+		utils.WebWriteError(w, 400, err.Error())
+		return
+	}
+
+	sharedbans.Log(c.SQL, channelID, 0, sharedbans.LogAllowlistAdd, c.Session.TwitchUserID, c.Session.TwitchUserName, userName)
+
+	utils.WebWrite(w, &sharedbans.AllowlistEntry{
+		UserID:    userID,
+		UserName:  userName,
+		AddedByID: c.Session.TwitchUserID,
+	})
+}
+
+func handleAllowlistRemove(w http.ResponseWriter, r *http.Request) {
+	c := state.Context(w, r)
+
+	if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	channelID := vars["channelID"]
+	userID := vars["user_id"]
+
+	if err := sharedbans.Disallow(c.SQL, channelID, userID); err != nil {
+		utils.WebWriteError(w, 400, err.Error())
+		return
+	}
+
+	sharedbans.Log(c.SQL, channelID, 0, sharedbans.LogAllowlistRemove, c.Session.TwitchUserID, c.Session.TwitchUserName, userID)
+
+	utils.WebWrite(w, map[string]string{
+		"UserID": userID,
+	})
+}
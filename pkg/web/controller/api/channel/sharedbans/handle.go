@@ -0,0 +1,66 @@
+package sharedbans
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/sharedbans"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+func handleApprove(w http.ResponseWriter, r *http.Request) {
+	handleBan(w, r, true)
+}
+
+func handleDeny(w http.ResponseWriter, r *http.Request) {
+	handleBan(w, r, false)
+}
+
+// handleBan makes sure the ban is still pending, then asks the shared bans module to approve or deny it
+func handleBan(w http.ResponseWriter, r *http.Request, approve bool) {
+	c := state.Context(w, r)
+
+	if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	banID, err := strconv.ParseInt(vars["banID"], 10, 64)
+	if err != nil {
+		utils.WebWriteError(w, 400, "Invalid shared ban ID")
+		return
+	}
+
+	b, err := sharedbans.LoadBan(c.SQL, vars["channelID"], banID)
+	if err != nil {
+		fmt.Println("Error loading shared ban:", err)
+		utils.WebWriteError(w, 500, "Internal error")
+		return
+	}
+
+	if b == nil {
+		utils.WebWriteError(w, 404, "No shared ban with that ID exists")
+		return
+	}
+
+	if b.State != sharedbans.StatePending {
+		utils.WebWriteError(w, 400, "Shared ban has already been "+b.State.String())
+		return
+	}
+
+	msg := &pkg.PubSubHandleSharedBan{
+		ChannelID: b.ChannelID,
+		BanID:     b.ID,
+		Approve:   approve,
+	}
+
+	c.PubSub.Publish(c.PubSubSource(), "HandleSharedBan", msg)
+
+	utils.WebWrite(w, msg)
+}
@@ -0,0 +1,95 @@
+package sharedbans
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/sharedbans"
+	"github.com/pajlada/pajbot2/pkg/utils"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
+)
+
+type sharedBan struct {
+	*sharedbans.Ban
+
+	OriginChannelName string
+	StateName         string
+}
+
+type listResponse struct {
+	ChannelID string
+
+	Bans []sharedBan
+}
+
+func handleList(w http.ResponseWriter, r *http.Request) {
+	c := state.Context(w, r)
+
+	if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	var response listResponse
+
+	response.ChannelID = vars["channelID"]
+
+	bans, err := sharedbans.LoadBans(c.SQL, response.ChannelID, 100)
+	if err != nil {
+		fmt.Println("Error loading shared bans:", err)
+		utils.WebWriteError(w, 500, "Internal error")
+		return
+	}
+
+	request := pkg.NewUserStoreRequest()
+	for _, b := range bans {
+		request.AddID(b.OriginChannelID)
+	}
+
+	names, _ := request.Execute(c.TwitchUserStore)
+
+	response.Bans = []sharedBan{}
+	for _, b := range bans {
+		response.Bans = append(response.Bans, sharedBan{
+			Ban:               b,
+			OriginChannelName: names[b.OriginChannelID],
+			StateName:         b.State.String(),
+		})
+	}
+
+	utils.WebWrite(w, response)
+}
+
+type logResponse struct {
+	ChannelID string
+
+	Entries []*sharedbans.LogEntry
+}
+
+func handleLog(w http.ResponseWriter, r *http.Request) {
+	c := state.Context(w, r)
+
+	if !webutils.RequirePermission(w, c, pkg.PermissionModeration) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	var response logResponse
+
+	response.ChannelID = vars["channelID"]
+
+	entries, err := sharedbans.LoadLog(c.SQL, response.ChannelID, 100)
+	if err != nil {
+		fmt.Println("Error loading shared ban log:", err)
+		utils.WebWriteError(w, 500, "Internal error")
+		return
+	}
+
+	response.Entries = []*sharedbans.LogEntry{}
+	response.Entries = append(response.Entries, entries...)
+
+	utils.WebWrite(w, response)
+}
@@ -0,0 +1,19 @@
+package sharedbans
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg/web/router"
+)
+
+func Load(parent *mux.Router) {
+	m := parent.PathPrefix("/sharedbans").Subrouter()
+
+	router.RGet(m, `/list`, handleList)
+	router.RGet(m, `/log`, handleLog)
+	router.RPost(m, `/{banID:[0-9]+}/approve`, handleApprove)
+	router.RPost(m, `/{banID:[0-9]+}/deny`, handleDeny)
+
+	router.RGet(m, `/allowlist`, handleAllowlist)
+	router.RPost(m, `/allowlist/add`, handleAllowlistAdd).Queries("user_name", `{user_name:\w+}`)
+	router.RPost(m, `/allowlist/remove`, handleAllowlistRemove).Queries("user_id", `{user_id:[0-9]+}`)
+}
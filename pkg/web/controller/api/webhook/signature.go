@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// verifySignature checks the X-Hub-Signature header that Twitch signs webhook notifications with, i.e. "sha256=<hex digest>"
+func verifySignature(secret string, signature string, body []byte) bool {
+	parts := strings.SplitN(signature, "=", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	var newHash func() hash.Hash
+
+	switch parts[0] {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+
+	computed := hmac.New(newHash, []byte(secret))
+	computed.Write(body)
+
+	return hmac.Equal(computed.Sum(nil), expected)
+}
+
+// requireSignature makes sure a webhook notification was signed with our webhook secret before passing it on to next.
+// If no secret is configured, notifications can't be verified, so they're all rejected
+func requireSignature(secret string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if secret == "" {
+			fmt.Println("Webhook notification sent to", r.URL.Path, "rejected, no webhook secret is configured")
+			w.WriteHeader(403)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			fmt.Println("Error reading webhook body:", err)
+			w.WriteHeader(400)
+			return
+		}
+
+		if !verifySignature(secret, r.Header.Get("X-Hub-Signature"), body) {
+			fmt.Println("Webhook notification with an invalid signature sent to", r.URL.Path)
+			w.WriteHeader(403)
+			return
+		}
+
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"data":[]}`)
+
+	tests := []struct {
+		signature string
+		expected  bool
+	}{
+		{"sha256=1d4e840702bf2c47e586d78cc52efdf26aa53fb52949666e772a32ff38070197", true},
+		{"sha1=8e0bf30d46e728ee1c8eee972faf2557d32c5aff", true},
+		{"sha256=2d4e840702bf2c47e586d78cc52efdf26aa53fb52949666e772a32ff38070197", false},
+		{"sha256=", false},
+		{"md5=xd", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if verifySignature("secret", test.signature, body) != test.expected {
+			t.Errorf("Signature %q should be valid: %v", test.signature, test.expected)
+		}
+	}
+}
+
+func TestRequireSignatureWithoutSecret(t *testing.T) {
+	called := false
+	handler := requireSignature("", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/api/webhook/11148817/streams", strings.NewReader(`{"data":[]}`)))
+
+	if called || w.Code != 403 {
+		t.Errorf("Notifications should be rejected when no secret is configured, got status %d", w.Code)
+	}
+}
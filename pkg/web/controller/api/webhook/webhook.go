@@ -1,12 +1,21 @@
 package webhook
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 
+	"github.com/dankeroni/gotwitch"
 	"github.com/gorilla/mux"
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/apirequest"
+	"github.com/pajlada/pajbot2/pkg/common/config"
+	"github.com/pajlada/pajbot2/pkg/twitch"
+	"github.com/pajlada/pajbot2/pkg/utils"
 	"github.com/pajlada/pajbot2/pkg/web/router"
+	"github.com/pajlada/pajbot2/pkg/web/state"
+	"github.com/pajlada/pajbot2/pkg/webutils"
 )
 
 func apiHook(w http.ResponseWriter, r *http.Request) {
@@ -61,36 +70,71 @@ func apiHook(w http.ResponseWriter, r *http.Request) {
 	*/
 }
 
+type followersResponse struct {
+	Data []gotwitch.Follow `json:"data"`
+}
+
 func apiFollowers(w http.ResponseWriter, r *http.Request) {
-	v := mux.Vars(r)
-	channel := v["channelID"]
-	fmt.Println("Channel ID:", channel)
+	c := state.Context(w, r)
 
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		fmt.Println("ERROR", err)
-		//p.Add("error", "Internal error")
-		//utils.WebWrite(w, p.data)
 		return
 	}
 
-	fmt.Println("Body:", string(body))
+	var response followersResponse
+	if err = json.Unmarshal(body, &response); err != nil {
+		fmt.Println("Error parsing followers webhook body:", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	for _, follow := range response.Data {
+		c.PubSub.Publish(c.PubSubSource(), "Follow", &pkg.PubSubFollowEvent{
+			Channel: pkg.PubSubUser{
+				ID:   follow.ToID,
+				Name: follow.ToName,
+			},
+			User: pkg.PubSubUser{
+				ID:   follow.FromID,
+				Name: follow.FromName,
+			},
+			FollowedAt: follow.FollowedAt,
+		})
+	}
 }
 
-func apiStreams(w http.ResponseWriter, r *http.Request) {
-	v := mux.Vars(r)
-	channel := v["channelID"]
-	fmt.Println("Channel ID:", channel)
+type streamsResponse struct {
+	Data []gotwitch.Stream `json:"data"`
+}
 
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		fmt.Println("ERROR", err)
-		//p.Add("error", "Internal error")
-		//utils.WebWrite(w, p.data)
-		return
-	}
+// The streams webhook sends us the stream if it went online or changed, or no streams at all if it went offline
+func apiStreams(a pkg.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v := mux.Vars(r)
+		channelID := v["channelID"]
 
-	fmt.Println("streams Body:", string(body))
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			fmt.Println("ERROR", err)
+			return
+		}
+
+		var response streamsResponse
+		if err = json.Unmarshal(body, &response); err != nil {
+			fmt.Println("Error parsing streams webhook body:", err)
+			w.WriteHeader(400)
+			return
+		}
+
+		var stream *pkg.StreamData
+		if len(response.Data) > 0 {
+			stream = twitch.NewStreamData(&response.Data[0])
+		}
+
+		a.StreamStore().UpdateStream(channelID, stream)
+	}
 }
 
 func apiUserChanged(w http.ResponseWriter, r *http.Request) {
@@ -109,14 +153,40 @@ func apiUserChanged(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("user changed Body:", body)
 }
 
-func Load(parent *mux.Router) {
+// apiStatus lists the webhook subscriptions we have asked for, and whether they're active
+func apiStatus(w http.ResponseWriter, r *http.Request) {
+	c := state.Context(w, r)
+
+	if !webutils.RequirePermission(w, c, pkg.PermissionAdmin) {
+		return
+	}
+
+	if apirequest.TwitchWrapper == nil {
+		utils.WebWriteError(w, 500, "Twitch API not initialized")
+		return
+	}
+
+	utils.WebWrite(w, apirequest.TwitchWrapper.WebhookSubscriptionStatuses())
+}
+
+func Load(parent *mux.Router, a pkg.Application, cfg *config.Config) {
 	m := parent.PathPrefix("/webhook").Subrouter()
 
+	secret := cfg.Auth.Twitch.Webhook.Secret
+
+	// Must be registered before the channel routes, which would match it otherwise
+	router.RGet(m, `/status`, apiStatus)
 	router.RGet(m, `/{channel:\w+}`, apiHook)
 	router.RGet(m, `/{channelID:\w+}/{topic:\w+}`, verifyHandler)
-	router.RPost(m, `/{channelID:\w+}/followers`, apiFollowers)
-	router.RPost(m, `/{channelID:\w+}/streams`, apiStreams)
-	router.RPost(m, `/{channelID:\w+}/user_changed`, apiUserChanged)
+
+	// Anyone could send us fake stream and follow events if we didn't verify them, so requireSignature rejects every notification without a secret
+	if secret == "" {
+		fmt.Println("No webhook secret is configured, webhook notifications will not be accepted")
+	}
+
+	router.RPost(m, `/{channelID:\w+}/followers`, requireSignature(secret, apiFollowers))
+	router.RPost(m, `/{channelID:\w+}/streams`, requireSignature(secret, apiStreams(a)))
+	router.RPost(m, `/{channelID:\w+}/user_changed`, requireSignature(secret, apiUserChanged))
 }
 
 func verifyHandler(w http.ResponseWriter, r *http.Request) {
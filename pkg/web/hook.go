@@ -44,88 +44,6 @@ func apiCallbacksFollow(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-type streamsResponse struct {
-	Data []struct {
-		ID           string        `json:"id"`
-		UserID       string        `json:"user_id"`
-		GameID       string        `json:"game_id"`
-		CommunityIds []interface{} `json:"community_ids"`
-		Type         string        `json:"type"`
-		Title        string        `json:"title"`
-		ViewerCount  int           `json:"viewer_count"`
-		StartedAt    time.Time     `json:"started_at"`
-		Language     string        `json:"language"`
-		ThumbnailURL string        `json:"thumbnail_url"`
-	} `json:"data"`
-}
-
-func apiCallbacksStreams(w http.ResponseWriter, r *http.Request) {
-	challenge := r.URL.Query().Get("hub.challenge")
-	if challenge != "" {
-		fmt.Fprintf(w, challenge)
-		fmt.Println("Responding to streams")
-		return
-	}
-
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-	fmt.Printf("Streams response xd \n")
-
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		panic(err)
-	}
-
-	var response streamsResponse
-
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		panic(err)
-	}
-
-	if len(response.Data) > 0 {
-		fmt.Printf("%#v\n", response.Data)
-		fmt.Printf("Online!\n")
-		fmt.Printf("Online!\n")
-		fmt.Printf("Online!\n")
-		fmt.Printf("Online!\n")
-		fmt.Printf("Online!\n")
-		fmt.Printf("Online!\n")
-		fmt.Printf("Online!\n")
-		fmt.Printf("Online!\n")
-		fmt.Printf("Online!\n")
-		fmt.Printf("Online!\n")
-		fmt.Printf("Online!\n")
-	} else {
-		fmt.Printf("%#v\n", response.Data)
-		fmt.Printf("Offline!\n")
-		fmt.Printf("Offline!\n")
-		fmt.Printf("Offline!\n")
-		fmt.Printf("Offline!\n")
-		fmt.Printf("Offline!\n")
-		fmt.Printf("Offline!\n")
-		fmt.Printf("Offline!\n")
-		fmt.Printf("Offline!\n")
-		fmt.Printf("Offline!\n")
-		fmt.Printf("Offline!\n")
-		fmt.Printf("Offline!\n")
-	}
-}
-
 func handlePush(b pkg.Sender, body []byte, p *customPayload) {
 	var pushData PushHookResponse
 
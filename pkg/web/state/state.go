@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/users"
 	"github.com/pajlada/pajbot2/pkg/utils"
 )
 
@@ -98,3 +99,30 @@ func Context(w http.ResponseWriter, r *http.Request) State {
 
 	return state
 }
+
+type webPubSubSource struct {
+	session *Session
+}
+
+func (s *webPubSubSource) IsApplication() bool {
+	return true
+}
+
+func (s *webPubSubSource) Connection() pkg.PubSubConnection {
+	return nil
+}
+
+func (s *webPubSubSource) AuthenticatedUser() pkg.User {
+	if s.session == nil {
+		return nil
+	}
+
+	return users.NewSimpleTwitchUser(s.session.TwitchUserID, s.session.TwitchUserName)
+}
+
+// PubSubSource returns the source that should be used when the web api publishes messages to pubsub
+func (s *State) PubSubSource() pkg.PubSubSource {
+	return &webPubSubSource{
+		session: s.Session,
+	}
+}
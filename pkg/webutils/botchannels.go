@@ -0,0 +1,68 @@
+package webutils
+
+import (
+	"net/http"
+
+	"github.com/pajlada/pajbot2/pkg"
+	"github.com/pajlada/pajbot2/pkg/utils"
+)
+
+// BotChannels returns the bot channel of each of our bots that has joined the given channel, by bot name
+func BotChannels(a pkg.Application, channelID string) map[string]pkg.BotChannel {
+	botChannels := make(map[string]pkg.BotChannel)
+
+	for it := a.TwitchBots().Iterate(); it.Next(); {
+		bot := it.Value()
+
+		if botChannel := bot.GetBotChannel(channelID); botChannel != nil {
+			botChannels[bot.TwitchAccount().Name()] = botChannel
+		}
+	}
+
+	return botChannels
+}
+
+// EditResult is embedded in the responses of endpoints that apply a change to every bot in a channel
+type EditResult struct {
+	// Bots the change was applied to
+	Bots []string
+
+	// Bots the change could not be applied to, and why
+	Errors map[string]string `json:",omitempty"`
+}
+
+// EditBotChannels calls cb with the bot channel of each of our bots that has joined the given channel.
+// A bot that fails to apply the change doesn't stop the others from applying it, the failures are listed in the result.
+// If no bot has joined the channel, or no bot managed to apply the change, an error is written to w and ok is false
+func EditBotChannels(w http.ResponseWriter, a pkg.Application, channelID string, errorStatus int, cb func(botChannel pkg.BotChannel) error) (result EditResult, ok bool) {
+	result.Bots = []string{}
+
+	botChannels := BotChannels(a, channelID)
+	if len(botChannels) == 0 {
+		utils.WebWriteError(w, 404, "No bot has joined that channel")
+		return
+	}
+
+	var firstError string
+
+	for botName, botChannel := range botChannels {
+		if err := cb(botChannel); err != nil {
+			if result.Errors == nil {
+				result.Errors = make(map[string]string)
+				firstError = err.Error()
+			}
+
+			result.Errors[botName] = err.Error()
+			continue
+		}
+
+		result.Bots = append(result.Bots, botName)
+	}
+
+	if len(result.Bots) == 0 {
+		utils.WebWriteError(w, errorStatus, firstError)
+		return
+	}
+
+	return result, true
+}
@@ -21,7 +21,7 @@ func RequirePermission(w http.ResponseWriter, c state.State, permission pkg.Perm
 		return false
 	}
 
-	if !user.HasGlobalPermission(pkg.PermissionModeration) {
+	if !user.HasGlobalPermission(permission) {
 		utils.WebWriteError(w, 400, "Not authorized to view this endpoint!!!")
 		return false
 	}